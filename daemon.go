@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	daemonflag "github.com/giantswarm/microkit/command/daemon/flag"
+	microflag "github.com/giantswarm/microkit/flag"
+	microserver "github.com/giantswarm/microkit/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	df = daemonflag.New()
+)
+
+// newDaemonRun returns the run function of the daemon command. It does what
+// the microkit daemon does, except for shutting down. The microkit daemon
+// exits the process three seconds after shutting down its own HTTP server on
+// termination, without shutting down our server, which would kill the
+// deployment being installed. Here, the process only exits once our server,
+// and so the service, is shut down.
+func newDaemonRun(v *viper.Viper, newServerFactory func(v *viper.Viper) microserver.Server) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		microflag.Parse(v, cmd.Flags())
+
+		err := microflag.Merge(v, cmd.Flags(), v.GetStringSlice(df.Config.Dirs), v.GetStringSlice(df.Config.Files))
+		if err != nil {
+			panic(err)
+		}
+
+		newServer := newServerFactory(v)
+
+		var newMicroServer microserver.Server
+		{
+			c := newServer.Config()
+
+			c.EnableDebugServer = v.GetBool(df.Server.Enable.Debug.Server)
+			c.LogAccess = v.GetBool(df.Server.Log.Access)
+			if c.ListenAddress == "" {
+				c.ListenAddress = v.GetString(df.Server.Listen.Address)
+			}
+			if c.ListenMetricsAddress == "" {
+				c.ListenMetricsAddress = v.GetString(df.Server.Listen.MetricsAddress)
+			}
+			if c.TLSCAFile == "" {
+				c.TLSCAFile = v.GetString(df.Server.TLS.CaFile)
+			}
+			if c.TLSCrtFile == "" {
+				c.TLSCrtFile = v.GetString(df.Server.TLS.CrtFile)
+			}
+			if c.TLSKeyFile == "" {
+				c.TLSKeyFile = v.GetString(df.Server.TLS.KeyFile)
+			}
+
+			newMicroServer, err = microserver.New(c)
+			if err != nil {
+				panic(err)
+			}
+			go newMicroServer.Boot()
+		}
+
+		// Pressing Ctrl+C produces SIGINT. A second signal exits right away,
+		// without waiting for the deployment being installed.
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		shutdownOnSignal(signals, newServer, newMicroServer)
+	}
+}
+
+// shutdownOnSignal waits for a signal, and then shuts down the given servers
+// in order. It returns once all of them are shut down. A second signal exits
+// the process right away.
+func shutdownOnSignal(signals <-chan os.Signal, servers ...microserver.Server) {
+	<-signals
+
+	go func() {
+		<-signals
+		os.Exit(0)
+	}()
+
+	for _, s := range servers {
+		s.Shutdown()
+	}
+}
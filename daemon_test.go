@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	microserver "github.com/giantswarm/microkit/server"
+)
+
+// testServer is a server whose Shutdown blocks until it is released.
+type testServer struct {
+	name     string
+	release  chan struct{}
+	shutdown chan string
+}
+
+func (s *testServer) Boot() {}
+
+func (s *testServer) Config() microserver.Config {
+	return microserver.Config{}
+}
+
+func (s *testServer) Shutdown() {
+	<-s.release
+	s.shutdown <- s.name
+}
+
+// TestShutdownOnSignal tests that servers are shut down in order on a signal,
+// and that it only returns once all of them are shut down.
+func TestShutdownOnSignal(t *testing.T) {
+	signals := make(chan os.Signal, 2)
+	shutdown := make(chan string, 2)
+
+	service := &testServer{name: "service", release: make(chan struct{}), shutdown: shutdown}
+	http := &testServer{name: "http", release: make(chan struct{}), shutdown: shutdown}
+	close(http.release)
+
+	returned := make(chan struct{})
+	go func() {
+		shutdownOnSignal(signals, service, http)
+		close(returned)
+	}()
+
+	signals <- syscall.SIGTERM
+
+	select {
+	case <-returned:
+		t.Fatalf("expected to wait for the service to shut down")
+	case name := <-shutdown:
+		t.Fatalf("expected nothing to be shut down before the service, returned: %s", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(service.release)
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatalf("expected to return once all servers are shut down")
+	}
+
+	for _, expected := range []string{"service", "http"} {
+		if returned := <-shutdown; returned != expected {
+			t.Fatalf("expected: %s\nreturned: %s\n", expected, returned)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer"
 	"github.com/giantswarm/draughtsman/flag/service/deployer/installer"
 	"github.com/giantswarm/draughtsman/flag/service/deployer/notifier"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/project"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/status"
 )
 
 type Deployer struct {
@@ -12,5 +14,7 @@ type Deployer struct {
 	Eventer     eventer.Eventer
 	Installer   installer.Installer
 	Notifier    notifier.Notifier
+	Project     project.Project
+	Status      status.Status
 	Type        string
 }
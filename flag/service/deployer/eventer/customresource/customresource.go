@@ -0,0 +1,5 @@
+package customresource
+
+type CustomResource struct {
+	Namespace string
+}
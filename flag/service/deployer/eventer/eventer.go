@@ -1,10 +1,24 @@
 package eventer
 
 import (
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/customresource"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/file"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/gitea"
 	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/github"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/gitlab"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/poll"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/registry"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/state"
 )
 
 type Eventer struct {
-	GitHub github.GitHub
-	Type   string
+	CustomResource customresource.CustomResource
+	File           file.File
+	Gitea          gitea.Gitea
+	GitHub         github.GitHub
+	GitLab         gitlab.GitLab
+	Poll           poll.Poll
+	Registry       registry.Registry
+	State          state.State
+	Type           string
 }
@@ -0,0 +1,6 @@
+package file
+
+type File struct {
+	Directory    string
+	PollInterval string
+}
@@ -0,0 +1,10 @@
+package gitea
+
+type Gitea struct {
+	BaseURL      string
+	Branch       string
+	Organisation string
+	PollInterval string
+	Token        string
+	Trigger      string
+}
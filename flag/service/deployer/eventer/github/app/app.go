@@ -0,0 +1,7 @@
+package app
+
+type App struct {
+	ID             string
+	InstallationID string
+	PrivateKey     string
+}
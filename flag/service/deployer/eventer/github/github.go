@@ -1,7 +1,16 @@
 package github
 
+import (
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/github/app"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/github/topic"
+)
+
 type GitHub struct {
-	OAuthToken   string
-	Organisation string
-	PollInterval string
+	App           app.App
+	BaseURL       string
+	OAuthToken    string
+	Organisation  string
+	PollInterval  string
+	Topic         topic.Topic
+	WebhookSecret string
 }
@@ -0,0 +1,6 @@
+package topic
+
+type Topic struct {
+	Name            string
+	RefreshInterval string
+}
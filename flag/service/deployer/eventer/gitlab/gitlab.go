@@ -0,0 +1,8 @@
+package gitlab
+
+type GitLab struct {
+	BaseURL      string
+	Group        string
+	PollInterval string
+	Token        string
+}
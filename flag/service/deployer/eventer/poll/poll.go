@@ -0,0 +1,6 @@
+package poll
+
+type Poll struct {
+	Jitter  string
+	Workers string
+}
@@ -0,0 +1,17 @@
+package registry
+
+import (
+	"github.com/giantswarm/draughtsman/flag/service/deployer/eventer/registry/sink"
+)
+
+type Registry struct {
+	API             string
+	BaseURL         string
+	Organisation    string
+	Password        string
+	Pattern         string
+	PollInterval    string
+	ProjectPatterns string
+	Sink            sink.Sink
+	Username        string
+}
@@ -0,0 +1,6 @@
+package sink
+
+type Sink struct {
+	Type       string
+	WebhookURL string
+}
@@ -0,0 +1,5 @@
+package state
+
+type State struct {
+	ConfigMap string
+}
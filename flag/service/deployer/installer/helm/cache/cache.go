@@ -0,0 +1,6 @@
+package cache
+
+type Cache struct {
+	Directory string
+	MaxSize   string
+}
@@ -1,9 +1,21 @@
 package helm
 
+import (
+	"github.com/giantswarm/draughtsman/flag/service/deployer/installer/helm/cache"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/installer/helm/oci"
+	"github.com/giantswarm/draughtsman/flag/service/deployer/installer/helm/repository"
+)
+
 type Helm struct {
+	Atomic         string
+	Cache          cache.Cache
+	Chart          string
 	HelmBinaryPath string
+	OCI            oci.OCI
 	Organisation   string
 	Password       string
 	Registry       string
+	Repository     repository.Repository
+	Timeout        string
 	Username       string
 }
@@ -0,0 +1,7 @@
+package oci
+
+type OCI struct {
+	PlainHTTP       string
+	SecretName      string
+	SecretNamespace string
+}
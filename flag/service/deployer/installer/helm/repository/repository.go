@@ -0,0 +1,7 @@
+package repository
+
+type Repository struct {
+	CAFile   string
+	Password string
+	Username string
+}
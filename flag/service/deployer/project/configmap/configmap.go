@@ -0,0 +1,6 @@
+package configmap
+
+type ConfigMap struct {
+	Key  string
+	Name string
+}
@@ -0,0 +1,9 @@
+package project
+
+import (
+	"github.com/giantswarm/draughtsman/flag/service/deployer/project/configmap"
+)
+
+type Project struct {
+	ConfigMap configmap.ConfigMap
+}
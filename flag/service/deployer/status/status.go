@@ -0,0 +1,7 @@
+package status
+
+type Status struct {
+	EnvironmentURL string
+	ExternalURL    string
+	Token          string
+}
@@ -3,6 +3,7 @@ module github.com/giantswarm/draughtsman
 go 1.13
 
 require (
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/ghodss/yaml v1.0.0
 	github.com/giantswarm/backoff v0.2.0
 	github.com/giantswarm/k8sclient v0.2.0
@@ -11,11 +12,25 @@ require (
 	github.com/giantswarm/microkit v0.2.0
 	github.com/giantswarm/micrologger v0.5.0
 	github.com/giantswarm/operatorkit v0.2.0
+	github.com/go-kit/kit v0.10.0
+	github.com/gorilla/mux v1.7.3
 	github.com/juju/ratelimit v1.0.1
 	github.com/nlopes/slack v0.1.0
 	github.com/prometheus/client_golang v1.3.0
 	github.com/spf13/afero v1.2.2
+	github.com/spf13/cobra v0.0.5
 	github.com/spf13/viper v1.6.2
+	helm.sh/helm/v3 v3.0.2
+	k8s.io/api v0.16.6
 	k8s.io/apimachinery v0.16.6
 	k8s.io/client-go v0.16.6
 )
+
+replace (
+	github.com/Azure/go-autorest/autorest => github.com/Azure/go-autorest/autorest v0.9.0
+	github.com/docker/docker => github.com/moby/moby v0.7.3-0.20190826074503-38ab9da00309
+	github.com/miekg/dns => github.com/miekg/dns v0.0.0-20181005163659-0d29b283ac0f
+	gopkg.in/inf.v0 v0.9.1 => github.com/go-inf/inf v0.9.1
+	gopkg.in/square/go-jose.v2 v2.3.0 => github.com/square/go-jose v2.3.0+incompatible
+	rsc.io/letsencrypt => github.com/dmcgowan/letsencrypt v0.0.0-20160928181947-1847a81d2087
+)
@@ -21,7 +21,11 @@ import (
 	"github.com/giantswarm/draughtsman/service/configurer/configmap"
 	"github.com/giantswarm/draughtsman/service/configurer/secret"
 	"github.com/giantswarm/draughtsman/service/deployer"
+	"github.com/giantswarm/draughtsman/service/eventer/gitea"
 	"github.com/giantswarm/draughtsman/service/eventer/github"
+	"github.com/giantswarm/draughtsman/service/eventer/gitlab"
+	"github.com/giantswarm/draughtsman/service/eventer/registry"
+	"github.com/giantswarm/draughtsman/service/eventer/sink"
 	"github.com/giantswarm/draughtsman/service/installer/helm"
 	slacknotifier "github.com/giantswarm/draughtsman/service/notifier/slack"
 	slackspec "github.com/giantswarm/draughtsman/service/slack"
@@ -97,10 +101,12 @@ func mainError() error {
 		var newServer microserver.Server
 		{
 			c := server.Config{
-				Logger:      newLogger,
-				Service:     newService,
-				Viper:       v,
-				ProjectName: project.Name(),
+				Logger:  newLogger,
+				Service: newService,
+				Viper:   v,
+
+				DeploymentToken: v.GetString(f.Service.Deployer.Status.Token),
+				ProjectName:     project.Name(),
 			}
 
 			newServer, err = server.New(c)
@@ -113,11 +119,14 @@ func mainError() error {
 	}
 
 	// Create a new microkit command which manages our custom microservice.
+	newViper := viper.New()
+
 	var newCommand command.Command
 	{
 		c := command.Config{
 			Logger:        newLogger,
 			ServerFactory: newServerFactory,
+			Viper:         newViper,
 
 			Description: project.Description(),
 			GitCommit:   project.GitSHA(),
@@ -134,13 +143,22 @@ func mainError() error {
 
 	daemonCommand := newCommand.DaemonCommand().CobraCommand()
 
+	// The daemon is run by us, so that the process waits for the service to
+	// shut down on termination.
+	daemonCommand.Run = newDaemonRun(newViper, newServerFactory)
+
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Environment, "", "Environment name that draughtsman is running in.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Provider, "", "Provider that draughtsman is running in.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Project.ConfigMap.Key, "projects.yaml", "Key in configmap holding the project manifest.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Project.ConfigMap.Name, "draughtsman-projects", "Name of configmap in the release namespace holding the project manifest, listing the projects to deploy and their settings.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Status.EnvironmentURL, "", "URL of the environment draughtsman deploys to, linked from deployment statuses.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Status.ExternalURL, "", "URL draughtsman is reachable at, used to link deployment statuses to their details. When empty, no link is set.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Status.Token, "", "Bearer token required to read the full output of deployments from their details. When empty, the output is not served.")
 
 	// Component type selection.
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Type, string(deployer.StandardDeployer), "Which deployer to use for deployment management.")
-	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Type, string(github.GithubEventerType), "Which eventer to use for event management.")
-	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Type, string(helm.HelmInstallerType), "Which installer to use for installation management.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Type, string(github.GithubEventerType), "Comma separated list of eventers to use for event management.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Type, string(helm.HelmInstallerType), "Which installer to use for installation management, HelmInstaller or HelmSDKInstaller.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Configurer.Types, string(configmap.ConfigurerType)+","+string(secret.ConfigurerType), "Comma separated list of configurers to use for configuration management.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Notifier.Type, string(slacknotifier.SlackNotifierType), "Which notifier to use for notification management.")
 
@@ -157,14 +175,58 @@ func mainError() error {
 	daemonCommand.PersistentFlags().String(f.Service.Slack.Token, "", "Token to post Slack notifications with.")
 
 	// Service configuration.
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.CustomResource.Namespace, "", "Namespace watched for DraughtsmanDeployment resources. Defaults to the release namespace.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.File.Directory, "", "Directory watched for deployment request files.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Deployer.Eventer.File.PollInterval, 10*time.Second, "Interval to poll the directory for new deployment request files.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Gitea.BaseURL, "", "URL of the Gitea, or Forgejo, instance.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Gitea.Branch, "master", "Branch whose head is deployed when using the status trigger.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Gitea.Organisation, "", "Organisation under which to check for deployments.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Deployer.Eventer.Gitea.PollInterval, 1*time.Minute, "Interval to poll for new deployments.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Gitea.Token, "", "Access token for authenticating against Gitea. Needs repository write access.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Gitea.Trigger, string(gitea.StatusTrigger), "What triggers deployments. Either 'release' or 'status'.")
+	daemonCommand.PersistentFlags().Int64(f.Service.Deployer.Eventer.GitHub.App.ID, 0, "ID of the GitHub App to authenticate as. When set, the OAuth token is not used.")
+	daemonCommand.PersistentFlags().Int64(f.Service.Deployer.Eventer.GitHub.App.InstallationID, 0, "ID of the GitHub App installation. When empty, the installation of the organisation is used.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.GitHub.App.PrivateKey, "", "PEM encoded private key of the GitHub App. Needs 'deployments' read and write permission.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.GitHub.BaseURL, github.DefaultBaseURL, "URL of the GitHub API. For GitHub Enterprise Server, the URL of the instance.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.GitHub.OAuthToken, "", "OAuth token for authenticating against GitHub. Needs 'repo_deployment' scope.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.GitHub.Organisation, "", "Organisation under which to check for deployments.")
-	daemonCommand.PersistentFlags().Duration(f.Service.Deployer.Eventer.GitHub.PollInterval, 1*time.Minute, "Interval to poll for new deployments.")
-
+	daemonCommand.PersistentFlags().Duration(f.Service.Deployer.Eventer.GitHub.PollInterval, 1*time.Minute, "Interval to poll for new deployments. Acts as reconciliation interval when using webhooks.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.GitHub.Topic.Name, "", "Topic of the organisation's repositories to deploy, e.g. 'draughtsman-aws'. When set, projects are discovered instead of using the project list of the provider.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Deployer.Eventer.GitHub.Topic.RefreshInterval, 10*time.Minute, "Interval to discover projects by their topic again.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.GitHub.WebhookSecret, "", "Secret used to verify GitHub webhook deliveries. Only used by the GitHub webhook eventer.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.GitLab.BaseURL, gitlab.DefaultBaseURL, "URL of the GitLab instance.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.GitLab.Group, "", "Group under which to check for deployments.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Deployer.Eventer.GitLab.PollInterval, 1*time.Minute, "Interval to poll for new deployments.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.GitLab.Token, "", "Access token for authenticating against GitLab. Needs 'api' scope.")
+	daemonCommand.PersistentFlags().Float64(f.Service.Deployer.Eventer.Poll.Jitter, 0.1, "Maximum factor of the poll interval added as random jitter to every poll interval, so that replicas do not poll in lockstep.")
+	daemonCommand.PersistentFlags().Int(f.Service.Deployer.Eventer.Poll.Workers, 4, "Maximum number of projects polled concurrently.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Registry.API, string(registry.CNRAPI), "API used to list chart tags. Either 'cnr' for Quay application repositories or 'oci' for OCI registries.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Registry.BaseURL, registry.DefaultBaseURL, "URL of the chart registry.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Registry.Organisation, "", "Organisation under which to check for chart tags.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Registry.Password, "", "Password for the chart registry. Optional for public repositories.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Registry.Pattern, registry.DefaultPattern, "Pattern of chart tags to deploy, as glob or semver range.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Deployer.Eventer.Registry.PollInterval, 1*time.Minute, "Interval to poll for new chart tags.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Registry.ProjectPatterns, "", "Patterns of chart tags to deploy for specific projects, e.g: 'api=1.0.0-*;web=~1.2'.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Registry.Sink.Type, string(sink.LogSinkType), "Where deployment statuses are reported to. Either 'log' or 'webhook'.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Registry.Sink.WebhookURL, "", "URL deployment statuses are posted to by the webhook sink.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.Registry.Username, "", "Username for the chart registry. Optional for public repositories.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Eventer.State.ConfigMap, "draughtsman-eventer-state", "Name of the configmap in the release namespace persisting eventer state across restarts. Empty disables persistence.")
+
+	daemonCommand.PersistentFlags().Bool(f.Service.Deployer.Installer.Helm.Atomic, false, "Whether to install releases atomically, waiting for their resources to become ready, and rolling them back if they do not. Only used by the HelmInstaller. Projects may override it.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.Cache.Directory, "/tmp/draughtsman/charts", "Directory of the cache of pulled charts. Only used by the HelmInstaller. Charts are not cached when empty.")
+	daemonCommand.PersistentFlags().Int64(f.Service.Deployer.Installer.Helm.Cache.MaxSize, 512*1024*1024, "Maximum size of the cache of pulled charts in bytes. Least recently used charts are evicted beyond it.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.Chart, "", "Template of the reference of the chart of projects, e.g. oci://quay.io/giantswarm/{{ .Project }}-chart:{{ .Version }}, or https://giantswarm.github.io/charts/{{ .Project }}-chart for Helm chart repositories. Charts are pulled from the Helm CNR registry when empty.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.HelmBinaryPath, "/bin/helm", "Path to Helm binary. Needs CNR registry plugin installed.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Deployer.Installer.Helm.OCI.PlainHTTP, false, "Whether to pull charts from OCI registries over HTTP instead of HTTPS.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.OCI.SecretName, "", "Name of Docker config secret holding credentials of OCI registries. Registries are accessed anonymously when empty.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.OCI.SecretNamespace, "draughtsman", "Namespace of Docker config secret holding credentials of OCI registries.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.Organisation, "", "Organisation of Helm CNR registry.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.Password, "", "Password for Helm CNR registry.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.Registry, "quay.io", "URL for Helm CNR registry.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.Repository.CAFile, "", "Path of PEM file holding certificate authorities trusted by Helm chart repositories, in addition to the system ones.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.Repository.Password, "", "Password for Helm chart repositories.")
+	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.Repository.Username, "", "Username for Helm chart repositories.")
+	daemonCommand.PersistentFlags().Duration(f.Service.Deployer.Installer.Helm.Timeout, 5*time.Minute, "Timeout of Helm actions, and of atomic installs of the HelmInstaller.")
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Helm.Username, "", "Username for Helm CNR registry.")
 
 	daemonCommand.PersistentFlags().String(f.Service.Deployer.Installer.Configurer.ConfigMap.Key, "values", "Key in configmap holding values data.")
@@ -0,0 +1,50 @@
+package configuration
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/giantswarm/microerror"
+)
+
+// ChartReferenceData is the data chart reference templates are rendered
+// with.
+type ChartReferenceData struct {
+	// Organisation is the organisation of the chart, e.g: giantswarm.
+	Organisation string
+	// Project is the name of the project, e.g: api.
+	Project string
+	// Registry is the host of the registry, e.g: quay.io.
+	Registry string
+	// Sha is the deployed sha, e.g: a1b2c3.
+	Sha string
+	// Version is the chart version to install, e.g: 1.0.0-a1b2c3.
+	Version string
+}
+
+// ChartReference renders the reference of the chart of the project, using
+// the chart template of the project, or the given default template. It
+// returns an empty reference if both are empty, which refers to the chart in
+// the CNR registry. Templates can be validated by rendering them with empty
+// data.
+func (p Project) ChartReference(defaultTemplate string, data ChartReferenceData) (string, error) {
+	chartTemplate := p.Chart
+	if chartTemplate == "" {
+		chartTemplate = defaultTemplate
+	}
+	if chartTemplate == "" {
+		return "", nil
+	}
+
+	t, err := template.New("chart").Parse(chartTemplate)
+	if err != nil {
+		return "", microerror.Maskf(invalidChartTemplateError, err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", microerror.Maskf(invalidChartTemplateError, err.Error())
+	}
+
+	return buf.String(), nil
+}
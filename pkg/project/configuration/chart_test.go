@@ -0,0 +1,61 @@
+package configuration
+
+import (
+	"testing"
+)
+
+// TestChartReference tests rendering chart references from templates.
+func TestChartReference(t *testing.T) {
+	data := ChartReferenceData{
+		Organisation: "giantswarm",
+		Project:      "api",
+		Registry:     "quay.io",
+		Sha:          "a1b2c3",
+		Version:      "1.0.0-a1b2c3",
+	}
+
+	tests := []struct {
+		project           Project
+		defaultTemplate   string
+		expectedReference string
+		errorMatcher      func(error) bool
+	}{
+		{
+			project:           Project{Name: "api"},
+			defaultTemplate:   "",
+			expectedReference: "",
+		},
+		{
+			project:           Project{Name: "api"},
+			defaultTemplate:   "oci://{{ .Registry }}/{{ .Organisation }}/{{ .Project }}-chart:{{ .Version }}",
+			expectedReference: "oci://quay.io/giantswarm/api-chart:1.0.0-a1b2c3",
+		},
+		{
+			project:           Project{Name: "api", Chart: "oci://localhost:5000/charts/api:{{ .Sha }}"},
+			defaultTemplate:   "oci://{{ .Registry }}/{{ .Organisation }}/{{ .Project }}-chart:{{ .Version }}",
+			expectedReference: "oci://localhost:5000/charts/api:a1b2c3",
+		},
+		{
+			project:         Project{Name: "api", Chart: "oci://{{ .Host }}/api:{{ .Sha }}"},
+			defaultTemplate: "",
+			errorMatcher:    IsInvalidChartTemplate,
+		},
+	}
+
+	for index, test := range tests {
+		reference, err := test.project.ChartReference(test.defaultTemplate, data)
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v: unexpected error returned: %#v", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+
+		if reference != test.expectedReference {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedReference, reference)
+		}
+	}
+}
@@ -1,27 +1,193 @@
+// Package configuration holds the projects draughtsman deploys, and their
+// settings, as read from a project manifest.
 package configuration
 
-var (
-	commonProjectList = []string{
-		"shared-app-collection",
-		"draughtsman",
-	}
-
-	providerProjectList = map[string][]string{
-		"aws": {
-			"aws-app-collection",
-		},
-		"kvm": {
-			"kvm-app-collection",
-		},
-		"azure": {
-			"azure-app-collection",
-		},
-		"vmware": {
-			"vmware-app-collection",
-		},
-	}
+import (
+	"github.com/ghodss/yaml"
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// DefaultNamespace is the namespace of the releases of projects that do
+	// not configure a namespace.
+	DefaultNamespace = "draughtsman"
 )
 
-func GetProjectList(provider, installation string) []string {
-	return append(commonProjectList, providerProjectList[provider]...)
+// Getter represents a Service that looks up the settings of projects.
+type Getter interface {
+	// Project returns the settings of the project of the given name. Projects
+	// that are not configured get the default settings.
+	Project(name string) Project
+}
+
+// Manifest represents the project manifest, e.g:
+//
+//	projects:
+//	- name: aws-app-collection
+//	  atomic: true
+//	  force: true
+//	  providers:
+//	  - aws
+//	- name: draughtsman
+//	  namespace: giantswarm
+//	  payloadNamespaces:
+//	  - monitoring
+//	  payloadValues:
+//	  - image.tag
+type Manifest struct {
+	// Projects are the projects to deploy.
+	Projects []Project `json:"projects"`
+}
+
+// Project represents the settings of a project.
+type Project struct {
+	// Name is the name of the project, e.g: api.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the release of the project. It defaults
+	// to DefaultNamespace.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Release is the name of the release of the project. It defaults to the
+	// name of the project.
+	Release string `json:"release,omitempty"`
+
+	// Force upgrades the release with --force, replacing resources that can
+	// not be updated in place.
+	Force bool `json:"force,omitempty"`
+
+	// Atomic overrides whether the release of the project is installed
+	// atomically, waiting for its resources to become ready, and rolling it
+	// back if they do not. The configured mode is used when empty.
+	Atomic *bool `json:"atomic,omitempty"`
+
+	// ValuesKeys are the keys of the values configmap and secret used for the
+	// project. The configured keys are used when empty.
+	ValuesKeys []string `json:"valuesKeys,omitempty"`
+
+	// Providers are the providers the project is deployed to. It is deployed
+	// to all providers when empty.
+	Providers []string `json:"providers,omitempty"`
+
+	// Environments are the environments the project is deployed to. It is
+	// deployed to all environments when empty.
+	Environments []string `json:"environments,omitempty"`
+
+	// Chart is the template of the reference of the chart of the project,
+	// e.g: oci://quay.io/giantswarm/{{ .Project }}-chart:{{ .Version }}. See
+	// ChartReference. The configured template is used when empty.
+	Chart string `json:"chart,omitempty"`
+
+	// PayloadNamespaces are the namespaces, other than Namespace, that
+	// deployments of the project may request in their payload. Requests of
+	// other namespaces are rejected.
+	PayloadNamespaces []string `json:"payloadNamespaces,omitempty"`
+
+	// PayloadValues are the keys of the values that deployments of the
+	// project may set in their payload, e.g: image.tag. Requests of other
+	// values are rejected.
+	PayloadValues []string `json:"payloadValues,omitempty"`
+}
+
+// ParseManifest parses a YAML, or JSON, project manifest.
+func ParseManifest(data []byte) (Manifest, error) {
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, microerror.Maskf(invalidManifestError, err.Error())
+	}
+
+	names := map[string]bool{}
+	for index, project := range manifest.Projects {
+		if project.Name == "" {
+			return Manifest{}, microerror.Maskf(invalidManifestError, "name of project %d must not be empty", index)
+		}
+		if names[project.Name] {
+			return Manifest{}, microerror.Maskf(invalidManifestError, "project %#q must only be configured once", project.Name)
+		}
+		if _, err := project.ChartReference("", ChartReferenceData{}); err != nil {
+			return Manifest{}, microerror.Maskf(invalidManifestError, "chart of project %#q: %s", project.Name, err.Error())
+		}
+
+		names[project.Name] = true
+	}
+
+	return manifest, nil
+}
+
+// Project returns the settings of the project of the given name, with
+// defaults applied.
+func (m Manifest) Project(name string) Project {
+	project := Project{Name: name}
+	for _, p := range m.Projects {
+		if p.Name == name {
+			project = p
+			break
+		}
+	}
+
+	if project.Namespace == "" {
+		project.Namespace = DefaultNamespace
+	}
+	if project.Release == "" {
+		project.Release = project.Name
+	}
+
+	return project
+}
+
+// IsAtomic returns true if the release of the project is installed
+// atomically, using the given default if the project does not configure it.
+func (p Project) IsAtomic(atomicByDefault bool) bool {
+	if p.Atomic != nil {
+		return *p.Atomic
+	}
+
+	return atomicByDefault
+}
+
+// AllowsPayloadNamespace returns true if deployments of the project may
+// request the given namespace in their payload.
+func (p Project) AllowsPayloadNamespace(namespace string) bool {
+	return namespace == p.Namespace || contains(p.PayloadNamespaces, namespace)
+}
+
+// AllowsPayloadValue returns true if deployments of the project may set the
+// value of the given key in their payload.
+func (p Project) AllowsPayloadValue(key string) bool {
+	return contains(p.PayloadValues, key)
+}
+
+// ProjectList returns the names of the projects deployed to the given
+// provider and environment.
+func (m Manifest) ProjectList(provider, environment string) []string {
+	projectList := []string{}
+	for _, project := range m.Projects {
+		if !matches(project.Providers, provider) || !matches(project.Environments, environment) {
+			continue
+		}
+
+		projectList = append(projectList, project.Name)
+	}
+
+	return projectList
+}
+
+// matches returns true if the list is empty, or contains the value.
+func matches(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	return contains(list, value)
+}
+
+// contains returns true if the list contains the value.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
 }
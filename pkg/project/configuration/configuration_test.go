@@ -0,0 +1,218 @@
+package configuration
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseManifest tests parsing, and validating, project manifests.
+func TestParseManifest(t *testing.T) {
+	atomic := false
+
+	tests := []struct {
+		data             string
+		expectedManifest Manifest
+		errorMatcher     func(error) bool
+	}{
+		{
+			data: `
+projects:
+- name: aws-app-collection
+  atomic: false
+  force: true
+  providers:
+  - aws
+- name: draughtsman
+  namespace: giantswarm
+  valuesKeys:
+  - draughtsman-values
+`,
+			expectedManifest: Manifest{
+				Projects: []Project{
+					{Name: "aws-app-collection", Atomic: &atomic, Force: true, Providers: []string{"aws"}},
+					{Name: "draughtsman", Namespace: "giantswarm", ValuesKeys: []string{"draughtsman-values"}},
+				},
+			},
+		},
+		{
+			data:         "projects:\n- namespace: giantswarm\n",
+			errorMatcher: IsInvalidManifest,
+		},
+		{
+			data:         "projects:\n- name: api\n- name: api\n",
+			errorMatcher: IsInvalidManifest,
+		},
+		{
+			data:         "projects:\n- name: api\n  chart: oci://{{ .Host }}/giantswarm/api-chart\n",
+			errorMatcher: IsInvalidManifest,
+		},
+		{
+			data:         "projects: api",
+			errorMatcher: IsInvalidManifest,
+		},
+	}
+
+	for index, test := range tests {
+		manifest, err := ParseManifest([]byte(test.data))
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v: unexpected error returned: %#v", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+
+		if !reflect.DeepEqual(test.expectedManifest, manifest) {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedManifest, manifest)
+		}
+	}
+}
+
+// TestProject tests that defaults are applied to project settings.
+func TestProject(t *testing.T) {
+	manifest := Manifest{
+		Projects: []Project{
+			{Name: "draughtsman", Namespace: "giantswarm"},
+			{Name: "web", Release: "frontend"},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		expectedProject Project
+	}{
+		{
+			name:            "draughtsman",
+			expectedProject: Project{Name: "draughtsman", Namespace: "giantswarm", Release: "draughtsman"},
+		},
+		{
+			name:            "web",
+			expectedProject: Project{Name: "web", Namespace: DefaultNamespace, Release: "frontend"},
+		},
+		{
+			name:            "api",
+			expectedProject: Project{Name: "api", Namespace: DefaultNamespace, Release: "api"},
+		},
+	}
+
+	for index, test := range tests {
+		project := manifest.Project(test.name)
+
+		if !reflect.DeepEqual(test.expectedProject, project) {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedProject, project)
+		}
+	}
+}
+
+// TestProjectList tests that projects are selected by provider and
+// environment.
+func TestProjectList(t *testing.T) {
+	manifest := Manifest{
+		Projects: []Project{
+			{Name: "shared-app-collection"},
+			{Name: "aws-app-collection", Providers: []string{"aws"}},
+			{Name: "kvm-app-collection", Providers: []string{"kvm"}},
+			{Name: "canary", Providers: []string{"aws"}, Environments: []string{"gauss"}},
+		},
+	}
+
+	tests := []struct {
+		provider            string
+		environment         string
+		expectedProjectList []string
+	}{
+		{
+			provider:            "aws",
+			environment:         "gauss",
+			expectedProjectList: []string{"shared-app-collection", "aws-app-collection", "canary"},
+		},
+		{
+			provider:            "aws",
+			environment:         "ginger",
+			expectedProjectList: []string{"shared-app-collection", "aws-app-collection"},
+		},
+		{
+			provider:            "azure",
+			environment:         "gauss",
+			expectedProjectList: []string{"shared-app-collection"},
+		},
+	}
+
+	for index, test := range tests {
+		projectList := manifest.ProjectList(test.provider, test.environment)
+
+		if !reflect.DeepEqual(test.expectedProjectList, projectList) {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedProjectList, projectList)
+		}
+	}
+}
+
+// TestIsAtomic tests that projects override the default atomic mode.
+func TestIsAtomic(t *testing.T) {
+	enabled := true
+	disabled := false
+
+	tests := []struct {
+		project         Project
+		atomicByDefault bool
+		expectedAtomic  bool
+	}{
+		{project: Project{Name: "api"}, atomicByDefault: false, expectedAtomic: false},
+		{project: Project{Name: "api"}, atomicByDefault: true, expectedAtomic: true},
+		{project: Project{Name: "api", Atomic: &enabled}, atomicByDefault: false, expectedAtomic: true},
+		{project: Project{Name: "api", Atomic: &disabled}, atomicByDefault: true, expectedAtomic: false},
+	}
+
+	for index, test := range tests {
+		atomic := test.project.IsAtomic(test.atomicByDefault)
+
+		if atomic != test.expectedAtomic {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedAtomic, atomic)
+		}
+	}
+}
+
+// TestAllowsPayload tests that payloads may only request the namespaces and
+// values the project allows.
+func TestAllowsPayload(t *testing.T) {
+	project := Manifest{
+		Projects: []Project{
+			{
+				Name:              "api",
+				Namespace:         "giantswarm",
+				PayloadNamespaces: []string{"monitoring"},
+				PayloadValues:     []string{"image.tag"},
+			},
+		},
+	}.Project("api")
+
+	namespaces := map[string]bool{
+		"giantswarm":  true,
+		"monitoring":  true,
+		"kube-system": false,
+		"":            false,
+	}
+	for namespace, expected := range namespaces {
+		if allowed := project.AllowsPayloadNamespace(namespace); allowed != expected {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", namespace, expected, allowed)
+		}
+	}
+
+	values := map[string]bool{
+		"image.tag":  true,
+		"image":      false,
+		"replicas":   false,
+		"image.tag2": false,
+	}
+	for key, expected := range values {
+		if allowed := project.AllowsPayloadValue(key); allowed != expected {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", key, expected, allowed)
+		}
+	}
+
+	if (Project{Name: "web"}).AllowsPayloadValue("image.tag") {
+		t.Fatalf("expected projects to allow no payload values by default")
+	}
+}
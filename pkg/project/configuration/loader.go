@@ -0,0 +1,206 @@
+package configuration
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// DefaultKey is the default key of the manifest in the configmap.
+	DefaultKey = "projects.yaml"
+
+	// resyncPeriod is the interval in which the configmap is reloaded, even
+	// without changes.
+	resyncPeriod = 5 * time.Minute
+)
+
+// Config represents the configuration used to create a Loader.
+type Config struct {
+	// Dependencies.
+	KubernetesClient kubernetes.Interface
+	Logger           micrologger.Logger
+
+	// Settings.
+
+	// Environment and Provider select the projects listed by ProjectList.
+	Environment string
+	// Key is the key to reference the manifest in the configmap.
+	Key       string
+	Name      string
+	Namespace string
+	Provider  string
+}
+
+// DefaultConfig provides a default configuration to create a new Loader by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		KubernetesClient: nil,
+		Logger:           nil,
+
+		// Settings.
+		Environment: "",
+		Key:         DefaultKey,
+		Name:        "",
+		Namespace:   "",
+		Provider:    "",
+	}
+}
+
+// New creates a new configured Loader.
+func New(config Config) (*Loader, error) {
+	// Dependencies.
+	if config.KubernetesClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "kubernetes client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	// Settings.
+	if config.Key == "" {
+		return nil, microerror.Maskf(invalidConfigError, "key must not be empty")
+	}
+	if config.Name == "" {
+		return nil, microerror.Maskf(invalidConfigError, "name must not be empty")
+	}
+	if config.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "namespace must not be empty")
+	}
+
+	loader := &Loader{
+		// Dependencies.
+		kubernetesClient: config.KubernetesClient,
+		logger:           config.Logger,
+
+		// Internals.
+		mutex: &sync.RWMutex{},
+
+		// Settings.
+		environment: config.Environment,
+		key:         config.Key,
+		name:        config.Name,
+		namespace:   config.Namespace,
+		provider:    config.Provider,
+	}
+
+	return loader, nil
+}
+
+// Loader reads the project manifest from a configmap, and reloads it
+// whenever the configmap changes. It implements the ProjectLister interface
+// of the eventers, and the Getter interface.
+type Loader struct {
+	// Dependencies.
+	kubernetesClient kubernetes.Interface
+	logger           micrologger.Logger
+
+	// Internals.
+	manifest Manifest
+	mutex    *sync.RWMutex
+
+	// Settings.
+	environment string
+	key         string
+	name        string
+	namespace   string
+	provider    string
+}
+
+// Boot watches the configmap until the context is cancelled. It returns once
+// the configmap has been loaded for the first time, or found missing.
+func (l *Loader) Boot(ctx context.Context) error {
+	l.logger.Log("debug", "starting watching project manifest", "name", l.name, "namespace", l.namespace)
+
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		l.kubernetesClient,
+		resyncPeriod,
+		informers.WithNamespace(l.namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", l.name).String()
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: l.load,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			l.load(newObj)
+		},
+		// The last manifest is kept when the configmap is deleted, so that an
+		// accidental deletion does not stop deployments.
+		DeleteFunc: func(obj interface{}) {
+			l.logger.Log("error", "project manifest configmap was deleted, keeping last manifest", "name", l.name, "namespace", l.namespace)
+		},
+	})
+
+	go informer.Run(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return microerror.Maskf(executionFailedError, "project manifest configmap was not synced")
+	}
+
+	if len(informer.GetStore().List()) == 0 {
+		l.logger.Log("error", "project manifest configmap not found, no projects are deployed until it is created", "name", l.name, "namespace", l.namespace)
+	}
+
+	return nil
+}
+
+// Manifest returns the last loaded project manifest.
+func (l *Loader) Manifest() Manifest {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.manifest
+}
+
+// Project returns the settings of the project of the given name, with
+// defaults applied.
+func (l *Loader) Project(name string) Project {
+	return l.Manifest().Project(name)
+}
+
+// ProjectList returns the names of the projects deployed to the configured
+// provider and environment.
+func (l *Loader) ProjectList() []string {
+	return l.Manifest().ProjectList(l.provider, l.environment)
+}
+
+// load parses the manifest of the configmap. Invalid manifests are logged,
+// and the last manifest is kept.
+func (l *Loader) load(obj interface{}) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok || configMap.Name != l.name {
+		return
+	}
+
+	data, ok := configMap.Data[l.key]
+	if !ok {
+		l.logger.Log("error", "project manifest configmap is missing key, keeping last manifest", "key", l.key, "name", l.name)
+		return
+	}
+
+	manifest, err := ParseManifest([]byte(data))
+	if err != nil {
+		l.logger.Log("error", "could not parse project manifest, keeping last manifest", "name", l.name, "message", err.Error())
+		return
+	}
+
+	l.mutex.Lock()
+	l.manifest = manifest
+	l.mutex.Unlock()
+
+	l.logger.Log("debug", "loaded project manifest", "name", l.name, "projects", len(manifest.Projects), "resourceVersion", configMap.ResourceVersion)
+}
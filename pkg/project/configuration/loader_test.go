@@ -0,0 +1,82 @@
+package configuration
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestLoaderReload tests that the manifest is loaded on boot, reloaded when
+// the configmap changes, and kept when the change is invalid.
+func TestLoaderReload(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "draughtsman-projects",
+			Namespace: "draughtsman",
+		},
+		Data: map[string]string{
+			DefaultKey: "projects:\n- name: api\n",
+		},
+	}
+
+	client := fake.NewSimpleClientset(configMap)
+
+	c := DefaultConfig()
+
+	c.KubernetesClient = client
+	c.Logger = microloggertest.New()
+
+	c.Name = "draughtsman-projects"
+	c.Namespace = "draughtsman"
+
+	l, err := New(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := l.Boot(ctx); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	if projectList := l.ProjectList(); !reflect.DeepEqual([]string{"api"}, projectList) {
+		t.Fatalf("expected manifest to be loaded on boot, returned: %#v", projectList)
+	}
+
+	update := func(data string) {
+		configMap = configMap.DeepCopy()
+		configMap.Data[DefaultKey] = data
+
+		if _, err := client.CoreV1().ConfigMaps("draughtsman").Update(configMap); err != nil {
+			t.Fatalf("expected nil error, returned: %#v", err)
+		}
+	}
+
+	update("projects:\n- name: api\n- name: web\n  force: true\n")
+
+	expectedProjectList := []string{"api", "web"}
+	for start := time.Now(); !reflect.DeepEqual(expectedProjectList, l.ProjectList()); {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("expected manifest to be reloaded, returned: %#v", l.ProjectList())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !l.Project("web").Force {
+		t.Fatalf("expected settings of reloaded manifest, returned: %#v", l.Project("web"))
+	}
+
+	update("projects: invalid")
+	time.Sleep(100 * time.Millisecond)
+
+	if projectList := l.ProjectList(); !reflect.DeepEqual(expectedProjectList, projectList) {
+		t.Fatalf("expected last manifest to be kept, returned: %#v", projectList)
+	}
+}
@@ -0,0 +1,23 @@
+package registryauth
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidChallengeError = &microerror.Error{
+	Kind: "invalidChallengeError",
+}
+
+// IsInvalidChallenge asserts invalidChallengeError.
+func IsInvalidChallenge(err error) bool {
+	return microerror.Cause(err) == invalidChallengeError
+}
+
+var unexpectedStatusCodeError = &microerror.Error{
+	Kind: "unexpectedStatusCodeError",
+}
+
+// IsUnexpectedStatusCode asserts unexpectedStatusCodeError.
+func IsUnexpectedStatusCode(err error) bool {
+	return microerror.Cause(err) == unexpectedStatusCodeError
+}
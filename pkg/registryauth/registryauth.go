@@ -0,0 +1,126 @@
+// Package registryauth implements the token authentication of OCI
+// registries, shared by everything that talks to them.
+// See: https://docs.docker.com/registry/spec/auth/token/
+package registryauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// BearerPrefix is the prefix of bearer authentication challenges, and of
+	// the authorization header of requests authenticated with a token.
+	BearerPrefix = "Bearer "
+
+	// defaultExpiresIn is the lifetime of tokens whose token service does not
+	// return one, as defined by the token authentication specification.
+	defaultExpiresIn = 60 * time.Second
+	// refreshBefore is the time before their expiry that tokens are no longer
+	// used, so that they do not expire while a request is made.
+	refreshBefore = 10 * time.Second
+)
+
+// challengeParameterRegexp matches the parameters of an authentication
+// challenge, e.g: realm="https://quay.io/v2/auth".
+var challengeParameterRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// DoFunc makes a GET request to the given URL of a token service, accepting
+// JSON, and authenticated as the caller sees fit.
+type DoFunc func(u string) (*http.Response, error)
+
+// tokenResponse represents the response of a registry token service.
+type tokenResponse struct {
+	// Token is the bearer token.
+	Token string `json:"token"`
+
+	// AccessToken is the bearer token, as returned by OAuth 2.0 compatible
+	// token services.
+	AccessToken string `json:"access_token"`
+
+	// ExpiresIn is the number of seconds the token is valid for, after it
+	// was issued.
+	ExpiresIn int `json:"expires_in"`
+
+	// IssuedAt is the time the token was issued at.
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// Token is a bearer token, as issued by a registry token service.
+type Token struct {
+	// Value is the bearer token, as set in the authorization header.
+	Value string
+
+	// ExpiresAt is the time the token expires at.
+	ExpiresAt time.Time
+}
+
+// Valid returns true if the token is set, and does not expire soon.
+func (t Token) Valid() bool {
+	return t.Value != "" && time.Now().Add(refreshBefore).Before(t.ExpiresAt)
+}
+
+// FetchToken fetches a bearer token from the token service named by the
+// given authentication challenge, e.g: Bearer realm="https://quay.io/v2/auth",
+// service="quay.io",scope="repository:giantswarm/api-chart:pull". The token
+// service is requested with do.
+func FetchToken(challenge string, do DoFunc) (Token, error) {
+	if !strings.HasPrefix(challenge, BearerPrefix) {
+		return Token{}, microerror.Maskf(invalidChallengeError, "received unsupported authentication challenge: %q", challenge)
+	}
+
+	parameters := map[string]string{}
+	for _, match := range challengeParameterRegexp.FindAllStringSubmatch(challenge, -1) {
+		parameters[match[1]] = match[2]
+	}
+	if parameters["realm"] == "" {
+		return Token{}, microerror.Maskf(invalidChallengeError, "received authentication challenge without realm: %q", challenge)
+	}
+
+	query := url.Values{}
+	for _, key := range []string{"scope", "service"} {
+		if parameters[key] != "" {
+			query.Set(key, parameters[key])
+		}
+	}
+
+	resp, err := do(parameters["realm"] + "?" + query.Encode())
+	if err != nil {
+		return Token{}, microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Token{}, microerror.Maskf(unexpectedStatusCodeError, "received status code from token service: %v", resp.StatusCode)
+	}
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return Token{}, microerror.Mask(err)
+	}
+
+	token := Token{
+		Value: tr.Token,
+	}
+	if token.Value == "" {
+		token.Value = tr.AccessToken
+	}
+
+	issuedAt := tr.IssuedAt
+	if issuedAt.IsZero() {
+		issuedAt = time.Now()
+	}
+	expiresIn := time.Duration(tr.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = defaultExpiresIn
+	}
+	token.ExpiresAt = issuedAt.Add(expiresIn)
+
+	return token, nil
+}
@@ -0,0 +1,100 @@
+package registryauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestFetchToken tests the FetchToken function.
+func TestFetchToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:giantswarm/api-chart:pull" || r.URL.Query().Get("service") != "registry" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/token":
+			fmt.Fprint(w, `{"token": "token"}`)
+		case "/oauth":
+			fmt.Fprint(w, `{"access_token": "access-token"}`)
+		case "/expiring":
+			fmt.Fprint(w, `{"token": "token", "expires_in": 300, "issued_at": "2020-01-01T00:00:00Z"}`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		challenge         string
+		expectedToken     string
+		expectedExpiresAt time.Time
+		errorMatcher      func(error) bool
+	}{
+		// Test that the token is fetched from the realm of the challenge.
+		{
+			challenge:     fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:giantswarm/api-chart:pull"`, server.URL),
+			expectedToken: "token",
+		},
+
+		// Test that OAuth 2.0 compatible token services are supported.
+		{
+			challenge:     fmt.Sprintf(`Bearer realm="%s/oauth",service="registry",scope="repository:giantswarm/api-chart:pull"`, server.URL),
+			expectedToken: "access-token",
+		},
+
+		// Test that the expiry of the token is returned.
+		{
+			challenge:         fmt.Sprintf(`Bearer realm="%s/expiring",service="registry",scope="repository:giantswarm/api-chart:pull"`, server.URL),
+			expectedToken:     "token",
+			expectedExpiresAt: time.Date(2020, 1, 1, 0, 5, 0, 0, time.UTC),
+		},
+
+		// Test that failing token services are reported.
+		{
+			challenge:    fmt.Sprintf(`Bearer realm="%s/denied",service="registry",scope="repository:giantswarm/api-chart:pull"`, server.URL),
+			errorMatcher: IsUnexpectedStatusCode,
+		},
+
+		// Test that other challenges are rejected.
+		{
+			challenge:    `Basic realm="registry"`,
+			errorMatcher: IsInvalidChallenge,
+		},
+
+		// Test that challenges without realm are rejected.
+		{
+			challenge:    `Bearer service="registry"`,
+			errorMatcher: IsInvalidChallenge,
+		},
+	}
+
+	for index, test := range tests {
+		token, err := FetchToken(test.challenge, http.Get)
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected error, returned: %#v\n", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+		}
+
+		if token.Value != test.expectedToken {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedToken, token.Value)
+		}
+
+		// Tokens without expiry are valid for the default of 60 seconds.
+		if test.expectedExpiresAt.IsZero() && !token.Valid() {
+			t.Fatalf("%v\nexpected valid token, returned: %#v\n", index, token)
+		}
+		if !test.expectedExpiresAt.IsZero() && !test.expectedExpiresAt.Equal(token.ExpiresAt) {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedExpiresAt, token.ExpiresAt)
+		}
+	}
+}
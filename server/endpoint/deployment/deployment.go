@@ -0,0 +1,161 @@
+package deployment
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/giantswarm/draughtsman/service/deployer/history"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "deployment"
+	// Path is the HTTP request path this endpoint is registered for. It is
+	// linked from the deployment statuses as log URL.
+	Path = "/deployments/{project}/{id:[0-9]+}"
+
+	// bearerPrefix is the prefix of the authorization header of requests
+	// authenticated with the token.
+	bearerPrefix = "Bearer "
+	// descriptionMaxLength is the length descriptions are truncated to for
+	// requests that are not authenticated, as failed deployments describe
+	// the full error of Helm.
+	descriptionMaxLength = 140
+)
+
+// Config represents the configuration used to create a deployment endpoint.
+type Config struct {
+	// Dependencies.
+	History *history.History
+	Logger  micrologger.Logger
+
+	// Settings.
+
+	// Token is the bearer token required to read the full output and
+	// description of deployments. Without it, the output is never served.
+	Token string
+}
+
+// New creates a new configured deployment endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.History == nil {
+		return nil, microerror.Maskf(invalidConfigError, "history must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newEndpoint := &Endpoint{
+		Config: config,
+	}
+
+	return newEndpoint, nil
+}
+
+// Endpoint serves the details of a recent deployment. The endpoint is not
+// authenticated, as it is linked from the deployment statuses, so the output
+// of failed installations, which may contain rendered values, is only served
+// to requests authenticated with the configured token.
+type Endpoint struct {
+	Config
+}
+
+// Request identifies the requested deployment.
+type Request struct {
+	// Authorized is true if the request is authenticated with the token.
+	Authorized bool
+	ID         int
+	Project    string
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		vars := mux.Vars(r)
+
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		request := Request{
+			Authorized: e.authorized(r.Header.Get("Authorization")),
+			ID:         id,
+			Project:    vars["project"],
+		}
+
+		return request, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return kithttp.EncodeJSONResponse
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r := request.(Request)
+
+		record, ok := e.History.Get(r.Project, r.ID)
+		if !ok {
+			return nil, microerror.Maskf(notFoundError, "deployment %d of project %#q not found", r.ID, r.Project)
+		}
+
+		if !r.Authorized {
+			record.Description = truncateDescription(record.Description)
+			record.Output = ""
+		}
+
+		return record, nil
+	}
+}
+
+// authorized returns true if the given authorization header holds the
+// token. Requests are never authorized if no token is configured.
+func (e *Endpoint) authorized(header string) bool {
+	if e.Token == "" || !strings.HasPrefix(header, bearerPrefix) {
+		return false
+	}
+
+	token := strings.TrimPrefix(header, bearerPrefix)
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(e.Token)) == 1
+}
+
+// truncateDescription truncates the description to descriptionMaxLength,
+// marking it as truncated.
+func truncateDescription(description string) string {
+	runes := []rune(description)
+	if len(runes) <= descriptionMaxLength {
+		return description
+	}
+
+	return string(runes[:descriptionMaxLength-1]) + "…"
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
@@ -5,7 +5,10 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 
+	"github.com/giantswarm/draughtsman/server/endpoint/deployment"
+	"github.com/giantswarm/draughtsman/server/endpoint/webhook"
 	"github.com/giantswarm/draughtsman/service"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
 )
 
 // Config represents the configuration used to create a endpoint.
@@ -13,11 +16,17 @@ type Config struct {
 	// Dependencies.
 	Logger  micrologger.Logger
 	Service *service.Service
+
+	// Settings.
+	DeploymentToken string
 }
 
 // Endpoint is the endpoint collection.
 type Endpoint struct {
-	Version *version.Endpoint
+	Deployment *deployment.Endpoint
+	Version    *version.Endpoint
+	// Webhook is only set when the configured Eventer receives webhooks.
+	Webhook *webhook.Endpoint
 }
 
 // New creates a new configured endpoint.
@@ -31,6 +40,21 @@ func New(config Config) (*Endpoint, error) {
 
 	var err error
 
+	var deploymentEndpoint *deployment.Endpoint
+	{
+		c := deployment.Config{
+			History: config.Service.History,
+			Logger:  config.Logger,
+
+			Token: config.DeploymentToken,
+		}
+
+		deploymentEndpoint, err = deployment.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var versionEndpoint *version.Endpoint
 	{
 		c := version.Config{
@@ -44,8 +68,23 @@ func New(config Config) (*Endpoint, error) {
 		}
 	}
 
+	var webhookEndpoint *webhook.Endpoint
+	if handler, ok := config.Service.Eventer.(eventerspec.WebhookHandler); ok {
+		c := webhook.Config{
+			Handler: handler,
+			Logger:  config.Logger,
+		}
+
+		webhookEndpoint, err = webhook.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	endpoint := &Endpoint{
-		Version: versionEndpoint,
+		Deployment: deploymentEndpoint,
+		Version:    versionEndpoint,
+		Webhook:    webhookEndpoint,
 	}
 
 	return endpoint, nil
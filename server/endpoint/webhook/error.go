@@ -0,0 +1,23 @@
+package webhook
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var bodyTooLargeError = &microerror.Error{
+	Kind: "bodyTooLargeError",
+}
+
+// IsBodyTooLarge asserts bodyTooLargeError.
+func IsBodyTooLarge(err error) bool {
+	return microerror.Cause(err) == bodyTooLargeError
+}
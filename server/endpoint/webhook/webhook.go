@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "POST"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "webhook"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/webhook"
+
+	// maxBodySize is the maximum size of webhook deliveries read. GitHub caps
+	// webhook payloads at 25 MB.
+	// See: https://developer.github.com/webhooks/#payloads
+	maxBodySize = 25 * 1024 * 1024
+)
+
+// Config represents the configuration used to create a webhook endpoint.
+type Config struct {
+	// Dependencies.
+	Handler eventerspec.WebhookHandler
+	Logger  micrologger.Logger
+}
+
+// New creates a new configured webhook endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Handler == nil {
+		return nil, microerror.Maskf(invalidConfigError, "handler must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newEndpoint := &Endpoint{
+		Config: config,
+	}
+
+	return newEndpoint, nil
+}
+
+// Endpoint receives webhook deliveries and forwards them to the configured
+// webhook handler, usually an Eventer.
+type Endpoint struct {
+	Config
+}
+
+// Request is the decoded webhook delivery.
+type Request struct {
+	Body   []byte
+	Header http.Header
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		// There is no response writer in decoders, which MaxBytesReader only
+		// uses to close the connection after the limit was hit.
+		body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, microerror.Maskf(bodyTooLargeError, "body exceeds %d bytes", maxBodySize)
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		request := Request{
+			Body:   body,
+			Header: r.Header,
+		}
+
+		return request, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		w.WriteHeader(http.StatusAccepted)
+
+		return nil
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r := request.(Request)
+
+		err := e.Handler.HandleWebhook(ctx, r.Header, r.Body)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return nil, nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
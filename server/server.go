@@ -13,7 +13,10 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/giantswarm/draughtsman/server/endpoint"
+	"github.com/giantswarm/draughtsman/server/endpoint/deployment"
+	"github.com/giantswarm/draughtsman/server/endpoint/webhook"
 	"github.com/giantswarm/draughtsman/service"
+	"github.com/giantswarm/draughtsman/service/eventer/github"
 )
 
 // Config represents the configuration used to construct server object.
@@ -22,7 +25,10 @@ type Config struct {
 	Service *service.Service
 	Viper   *viper.Viper
 
-	ProjectName string
+	// DeploymentToken is the bearer token required to read the full output
+	// of deployments. The output is not served if it is empty.
+	DeploymentToken string
+	ProjectName     string
 }
 
 // New creates a new configured server object.
@@ -48,6 +54,8 @@ func New(config Config) (microserver.Server, error) {
 		c := endpoint.Config{
 			Logger:  config.Logger,
 			Service: config.Service,
+
+			DeploymentToken: config.DeploymentToken,
 		}
 
 		endpointCollection, err = endpoint.New(c)
@@ -56,19 +64,26 @@ func New(config Config) (microserver.Server, error) {
 		}
 	}
 
+	endpoints := []microserver.Endpoint{
+		endpointCollection.Deployment,
+		endpointCollection.Version,
+	}
+	if endpointCollection.Webhook != nil {
+		endpoints = append(endpoints, endpointCollection.Webhook)
+	}
+
 	newServer := &Server{
 		// Dependencies
-		logger: config.Logger,
+		logger:  config.Logger,
+		service: config.Service,
 
 		// Internals
 		bootOnce: sync.Once{},
 		config: microserver.Config{
-			Logger:      config.Logger,
-			ServiceName: config.ProjectName,
-			Viper:       config.Viper,
-			Endpoints: []microserver.Endpoint{
-				endpointCollection.Version,
-			},
+			Logger:       config.Logger,
+			ServiceName:  config.ProjectName,
+			Viper:        config.Viper,
+			Endpoints:    endpoints,
 			ErrorEncoder: errorEncoder,
 		},
 		shutdownOnce: sync.Once{},
@@ -79,7 +94,8 @@ func New(config Config) (microserver.Server, error) {
 
 type Server struct {
 	// Dependencies
-	logger micrologger.Logger
+	logger  micrologger.Logger
+	service *service.Service
 
 	// Internals
 	bootOnce     sync.Once
@@ -98,9 +114,15 @@ func (s *Server) Config() microserver.Config {
 	return s.config
 }
 
+// Shutdown shuts down the service, and blocks until it is done, including the
+// deployment being installed, if any.
 func (s *Server) Shutdown() {
 	s.shutdownOnce.Do(func() {
-		// Insert here custom shutdown logic for server/endpoint if needed.
+		s.logger.Log("debug", "shutting down service")
+
+		s.service.Shutdown()
+
+		s.logger.Log("debug", "shut down service")
 	})
 }
 
@@ -108,7 +130,26 @@ func errorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
 	rErr := err.(microserver.ResponseError)
 	uErr := rErr.Underlying()
 
-	rErr.SetCode(microserver.CodeInternalError)
-	rErr.SetMessage(uErr.Error())
-	w.WriteHeader(http.StatusInternalServerError)
+	switch {
+	case deployment.IsNotFound(uErr):
+		rErr.SetCode(microserver.CodeResourceNotFound)
+		rErr.SetMessage(uErr.Error())
+		w.WriteHeader(http.StatusNotFound)
+	case github.IsInvalidSignature(uErr):
+		rErr.SetCode(microserver.CodeInvalidCredentials)
+		rErr.SetMessage(uErr.Error())
+		w.WriteHeader(http.StatusUnauthorized)
+	case webhook.IsBodyTooLarge(uErr):
+		rErr.SetCode(microserver.CodeInvalidInput)
+		rErr.SetMessage(uErr.Error())
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case github.IsQueueFull(uErr):
+		rErr.SetCode(microserver.CodeTooManyRequests)
+		rErr.SetMessage(uErr.Error())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		rErr.SetCode(microserver.CodeInternalError)
+		rErr.SetMessage(uErr.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 }
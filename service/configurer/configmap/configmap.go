@@ -103,19 +103,28 @@ func (c *ConfigMapConfigurer) Type() spec.ConfigurerType {
 }
 
 func (c *ConfigMapConfigurer) Values() (string, error) {
-	defer updateConfigMapMetrics(time.Now())
-
-	c.logger.Log("debug", "fetching configuration from configmap", "name", c.name, "namespace", c.namespace)
-
-	cm, err := c.kubernetesClient.CoreV1().ConfigMaps(c.namespace).Get(c.name, v1.GetOptions{})
+	valuesData, ok, err := c.KeyValues(c.key)
 	if err != nil {
 		return "", microerror.Mask(err)
 	}
-
-	valuesData, ok := cm.Data[c.key]
 	if !ok {
 		return "", microerror.Maskf(keyMissingError, "key '%v' not found in configmap", c.key)
 	}
 
 	return valuesData, nil
 }
+
+func (c *ConfigMapConfigurer) KeyValues(key string) (string, bool, error) {
+	defer updateConfigMapMetrics(time.Now())
+
+	c.logger.Log("debug", "fetching configuration from configmap", "name", c.name, "namespace", c.namespace, "key", key)
+
+	cm, err := c.kubernetesClient.CoreV1().ConfigMaps(c.namespace).Get(c.name, v1.GetOptions{})
+	if err != nil {
+		return "", false, microerror.Mask(err)
+	}
+
+	valuesData, ok := cm.Data[key]
+
+	return valuesData, ok, nil
+}
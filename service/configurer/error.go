@@ -12,3 +12,12 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var valuesKeyMissingError = &microerror.Error{
+	Kind: "valuesKeyMissingError",
+}
+
+// IsValuesKeyMissing asserts valuesKeyMissingError.
+func IsValuesKeyMissing(err error) bool {
+	return microerror.Cause(err) == valuesKeyMissingError
+}
@@ -104,23 +104,28 @@ func (c *SecretConfigurer) Type() spec.ConfigurerType {
 }
 
 func (c *SecretConfigurer) Values() (string, error) {
+	valuesData, ok, err := c.KeyValues(c.key)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if !ok {
+		return "", microerror.Maskf(keyMissingError, "key '%v' not found in secret", c.key)
+	}
+
+	return valuesData, nil
+}
+
+func (c *SecretConfigurer) KeyValues(key string) (string, bool, error) {
 	defer updateSecretMetrics(time.Now())
 
-	c.logger.Log("debug", "fetching configuration from secret", "name", c.name, "namespace", c.namespace)
+	c.logger.Log("debug", "fetching configuration from secret", "name", c.name, "namespace", c.namespace, "key", key)
 
 	s, err := c.kubernetesClient.CoreV1().Secrets(c.namespace).Get(c.name, v1.GetOptions{})
 	if err != nil {
-		return "", microerror.Mask(err)
+		return "", false, microerror.Mask(err)
 	}
 
-	var valuesData string
-	{
-		b, ok := s.Data[c.key]
-		if !ok {
-			return "", microerror.Maskf(keyMissingError, "key '%v' not found in secret", c.key)
-		}
-		valuesData = string(b)
-	}
+	b, ok := s.Data[key]
 
-	return valuesData, nil
+	return string(b), ok, nil
 }
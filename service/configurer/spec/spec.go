@@ -12,3 +12,12 @@ type Configurer interface {
 	// system.
 	Values() (string, error)
 }
+
+// KeyedConfigurer represents a Configurer that holds Helm values under
+// multiple keys, of which projects may select the ones they use.
+type KeyedConfigurer interface {
+	Configurer
+	// KeyValues returns the content of the values file under the given key.
+	// It returns false if the key does not exist.
+	KeyValues(key string) (string, bool, error)
+}
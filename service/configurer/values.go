@@ -0,0 +1,65 @@
+package configurer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/draughtsman/service/configurer/spec"
+)
+
+// Values represents the Helm values provided by a Configurer.
+type Values struct {
+	// Name identifies the values, e.g: configmapconfigurer, and is unique
+	// among the values of a project.
+	Name string
+	// Data is the content of the values, as a YAML document.
+	Data string
+}
+
+// ProjectValues returns the values of all configurers, in order. Configurers
+// holding values under multiple keys provide the values under the given
+// values keys of the project instead, if it has any. Keys that no configurer
+// holds are an error.
+func ProjectValues(configurers []spec.Configurer, valuesKeys []string) ([]Values, error) {
+	var values []Values
+	var keyedConfigurers bool
+	foundKeys := map[string]bool{}
+	for _, c := range configurers {
+		name := strings.ToLower(string(c.Type()))
+
+		keyed, ok := c.(spec.KeyedConfigurer)
+		if !ok || len(valuesKeys) == 0 {
+			data, err := c.Values()
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			values = append(values, Values{Name: name, Data: data})
+			continue
+		}
+
+		keyedConfigurers = true
+		for index, key := range valuesKeys {
+			data, ok, err := keyed.KeyValues(key)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			if !ok {
+				continue
+			}
+
+			values = append(values, Values{Name: fmt.Sprintf("%s-%d", name, index), Data: data})
+			foundKeys[key] = true
+		}
+	}
+
+	for _, key := range valuesKeys {
+		if keyedConfigurers && !foundKeys[key] {
+			return nil, microerror.Maskf(valuesKeyMissingError, "values key %#q not found in any configurer", key)
+		}
+	}
+
+	return values, nil
+}
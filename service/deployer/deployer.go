@@ -1,15 +1,21 @@
 package deployer
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 
 	"github.com/giantswarm/draughtsman/flag"
-	"github.com/giantswarm/draughtsman/service/eventer"
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	"github.com/giantswarm/draughtsman/service/deployer/history"
 	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
 	httpspec "github.com/giantswarm/draughtsman/service/http"
 	"github.com/giantswarm/draughtsman/service/installer"
@@ -25,11 +31,20 @@ type DeployerType string
 // Config represents the configuration used to create a Deployer.
 type Config struct {
 	// Dependencies.
+	Eventer          eventerspec.Eventer
 	FileSystem       afero.Fs
+	History          *history.History
 	HTTPClient       httpspec.Client
 	KubernetesClient kubernetes.Interface
 	Logger           micrologger.Logger
-	SlackClient      slackspec.Client
+	// ProjectConfiguration looks up the settings of the deployed projects.
+	ProjectConfiguration configuration.Getter
+	// ProjectLister lists the projects whose releases are checked by the
+	// Installer.
+	ProjectLister eventerspec.ProjectLister
+	// RESTConfig is the config of the cluster the Installer installs to.
+	RESTConfig  *rest.Config
+	SlackClient slackspec.Client
 
 	// Settings.
 	Flag  *flag.Flag
@@ -42,11 +57,16 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		// Dependencies.
-		FileSystem:       afero.NewMemMapFs(),
-		HTTPClient:       nil,
-		KubernetesClient: nil,
-		Logger:           nil,
-		SlackClient:      nil,
+		Eventer:              nil,
+		FileSystem:           afero.NewMemMapFs(),
+		History:              nil,
+		HTTPClient:           nil,
+		KubernetesClient:     nil,
+		Logger:               nil,
+		ProjectConfiguration: nil,
+		ProjectLister:        nil,
+		RESTConfig:           nil,
+		SlackClient:          nil,
 
 		// Settings.
 		Flag:  nil,
@@ -58,6 +78,12 @@ func DefaultConfig() Config {
 // New creates a new configured Deployer.
 func New(config Config) (Deployer, error) {
 	// Dependencies.
+	if config.Eventer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "eventer must not be empty")
+	}
+	if config.History == nil {
+		return nil, microerror.Maskf(invalidConfigError, "history must not be empty")
+	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
 	}
@@ -72,31 +98,17 @@ func New(config Config) (Deployer, error) {
 
 	var err error
 
-	var eventerService eventerspec.Eventer
-	{
-		eventerConfig := eventer.DefaultConfig()
-
-		eventerConfig.HTTPClient = config.HTTPClient
-		eventerConfig.Logger = config.Logger
-
-		eventerConfig.Flag = config.Flag
-		eventerConfig.Viper = config.Viper
-
-		eventerConfig.Type = eventerspec.EventerType(config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Type))
-
-		eventerService, err = eventer.New(eventerConfig)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-	}
-
 	var installerService installerspec.Installer
 	{
 		installerConfig := installer.DefaultConfig()
 
 		installerConfig.FileSystem = config.FileSystem
+		installerConfig.HTTPClient = config.HTTPClient
 		installerConfig.KubernetesClient = config.KubernetesClient
 		installerConfig.Logger = config.Logger
+		installerConfig.ProjectConfiguration = config.ProjectConfiguration
+		installerConfig.ProjectLister = config.ProjectLister
+		installerConfig.RESTConfig = config.RESTConfig
 
 		installerConfig.Flag = config.Flag
 		installerConfig.Viper = config.Viper
@@ -132,10 +144,15 @@ func New(config Config) (Deployer, error) {
 	case StandardDeployer:
 		newService = &standardDeployer{
 			// Dependencies.
-			eventer:   eventerService,
+			eventer:   config.Eventer,
+			history:   config.History,
 			installer: installerService,
 			logger:    config.Logger,
 			notifier:  notifierService,
+
+			// Settings.
+			environmentURL: config.Viper.GetString(config.Flag.Service.Deployer.Status.EnvironmentURL),
+			externalURL:    strings.TrimSuffix(config.Viper.GetString(config.Flag.Service.Deployer.Status.ExternalURL), "/"),
 		}
 	default:
 		return nil, microerror.Maskf(invalidConfigError, "could not find deployer type")
@@ -146,50 +163,138 @@ func New(config Config) (Deployer, error) {
 
 var StandardDeployer DeployerType = "StandardDeployer"
 
+const (
+	// logURLFormat is the string format for the URL of the details of a
+	// deployment, relative to the external URL of draughtsman.
+	logURLFormat = "%s/deployments/%s/%d"
+
+	// queueSize is the number of deployment events that are queued while
+	// another deployment is being installed.
+	queueSize = 100
+)
+
 // standardDeployer is an implementation of the Deployer interface.
 type standardDeployer struct {
 	// Dependencies.
 	eventer   eventerspec.Eventer
+	history   *history.History
 	installer installerspec.Installer
 	logger    micrologger.Logger
 	notifier  notifierspec.Notifier
+
+	// Settings.
+	environmentURL string
+	externalURL    string
 }
 
-// Boot starts the deployer.
-func (s *standardDeployer) Boot() {
+// Boot starts the deployer. It runs until the context is cancelled, and
+// then waits for the deployment being installed, if any. Deployments that
+// are still queued are not finished, and eventers emit unfinished
+// deployments again after a restart.
+func (s *standardDeployer) Boot(ctx context.Context) {
 	s.logger.Log("debug", "starting deployer")
 
-	deploymentEventChannel, err := s.eventer.NewDeploymentEvents()
+	deploymentEventChannel, err := s.eventer.NewDeploymentEvents(ctx)
 	if err != nil {
-		s.logger.Log("debug", "could not get deployment event channel", "message", err.Error())
+		s.logger.Log("error", "could not get deployment event channel", "message", err.Error())
+		return
 	}
 
-	for deploymentEvent := range deploymentEventChannel {
-		if err := s.eventer.SetPending(deploymentEvent); err != nil {
-			s.logger.Log("error", "could not set pending event", "message", err.Error())
-		}
+	// Deployment events are queued, so that they are reported as queued
+	// while another deployment is being installed.
+	queue := make(chan eventerspec.DeploymentEvent, queueSize)
+	go func() {
+		defer close(queue)
 
-		installErr := s.installer.Install(deploymentEvent)
-		if installErr == nil {
-			if err := s.eventer.SetSuccess(deploymentEvent); err != nil {
-				s.logger.Log("error", "could not set success event", "message", err.Error())
-			}
+		for deploymentEvent := range deploymentEventChannel {
+			s.setStatus(deploymentEvent, eventerspec.QueuedState, "Queued for installation", "")
 
-			if err := s.notifier.Success(deploymentEvent); err != nil {
-				s.logger.Log("error", "could not notify of success", "message", err.Error())
+			select {
+			case queue <- deploymentEvent:
+			case <-ctx.Done():
+				return
 			}
-		} else {
-			s.logger.Log("error", "could not install chart", "message", installErr.Error())
+		}
+	}()
 
-			if err := s.eventer.SetFailed(deploymentEvent); err != nil {
-				s.logger.Log("error", "could not set failed event", "message", err.Error())
-			}
+	// Installs are not cancelled with the context, as the install in flight
+	// is waited for on shutdown.
+	installCtx := context.Background()
 
-			if err := s.notifier.Failed(deploymentEvent, installErr.Error()); err != nil {
-				s.logger.Log("error", "could not notify of failure", "message", err.Error())
-			}
+	for deploymentEvent := range queue {
+		if ctx.Err() != nil {
+			break
 		}
+
+		s.install(installCtx, deploymentEvent)
 	}
 
 	s.logger.Log("debug", "finished deployment loop")
 }
+
+// install installs the deployment, and reports its outcome.
+func (s *standardDeployer) install(ctx context.Context, deploymentEvent eventerspec.DeploymentEvent) {
+	s.setStatus(deploymentEvent, eventerspec.InProgressState, describe(deploymentEvent, "Installing %s", "Rolling back"), "")
+
+	installErr := s.installer.Install(ctx, deploymentEvent)
+	if installErr == nil {
+		s.setStatus(deploymentEvent, eventerspec.SuccessState, describe(deploymentEvent, "Installed %s", "Rolled back"), "")
+
+		if err := s.notifier.Success(deploymentEvent); err != nil {
+			s.logger.Log("error", "could not notify of success", "message", err.Error())
+		}
+	} else {
+		s.logger.Log("error", "could not install chart", "message", installErr.Error())
+
+		s.setStatus(deploymentEvent, eventerspec.FailureState, describe(deploymentEvent, "Installation failed: %s", "Rollback failed: %s", installErr.Error()), installErr.Error())
+
+		if err := s.notifier.Failed(deploymentEvent, installErr.Error()); err != nil {
+			s.logger.Log("error", "could not notify of failure", "message", err.Error())
+		}
+	}
+}
+
+// describe returns the status description of the deployment, using the
+// format matching its task. The format of deploy tasks is given the sha,
+// unless other arguments are given.
+func describe(event eventerspec.DeploymentEvent, deployFormat, rollbackFormat string, args ...interface{}) string {
+	format := deployFormat
+	if event.Task == eventerspec.RollbackTask {
+		format = rollbackFormat
+	} else if len(args) == 0 {
+		args = []interface{}{event.Sha}
+	}
+
+	description := fmt.Sprintf(format, args...)
+	if event.Payload.DryRun {
+		description += " (dry run)"
+	}
+
+	return description
+}
+
+// setStatus records the status of the deployment, and reports it to the
+// eventer. The output is optional, and only recorded.
+func (s *standardDeployer) setStatus(event eventerspec.DeploymentEvent, state eventerspec.DeploymentState, description, output string) {
+	s.history.Set(history.Record{
+		ID:          event.ID,
+		Name:        event.Name,
+		Sha:         event.Sha,
+		State:       state,
+		Description: description,
+		Output:      output,
+	})
+
+	status := eventerspec.DeploymentStatus{
+		State:          state,
+		Description:    description,
+		EnvironmentURL: s.environmentURL,
+	}
+	if s.externalURL != "" {
+		status.LogURL = fmt.Sprintf(logURLFormat, s.externalURL, event.Name, event.ID)
+	}
+
+	if err := s.eventer.SetStatus(event, status); err != nil {
+		s.logger.Log("error", "could not set deployment status", "state", state, "message", err.Error())
+	}
+}
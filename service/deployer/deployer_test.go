@@ -0,0 +1,127 @@
+package deployer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/draughtsman/service/deployer/history"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// testEventer is an Eventer emitting the given events, and recording the
+// states set.
+type testEventer struct {
+	events []eventerspec.DeploymentEvent
+	states chan eventerspec.DeploymentState
+}
+
+func (e *testEventer) NewDeploymentEvents(ctx context.Context) (<-chan eventerspec.DeploymentEvent, error) {
+	c := make(chan eventerspec.DeploymentEvent)
+
+	go func() {
+		defer close(c)
+
+		for _, event := range e.events {
+			c <- event
+		}
+
+		<-ctx.Done()
+	}()
+
+	return c, nil
+}
+
+func (e *testEventer) SetStatus(event eventerspec.DeploymentEvent, status eventerspec.DeploymentStatus) error {
+	e.states <- status.State
+	return nil
+}
+
+// testInstaller is an Installer that blocks until it is released.
+type testInstaller struct {
+	installed []string
+	release   chan struct{}
+	started   chan struct{}
+}
+
+func (i *testInstaller) Install(ctx context.Context, event eventerspec.DeploymentEvent) error {
+	i.started <- struct{}{}
+	<-i.release
+
+	i.installed = append(i.installed, event.Name)
+
+	return nil
+}
+
+// testNotifier is a Notifier that does nothing.
+type testNotifier struct{}
+
+func (n *testNotifier) Success(event eventerspec.DeploymentEvent) error {
+	return nil
+}
+
+func (n *testNotifier) Failed(event eventerspec.DeploymentEvent, errorMessage string) error {
+	return nil
+}
+
+// TestBootShutdown tests that cancelling the context of the deployer waits
+// for the deployment in progress, and does not start queued deployments.
+func TestBootShutdown(t *testing.T) {
+	h, err := history.New(history.DefaultConfig())
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	e := &testEventer{
+		events: []eventerspec.DeploymentEvent{{ID: 1, Name: "api"}, {ID: 2, Name: "web"}},
+		states: make(chan eventerspec.DeploymentState, 10),
+	}
+	i := &testInstaller{
+		release: make(chan struct{}),
+		started: make(chan struct{}, 2),
+	}
+
+	d := &standardDeployer{
+		eventer:   e,
+		history:   h,
+		installer: i,
+		logger:    microloggertest.New(),
+		notifier:  &testNotifier{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		d.Boot(ctx)
+		close(done)
+	}()
+
+	<-i.started
+	cancel()
+
+	select {
+	case <-done:
+		t.Fatalf("expected deployer to wait for the deployment in progress")
+	default:
+	}
+
+	close(i.release)
+	<-done
+
+	if len(i.installed) != 1 || i.installed[0] != "api" {
+		t.Fatalf("expected only the deployment in progress to be installed, returned: %#v", i.installed)
+	}
+
+	close(e.states)
+	var succeeded int
+	for state := range e.states {
+		if state == eventerspec.SuccessState {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected deployment in progress to succeed, returned: %d successes", succeeded)
+	}
+}
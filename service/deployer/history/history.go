@@ -0,0 +1,129 @@
+// Package history keeps the outcome of recent deployments in memory, so that
+// they can be looked up via the deployment log URL.
+package history
+
+import (
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// Config represents the configuration used to create a History.
+type Config struct {
+	// Settings.
+
+	// Size is the number of deployments that are kept. The oldest
+	// deployment is forgotten when a new one is recorded.
+	Size int
+}
+
+// DefaultConfig provides a default configuration to create a new History by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		Size: 100,
+	}
+}
+
+// New creates a new configured History.
+func New(config Config) (*History, error) {
+	if config.Size <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "size must be greater than zero")
+	}
+
+	h := &History{
+		// Internals.
+		mutex:   &sync.Mutex{},
+		records: map[key]Record{},
+
+		// Settings.
+		size: config.Size,
+	}
+
+	return h, nil
+}
+
+// Record represents a single deployment.
+type Record struct {
+	// ID is the ID of the deployment event.
+	ID int `json:"id"`
+
+	// Name is the name of the deployed project.
+	Name string `json:"name"`
+
+	// Sha is the version of the deployed chart.
+	Sha string `json:"sha"`
+
+	// State is the current state of the deployment.
+	State eventerspec.DeploymentState `json:"state"`
+
+	// Description is the description of the current state.
+	Description string `json:"description"`
+
+	// Output is the full output of a failed installation. It is only served
+	// to authenticated requests.
+	Output string `json:"output,omitempty"`
+
+	// CreatedAt is the time the deployment was first recorded.
+	CreatedAt time.Time `json:"createdAt"`
+
+	// UpdatedAt is the time the deployment was last recorded.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type key struct {
+	id   int
+	name string
+}
+
+// History holds the most recent deployments.
+type History struct {
+	// Internals.
+	mutex *sync.Mutex
+	// order holds the keys of records from oldest to newest.
+	order   []key
+	records map[key]Record
+
+	// Settings.
+	size int
+}
+
+// Get returns the deployment with the given project name and ID, if it is
+// known.
+func (h *History) Get(name string, id int) (Record, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	record, ok := h.records[key{id: id, name: name}]
+
+	return record, ok
+}
+
+// Set records the current state of a deployment.
+func (h *History) Set(record Record) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	k := key{id: record.ID, name: record.Name}
+
+	record.UpdatedAt = time.Now()
+
+	if existing, ok := h.records[k]; ok {
+		record.CreatedAt = existing.CreatedAt
+		h.records[k] = record
+		return
+	}
+
+	record.CreatedAt = record.UpdatedAt
+	h.records[k] = record
+	h.order = append(h.order, k)
+
+	if len(h.order) > h.size {
+		delete(h.records, h.order[0])
+		h.order = h.order[1:]
+	}
+}
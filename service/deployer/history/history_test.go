@@ -0,0 +1,40 @@
+package history
+
+import (
+	"testing"
+
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// TestHistory tests that deployments are updated in place, and that the
+// oldest deployment is forgotten once the history is full.
+func TestHistory(t *testing.T) {
+	h, err := New(Config{Size: 2})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	h.Set(Record{ID: 1, Name: "api", State: eventerspec.QueuedState})
+	h.Set(Record{ID: 2, Name: "api", State: eventerspec.QueuedState})
+	h.Set(Record{ID: 1, Name: "api", State: eventerspec.SuccessState})
+
+	record, ok := h.Get("api", 1)
+	if !ok || record.State != eventerspec.SuccessState {
+		t.Fatalf("expected updated record, returned: %#v", record)
+	}
+	if record.CreatedAt.After(record.UpdatedAt) {
+		t.Fatalf("expected created time to be kept, returned: %#v", record)
+	}
+
+	h.Set(Record{ID: 3, Name: "api", State: eventerspec.QueuedState})
+
+	if _, ok := h.Get("api", 1); ok {
+		t.Fatalf("expected oldest record to be forgotten")
+	}
+	if _, ok := h.Get("api", 3); !ok {
+		t.Fatalf("expected newest record to be kept")
+	}
+	if _, ok := h.Get("kvm-operator", 2); ok {
+		t.Fatalf("expected records of other projects not to match")
+	}
+}
@@ -1,10 +1,17 @@
 package deployer
 
+import (
+	"context"
+)
+
 // Deployer is a service that handles deployments.
 // While the deployment control loop logic is unlikely to change drastically,
 // the Deployer interface exists to allow for it in the future.
 // e.g: installations that only allow for deployments during certain
 // maintenance windows.
 type Deployer interface {
-	Boot()
+	// Boot runs the deployment control loop until the context is
+	// cancelled, and returns once the deployment in progress, if any, is
+	// finished.
+	Boot(ctx context.Context)
 }
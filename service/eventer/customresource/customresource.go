@@ -0,0 +1,310 @@
+package customresource
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+const (
+	// resyncPeriod is the interval in which all DraughtsmanDeployments are
+	// checked again, e.g. for deployments that were interrupted.
+	resyncPeriod = 5 * time.Minute
+)
+
+// CustomResourceEventerType is an Eventer that uses DraughtsmanDeployment
+// custom resources as a backend.
+var CustomResourceEventerType spec.EventerType = "CustomResourceEventer"
+
+// Config represents the configuration used to create a Custom Resource
+// Eventer.
+type Config struct {
+	// Dependencies.
+	DynamicClient dynamic.Interface
+	Logger        micrologger.Logger
+
+	// Settings.
+
+	// Namespace is the namespace watched for DraughtsmanDeployments.
+	Namespace string
+}
+
+// DefaultConfig provides a default configuration to create a new Custom
+// Resource Eventer by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		DynamicClient: nil,
+		Logger:        nil,
+
+		// Settings.
+		Namespace: "",
+	}
+}
+
+// New creates a new configured Custom Resource Eventer.
+func New(config Config) (*CustomResourceEventer, error) {
+	if config.DynamicClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "dynamic client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	if config.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "namespace must not be empty")
+	}
+
+	eventer := &CustomResourceEventer{
+		// Dependencies.
+		client: config.DynamicClient,
+		logger: config.Logger,
+
+		// Internals.
+		deployments: map[int]deploymentRef{},
+		mutex:       &sync.Mutex{},
+
+		// Settings.
+		namespace: config.Namespace,
+	}
+
+	return eventer, nil
+}
+
+// deploymentRef references the generation of a DraughtsmanDeployment an
+// event was emitted for.
+type deploymentRef struct {
+	Generation int64
+	Name       string
+}
+
+// CustomResourceEventer is an implementation of the Eventer interface, that
+// watches DraughtsmanDeployment custom resources, and reports deployment
+// statuses in their status.
+type CustomResourceEventer struct {
+	// Dependencies.
+	client dynamic.Interface
+	logger micrologger.Logger
+
+	// Internals.
+
+	// deployments holds the DraughtsmanDeployments of events that were
+	// emitted, and are not finished yet, by event ID.
+	deployments map[int]deploymentRef
+	mutex       *sync.Mutex
+
+	// Settings.
+	namespace string
+}
+
+func (e *CustomResourceEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	e.logger.Log("debug", "starting watching for custom resource deployment events", "namespace", e.namespace)
+
+	deploymentEventChannel := make(chan spec.DeploymentEvent)
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(e.client, resyncPeriod, e.namespace, nil)
+	informer := factory.ForResource(deploymentResource).Informer()
+
+	handle := func(obj interface{}) {
+		event, ok := e.deploymentEvent(obj)
+		if !ok {
+			return
+		}
+
+		select {
+		case deploymentEventChannel <- event:
+		case <-ctx.Done():
+		}
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: handle,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			handle(newObj)
+		},
+	})
+
+	go func() {
+		// Handlers are stopped once the informer returns, so the channel can
+		// be closed safely.
+		informer.Run(ctx.Done())
+		close(deploymentEventChannel)
+
+		e.logger.Log("debug", "stopped watching for custom resource deployment events")
+	}()
+
+	return deploymentEventChannel, nil
+}
+
+func (e *CustomResourceEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	e.mutex.Lock()
+	ref, ok := e.deployments[event.ID]
+	if ok && status.State.IsFinal() {
+		delete(e.deployments, event.ID)
+	}
+	e.mutex.Unlock()
+
+	if !ok {
+		return microerror.Maskf(notFoundError, "draughtsman deployment of event %d", event.ID)
+	}
+
+	err := e.updateStatus(ref, status)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// deploymentEvent returns the deployment event of the given
+// DraughtsmanDeployment, if its current generation was neither deployed nor
+// emitted yet.
+func (e *CustomResourceEventer) deploymentEvent(obj interface{}) (spec.DeploymentEvent, bool) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return spec.DeploymentEvent{}, false
+	}
+
+	var d draughtsmanDeployment
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &d); err != nil {
+		e.logger.Log("error", "could not decode draughtsman deployment", "name", u.GetName(), "message", err.Error())
+		return spec.DeploymentEvent{}, false
+	}
+
+	if d.Status.ObservedGeneration >= d.Generation {
+		return spec.DeploymentEvent{}, false
+	}
+
+	id := eventID(d)
+	ref := deploymentRef{Generation: d.Generation, Name: d.Name}
+
+	e.mutex.Lock()
+	_, emitted := e.deployments[id]
+	e.deployments[id] = ref
+	e.mutex.Unlock()
+
+	if emitted {
+		return spec.DeploymentEvent{}, false
+	}
+
+	event, err := deploymentEventFor(id, d)
+	if err != nil {
+		e.logger.Log("error", "invalid draughtsman deployment", "name", d.Name, "message", err.Error())
+
+		err = e.SetStatus(event, spec.DeploymentStatus{State: spec.FailureState, Description: fmt.Sprintf("Invalid deployment: %s", err.Error())})
+		if err != nil {
+			e.logger.Log("error", "could not update draughtsman deployment status", "name", d.Name, "message", err.Error())
+		}
+
+		return spec.DeploymentEvent{}, false
+	}
+
+	e.logger.Log("debug", "found new deployment events", "name", d.Name, "project", event.Name, "generation", d.Generation)
+
+	return event, true
+}
+
+// updateStatus updates the status of the referenced DraughtsmanDeployment.
+// Final statuses mark the generation as deployed.
+func (e *CustomResourceEventer) updateStatus(ref deploymentRef, status spec.DeploymentStatus) error {
+	e.logger.Log("debug", "updating draughtsman deployment status", "name", ref.Name, "state", status.State)
+
+	client := e.client.Resource(deploymentResource).Namespace(e.namespace)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		u, err := client.Get(ref.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return microerror.Maskf(notFoundError, "draughtsman deployment %#q", ref.Name)
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		var d draughtsmanDeployment
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &d); err != nil {
+			return microerror.Mask(err)
+		}
+
+		d.Status.Conditions = setCondition(d.Status.Conditions, deployedCondition(status), metav1.Now())
+		d.Status.LogURL = status.LogURL
+		d.Status.State = status.State
+		if status.State.IsFinal() {
+			d.Status.ObservedGeneration = ref.Generation
+		}
+
+		s, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&d.Status)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if err := unstructured.SetNestedField(u.Object, s, "status"); err != nil {
+			return microerror.Mask(err)
+		}
+
+		_, err = client.UpdateStatus(u, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// deploymentEventFor returns the deployment event of the given
+// DraughtsmanDeployment.
+func deploymentEventFor(id int, d draughtsmanDeployment) (spec.DeploymentEvent, error) {
+	event := spec.DeploymentEvent{
+		ID:   id,
+		Name: d.Spec.Project,
+		Sha:  d.Spec.Sha,
+
+		Description: d.Spec.Description,
+		Payload: spec.DeploymentPayload{
+			DryRun:    d.Spec.DryRun,
+			Namespace: d.Spec.Namespace,
+			Values:    d.Spec.Values,
+		},
+		Task:    spec.DeploymentTask(d.Spec.Task),
+		Version: d.Spec.Version,
+	}
+	if event.Sha == "" {
+		event.Sha = d.Spec.Version
+	}
+
+	if event.Name == "" {
+		return event, microerror.Maskf(invalidSpecError, "project must not be empty")
+	}
+	if event.Sha == "" {
+		return event, microerror.Maskf(invalidSpecError, "sha or version must not be empty")
+	}
+
+	return event, nil
+}
+
+// eventID returns the ID of the events of the current generation of the
+// given DraughtsmanDeployment, as a positive hash of its UID and
+// generation.
+func eventID(d draughtsmanDeployment) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%d", d.UID, d.Generation)
+
+	return int(h.Sum32() & 0x7fffffff)
+}
@@ -0,0 +1,130 @@
+package customresource
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// newDeployment returns a DraughtsmanDeployment of the given generation and
+// spec.
+func newDeployment(generation int64, deploymentSpec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": deploymentSpec,
+	}}
+	u.SetAPIVersion("draughtsman.giantswarm.io/v1alpha1")
+	u.SetKind("DraughtsmanDeployment")
+	u.SetGeneration(generation)
+	u.SetName("api")
+	u.SetNamespace("draughtsman")
+	u.SetUID("1234")
+
+	return u
+}
+
+// TestCustomResourceEventer tests emitting events for DraughtsmanDeployments,
+// and reporting statuses in their status.
+func TestCustomResourceEventer(t *testing.T) {
+	deployment := newDeployment(1, map[string]interface{}{
+		"project": "api",
+		"sha":     "12345",
+		"values":  map[string]interface{}{"image.tag": "1.2.3"},
+	})
+
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+
+	e := CustomResourceEventer{
+		client: client,
+		logger: microloggertest.New(),
+
+		deployments: map[int]deploymentRef{},
+		mutex:       &sync.Mutex{},
+
+		namespace: "draughtsman",
+	}
+
+	event, ok := e.deploymentEvent(deployment)
+	if !ok {
+		t.Fatalf("expected deployment event")
+	}
+	if event.Name != "api" || event.Sha != "12345" || event.Payload.Values["image.tag"] != "1.2.3" {
+		t.Fatalf("expected deployment event of spec, returned: %#v", event)
+	}
+
+	if _, ok := e.deploymentEvent(deployment); ok {
+		t.Fatalf("expected deployment event to be emitted only once")
+	}
+
+	if err := e.SetStatus(event, spec.DeploymentStatus{State: spec.InProgressState}); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if err := e.SetStatus(event, spec.DeploymentStatus{State: spec.SuccessState, Description: "Deployed", LogURL: "https://draughtsman/deployments/api/1"}); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	u, err := client.Resource(deploymentResource).Namespace("draughtsman").Get("api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	var d draughtsmanDeployment
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &d); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	if d.Status.ObservedGeneration != 1 || d.Status.State != spec.SuccessState || d.Status.LogURL != "https://draughtsman/deployments/api/1" {
+		t.Fatalf("expected successful status, returned: %#v", d.Status)
+	}
+	if len(d.Status.Conditions) != 1 || d.Status.Conditions[0].Status != metav1.ConditionTrue || d.Status.Conditions[0].Message != "Deployed" {
+		t.Fatalf("expected deployed condition, returned: %#v", d.Status.Conditions)
+	}
+
+	if _, ok := e.deploymentEvent(u); ok {
+		t.Fatalf("expected deployed generation not to be emitted again")
+	}
+
+	if err := e.SetStatus(event, spec.DeploymentStatus{State: spec.SuccessState}); !IsNotFound(err) {
+		t.Fatalf("expected not found error for finished deployment, returned: %#v", err)
+	}
+}
+
+// TestDeploymentEventInvalid tests that invalid DraughtsmanDeployments are
+// failed without emitting events.
+func TestDeploymentEventInvalid(t *testing.T) {
+	deployment := newDeployment(1, map[string]interface{}{
+		"project": "api",
+	})
+
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), deployment)
+
+	e := CustomResourceEventer{
+		client: client,
+		logger: microloggertest.New(),
+
+		deployments: map[int]deploymentRef{},
+		mutex:       &sync.Mutex{},
+
+		namespace: "draughtsman",
+	}
+
+	if _, ok := e.deploymentEvent(deployment); ok {
+		t.Fatalf("expected invalid deployment not to be emitted")
+	}
+
+	u, err := client.Resource(deploymentResource).Namespace("draughtsman").Get("api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	state, _, _ := unstructured.NestedString(u.Object, "status", "state")
+	if state != string(spec.FailureState) {
+		t.Fatalf("expected failure state, returned: %#v", state)
+	}
+}
@@ -0,0 +1,129 @@
+package customresource
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// deploymentResource is the resource of DraughtsmanDeployments.
+var deploymentResource = schema.GroupVersionResource{
+	Group:    "draughtsman.giantswarm.io",
+	Version:  "v1alpha1",
+	Resource: "draughtsmandeployments",
+}
+
+// draughtsmanDeployment represents a DraughtsmanDeployment custom resource,
+// which requests a deployment.
+type draughtsmanDeployment struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   deploymentSpec   `json:"spec"`
+	Status deploymentStatus `json:"status,omitempty"`
+}
+
+// deploymentSpec is the requested deployment.
+type deploymentSpec struct {
+	// Project is the name of the project to deploy, e.g: aws-operator.
+	Project string `json:"project"`
+
+	// Sha is the sha of the chart to deploy.
+	Sha string `json:"sha,omitempty"`
+
+	// Version is the version of the chart to deploy. It defaults to the
+	// version the chart of Sha is published with.
+	Version string `json:"version,omitempty"`
+
+	// Description is a description of the deployment.
+	Description string `json:"description,omitempty"`
+
+	// DryRun requests the deployment to be simulated without being applied.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Namespace is the namespace to install the chart to.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Task is the task to perform, either deploy or rollback.
+	Task string `json:"task,omitempty"`
+
+	// Values are additional chart values, e.g: {"image.tag": "1.2.3"}.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// deploymentStatus is the status of the requested deployment.
+type deploymentStatus struct {
+	// Conditions holds the Deployed condition.
+	Conditions []condition `json:"conditions,omitempty"`
+
+	// LogURL is the URL of the output of the deployment.
+	LogURL string `json:"logURL,omitempty"`
+
+	// ObservedGeneration is the generation of the spec that was deployed
+	// last.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// State is the state of the deployment, e.g: in_progress.
+	State spec.DeploymentState `json:"state,omitempty"`
+}
+
+// condition represents a status condition of a DraughtsmanDeployment.
+type condition struct {
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime"`
+	Message            string                 `json:"message,omitempty"`
+	Reason             string                 `json:"reason"`
+	Status             metav1.ConditionStatus `json:"status"`
+	Type               string                 `json:"type"`
+}
+
+const (
+	// deployedConditionType is the type of the condition reporting whether
+	// the spec was deployed.
+	deployedConditionType = "Deployed"
+)
+
+// deployedCondition returns the Deployed condition matching the given
+// deployment status.
+func deployedCondition(status spec.DeploymentStatus) condition {
+	c := condition{
+		Message: status.Description,
+		Status:  metav1.ConditionUnknown,
+		Type:    deployedConditionType,
+	}
+
+	switch status.State {
+	case spec.QueuedState:
+		c.Reason = "Queued"
+	case spec.SuccessState:
+		c.Reason = "Succeeded"
+		c.Status = metav1.ConditionTrue
+	case spec.FailureState:
+		c.Reason = "Failed"
+		c.Status = metav1.ConditionFalse
+	default:
+		c.Reason = "InProgress"
+	}
+
+	return c
+}
+
+// setCondition sets the given condition, keeping the last transition time
+// if its status did not change.
+func setCondition(conditions []condition, c condition, now metav1.Time) []condition {
+	c.LastTransitionTime = now
+
+	for i, existing := range conditions {
+		if existing.Type != c.Type {
+			continue
+		}
+		if existing.Status == c.Status {
+			c.LastTransitionTime = existing.LastTransitionTime
+		}
+		conditions[i] = c
+
+		return conditions
+	}
+
+	return append(conditions, c)
+}
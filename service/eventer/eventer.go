@@ -1,29 +1,64 @@
 package eventer
 
 import (
+	"strings"
+
+	"github.com/spf13/afero"
 	"github.com/spf13/viper"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 
 	"github.com/giantswarm/draughtsman/flag"
-	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	"github.com/giantswarm/draughtsman/service/eventer/customresource"
+	"github.com/giantswarm/draughtsman/service/eventer/file"
+	"github.com/giantswarm/draughtsman/service/eventer/gitea"
 	"github.com/giantswarm/draughtsman/service/eventer/github"
+	"github.com/giantswarm/draughtsman/service/eventer/gitlab"
+	"github.com/giantswarm/draughtsman/service/eventer/multi"
+	"github.com/giantswarm/draughtsman/service/eventer/registry"
+	"github.com/giantswarm/draughtsman/service/eventer/sink"
 	"github.com/giantswarm/draughtsman/service/eventer/spec"
+	"github.com/giantswarm/draughtsman/service/eventer/state"
 	httpspec "github.com/giantswarm/draughtsman/service/http"
 )
 
+const (
+	// githubStateKey is the key under which the state of the GitHub
+	// Eventers is persisted. It is shared by all of them, so that switching
+	// between them does not fetch all deployments again. Only one of them may
+	// be configured at a time, as they would overwrite the state of each
+	// other, and install the same deployments twice otherwise.
+	githubStateKey = "github"
+
+	// registryStateKey is the key under which the state of the Registry
+	// Eventer is persisted.
+	registryStateKey = "registry"
+)
+
 // Config represents the configuration used to create an Eventer.
 type Config struct {
 	// Dependencies.
-	HTTPClient httpspec.Client
-	Logger     micrologger.Logger
+	DynamicClient dynamic.Interface
+	FileSystem    afero.Fs
+	// GithubClient makes the requests to the GitHub API, shared by the
+	// GitHub Eventers and the project discovery. It is nil if GitHub is not
+	// configured.
+	GithubClient     *github.Client
+	HTTPClient       httpspec.Client
+	KubernetesClient kubernetes.Interface
+	Logger           micrologger.Logger
+	// ProjectLister lists the projects polled by the Eventers.
+	ProjectLister spec.ProjectLister
 
 	// Settings.
 	Flag  *flag.Flag
 	Viper *viper.Viper
 
-	Type spec.EventerType
+	// Types are the types of the Eventers to create.
+	Types []spec.EventerType
 }
 
 // DefaultConfig provides a default configuration to create a new Eventer
@@ -31,8 +66,13 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		// Dependencies.
-		HTTPClient: nil,
-		Logger:     nil,
+		DynamicClient:    nil,
+		FileSystem:       nil,
+		GithubClient:     nil,
+		HTTPClient:       nil,
+		KubernetesClient: nil,
+		Logger:           nil,
+		ProjectLister:    nil,
 
 		// Settings.
 		Flag:  nil,
@@ -40,7 +80,8 @@ func DefaultConfig() Config {
 	}
 }
 
-// New creates a new configured Eventer.
+// New creates a new configured Eventer. When multiple types are
+// configured, their deployment events are merged.
 func New(config Config) (spec.Eventer, error) {
 	// Settings.
 	if config.Flag == nil {
@@ -49,29 +90,238 @@ func New(config Config) (spec.Eventer, error) {
 	if config.Viper == nil {
 		return nil, microerror.Maskf(invalidConfigError, "viper must not be empty")
 	}
+	if len(config.Types) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "types must not be empty")
+	}
+	if config.ProjectLister == nil {
+		return nil, microerror.Maskf(invalidConfigError, "project lister must not be empty")
+	}
+
+	if len(config.Types) == 1 {
+		newEventer, err := newEventer(config, config.Types[0])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return newEventer, nil
+	}
+
+	eventers := map[spec.EventerType]spec.Eventer{}
+	var githubType spec.EventerType
+	for _, t := range config.Types {
+		if _, ok := eventers[t]; ok {
+			return nil, microerror.Maskf(invalidConfigError, "eventer type %#q must only be configured once", t)
+		}
+		if isGithubType(t) && githubType != "" {
+			return nil, microerror.Maskf(invalidConfigError, "eventer types %#q and %#q must not be configured together, as both poll GitHub", githubType, t)
+		} else if isGithubType(t) {
+			githubType = t
+		}
+
+		newEventer, err := newEventer(config, t)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		eventers[t] = newEventer
+	}
+
+	multiConfig := multi.DefaultConfig()
+
+	multiConfig.Eventers = eventers
+	multiConfig.Logger = config.Logger
+
+	newEventer, err := multi.New(multiConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
+	return newEventer, nil
+}
+
+// newEventer creates a new configured Eventer of the given type.
+func newEventer(config Config, eventerType spec.EventerType) (spec.Eventer, error) {
 	var err error
 
+	var stateStore state.Store
+	if isGithubType(eventerType) {
+		if config.GithubClient == nil {
+			return nil, microerror.Maskf(invalidConfigError, "github oauth token or app id must not be empty")
+		}
+
+		stateStore, err = newStateStore(config, githubStateKey)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var newEventer spec.Eventer
-	switch config.Type {
+	switch eventerType {
 	case github.GithubEventerType:
 		githubConfig := github.DefaultConfig()
 
-		githubConfig.HTTPClient = config.HTTPClient
+		githubConfig.Client = config.GithubClient
+		githubConfig.Logger = config.Logger
+		githubConfig.StateStore = stateStore
+
+		githubConfig = githubConfigFromFlags(config, githubConfig)
+
+		newEventer, err = github.New(githubConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+	case github.GithubGraphQLEventerType:
+		githubConfig := github.DefaultConfig()
+
+		githubConfig.Client = config.GithubClient
 		githubConfig.Logger = config.Logger
+		githubConfig.StateStore = stateStore
+
+		githubConfig = githubConfigFromFlags(config, githubConfig)
+
+		newEventer, err = github.NewGraphQL(githubConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+	case github.GithubWebhookEventerType:
+		webhookConfig := github.DefaultWebhookConfig()
+
+		webhookConfig.Client = config.GithubClient
+		webhookConfig.Logger = config.Logger
+		webhookConfig.StateStore = stateStore
+
+		webhookConfig.Config = githubConfigFromFlags(config, webhookConfig.Config)
+		webhookConfig.WebhookSecret = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitHub.WebhookSecret)
+
+		newEventer, err = github.NewWebhook(webhookConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+	case gitlab.GitlabEventerType:
+		gitlabConfig := gitlab.DefaultConfig()
+
+		gitlabConfig.HTTPClient = config.HTTPClient
+		gitlabConfig.Logger = config.Logger
+
+		gitlabConfig.BaseURL = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitLab.BaseURL)
+		gitlabConfig.Environment = config.Viper.GetString(config.Flag.Service.Deployer.Environment)
+		gitlabConfig.Group = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitLab.Group)
+		gitlabConfig.PollInterval = config.Viper.GetDuration(config.Flag.Service.Deployer.Eventer.GitLab.PollInterval)
+		gitlabConfig.PollJitter = config.Viper.GetFloat64(config.Flag.Service.Deployer.Eventer.Poll.Jitter)
+		gitlabConfig.PollWorkers = config.Viper.GetInt(config.Flag.Service.Deployer.Eventer.Poll.Workers)
+		gitlabConfig.ProjectLister = config.ProjectLister
+		gitlabConfig.Token = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitLab.Token)
 
-		githubConfig.Environment = config.Viper.GetString(config.Flag.Service.Deployer.Environment)
-		githubConfig.OAuthToken = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitHub.OAuthToken)
-		githubConfig.Organisation = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitHub.Organisation)
-		githubConfig.PollInterval = config.Viper.GetDuration(config.Flag.Service.Deployer.Eventer.GitHub.PollInterval)
-		githubConfig.Provider = config.Viper.GetString(config.Flag.Service.Deployer.Provider)
+		newEventer, err = gitlab.New(gitlabConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+	case gitea.GiteaEventerType:
+		giteaConfig := gitea.DefaultConfig()
+
+		giteaConfig.HTTPClient = config.HTTPClient
+		giteaConfig.Logger = config.Logger
+
+		giteaConfig.BaseURL = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Gitea.BaseURL)
+		giteaConfig.Branch = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Gitea.Branch)
+		giteaConfig.Environment = config.Viper.GetString(config.Flag.Service.Deployer.Environment)
+		giteaConfig.Organisation = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Gitea.Organisation)
+		giteaConfig.PollInterval = config.Viper.GetDuration(config.Flag.Service.Deployer.Eventer.Gitea.PollInterval)
+		giteaConfig.PollJitter = config.Viper.GetFloat64(config.Flag.Service.Deployer.Eventer.Poll.Jitter)
+		giteaConfig.PollWorkers = config.Viper.GetInt(config.Flag.Service.Deployer.Eventer.Poll.Workers)
+		giteaConfig.ProjectLister = config.ProjectLister
+		giteaConfig.Token = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Gitea.Token)
+		giteaConfig.Trigger = gitea.Trigger(config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Gitea.Trigger))
+
+		newEventer, err = gitea.New(giteaConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
 
+	case registry.RegistryEventerType:
+		var statusSink spec.StatusSink
 		{
-			projectList := configuration.GetProjectList(githubConfig.Provider, githubConfig.Environment)
-			githubConfig.ProjectList = projectList
+			sinkConfig := sink.DefaultConfig()
+
+			sinkConfig.HTTPClient = config.HTTPClient
+			sinkConfig.Logger = config.Logger
+
+			sinkConfig.Type = sink.Type(config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Registry.Sink.Type))
+			sinkConfig.WebhookURL = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Registry.Sink.WebhookURL)
+
+			statusSink, err = sink.New(sinkConfig)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
 		}
 
-		newEventer, err = github.New(githubConfig)
+		var registryStateStore state.Store
+		{
+			registryStateStore, err = newStateStore(config, registryStateKey)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		projectPatterns, err := parseProjectPatterns(config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Registry.ProjectPatterns))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		registryConfig := registry.DefaultConfig()
+
+		registryConfig.HTTPClient = config.HTTPClient
+		registryConfig.Logger = config.Logger
+		registryConfig.StatusSink = statusSink
+		registryConfig.StateStore = registryStateStore
+
+		registryConfig.API = registry.API(config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Registry.API))
+		registryConfig.BaseURL = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Registry.BaseURL)
+		registryConfig.Organisation = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Registry.Organisation)
+		registryConfig.Password = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Registry.Password)
+		registryConfig.Pattern = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Registry.Pattern)
+		registryConfig.PollInterval = config.Viper.GetDuration(config.Flag.Service.Deployer.Eventer.Registry.PollInterval)
+		registryConfig.PollJitter = config.Viper.GetFloat64(config.Flag.Service.Deployer.Eventer.Poll.Jitter)
+		registryConfig.PollWorkers = config.Viper.GetInt(config.Flag.Service.Deployer.Eventer.Poll.Workers)
+		registryConfig.ProjectLister = config.ProjectLister
+		registryConfig.ProjectPatterns = projectPatterns
+		registryConfig.Username = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Registry.Username)
+
+		newEventer, err = registry.New(registryConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+	case customresource.CustomResourceEventerType:
+		customResourceConfig := customresource.DefaultConfig()
+
+		customResourceConfig.DynamicClient = config.DynamicClient
+		customResourceConfig.Logger = config.Logger
+
+		customResourceConfig.Namespace = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.CustomResource.Namespace)
+		if customResourceConfig.Namespace == "" {
+			customResourceConfig.Namespace = config.Viper.GetString(config.Flag.Release.Namespace)
+		}
+
+		newEventer, err = customresource.New(customResourceConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+	case file.FileEventerType:
+		fileConfig := file.DefaultConfig()
+
+		fileConfig.FileSystem = config.FileSystem
+		fileConfig.Logger = config.Logger
+
+		fileConfig.Directory = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.File.Directory)
+		fileConfig.PollInterval = config.Viper.GetDuration(config.Flag.Service.Deployer.Eventer.File.PollInterval)
+
+		newEventer, err = file.New(fileConfig)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
@@ -82,3 +332,148 @@ func New(config Config) (spec.Eventer, error) {
 
 	return newEventer, nil
 }
+
+// isGithubType returns true if the given eventer type polls GitHub.
+func isGithubType(eventerType spec.EventerType) bool {
+	switch eventerType {
+	case github.GithubEventerType, github.GithubGraphQLEventerType, github.GithubWebhookEventerType:
+		return true
+	default:
+		return false
+	}
+}
+
+// newStateStore creates the store persisting the state of the eventer under
+// the given key. It returns nil if no state configmap is configured, in which
+// case the state is only kept in memory.
+func newStateStore(config Config, key string) (state.Store, error) {
+	name := config.Viper.GetString(config.Flag.Service.Deployer.Eventer.State.ConfigMap)
+	if name == "" {
+		return nil, nil
+	}
+
+	c := state.DefaultConfig()
+
+	c.KubernetesClient = config.KubernetesClient
+	c.Logger = config.Logger
+
+	c.Key = key
+	c.Name = name
+	c.Namespace = config.Viper.GetString(config.Flag.Release.Namespace)
+
+	stateStore, err := state.New(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return stateStore, nil
+}
+
+// githubConfigFromFlags fills the settings of the given GitHub Eventer
+// configuration from the flags.
+func githubConfigFromFlags(config Config, githubConfig github.Config) github.Config {
+	githubConfig.Environment = config.Viper.GetString(config.Flag.Service.Deployer.Environment)
+	githubConfig.PollInterval = config.Viper.GetDuration(config.Flag.Service.Deployer.Eventer.GitHub.PollInterval)
+	githubConfig.PollJitter = config.Viper.GetFloat64(config.Flag.Service.Deployer.Eventer.Poll.Jitter)
+	githubConfig.PollWorkers = config.Viper.GetInt(config.Flag.Service.Deployer.Eventer.Poll.Workers)
+	githubConfig.Provider = config.Viper.GetString(config.Flag.Service.Deployer.Provider)
+
+	githubConfig.ProjectLister = config.ProjectLister
+
+	return githubConfig
+}
+
+// NewGithubClient creates the GitHub Client shared by the GitHub Eventers and
+// the project discovery, so that they share a single rate limit. It returns
+// nil if neither an OAuth token nor a GitHub App is configured.
+func NewGithubClient(config Config) (*github.Client, error) {
+	if config.Flag == nil {
+		return nil, microerror.Maskf(invalidConfigError, "flag must not be empty")
+	}
+	if config.Viper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "viper must not be empty")
+	}
+
+	c := github.DefaultClientConfig()
+
+	c.HTTPClient = config.HTTPClient
+	c.Logger = config.Logger
+
+	c.AppID = config.Viper.GetInt64(config.Flag.Service.Deployer.Eventer.GitHub.App.ID)
+	c.AppInstallationID = config.Viper.GetInt64(config.Flag.Service.Deployer.Eventer.GitHub.App.InstallationID)
+	c.AppPrivateKey = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitHub.App.PrivateKey)
+	c.BaseURL = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitHub.BaseURL)
+	c.OAuthToken = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitHub.OAuthToken)
+	c.Organisation = config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitHub.Organisation)
+
+	if c.AppID == 0 && c.OAuthToken == "" {
+		return nil, nil
+	}
+
+	githubClient, err := github.NewClient(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return githubClient, nil
+}
+
+// NewProjectLister creates the ProjectLister shared by the Eventers and the
+// Installer. It discovers projects by their GitHub topic, if one is
+// configured, and returns the configured ProjectLister otherwise.
+func NewProjectLister(config Config) (spec.ProjectLister, error) {
+	if config.ProjectLister == nil {
+		return nil, microerror.Maskf(invalidConfigError, "project lister must not be empty")
+	}
+	if config.Flag == nil {
+		return nil, microerror.Maskf(invalidConfigError, "flag must not be empty")
+	}
+	if config.Viper == nil {
+		return nil, microerror.Maskf(invalidConfigError, "viper must not be empty")
+	}
+
+	topic := config.Viper.GetString(config.Flag.Service.Deployer.Eventer.GitHub.Topic.Name)
+	if topic == "" {
+		return config.ProjectLister, nil
+	}
+
+	if config.GithubClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "github oauth token or app id must not be empty")
+	}
+
+	c := github.DefaultTopicListerConfig()
+
+	c.Client = config.GithubClient
+	c.Logger = config.Logger
+
+	c.RefreshInterval = config.Viper.GetDuration(config.Flag.Service.Deployer.Eventer.GitHub.Topic.RefreshInterval)
+	c.Topic = topic
+
+	topicLister, err := github.NewTopicLister(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return topicLister, nil
+}
+
+// parseProjectPatterns parses the per project tag patterns, given as
+// semicolon separated project=pattern pairs, e.g: api=1.0.0-*;web=~1.2.
+func parseProjectPatterns(value string) (map[string]string, error) {
+	patterns := map[string]string{}
+
+	for _, pair := range strings.Split(value, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, microerror.Maskf(invalidConfigError, "project pattern %#q must be of the form project=pattern", pair)
+		}
+
+		patterns[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return patterns, nil
+}
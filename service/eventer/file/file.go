@@ -0,0 +1,345 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+const (
+	// statusFileSuffix is appended to the name of request files to name
+	// their status files.
+	statusFileSuffix = ".status"
+)
+
+// requestFileExtensions are the extensions of request files. Other files in
+// the directory are ignored.
+var requestFileExtensions = map[string]bool{
+	".json": true,
+	".yaml": true,
+	".yml":  true,
+}
+
+// FileEventerType is an Eventer that uses deployment request files in a
+// local directory as a backend.
+var FileEventerType spec.EventerType = "FileEventer"
+
+// Config represents the configuration used to create a File Eventer.
+type Config struct {
+	// Dependencies.
+	FileSystem afero.Fs
+	Logger     micrologger.Logger
+
+	// Settings.
+
+	// Directory is the directory watched for deployment request files.
+	Directory    string
+	PollInterval time.Duration
+}
+
+// DefaultConfig provides a default configuration to create a new File
+// Eventer by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		FileSystem: nil,
+		Logger:     nil,
+
+		// Settings.
+		Directory:    "",
+		PollInterval: 10 * time.Second,
+	}
+}
+
+// New creates a new configured File Eventer.
+func New(config Config) (*FileEventer, error) {
+	if config.FileSystem == nil {
+		return nil, microerror.Maskf(invalidConfigError, "file system must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	if config.Directory == "" {
+		return nil, microerror.Maskf(invalidConfigError, "directory must not be empty")
+	}
+	if config.PollInterval.Seconds() == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "interval must be greater than zero")
+	}
+
+	eventer := &FileEventer{
+		// Dependencies.
+		fileSystem: config.FileSystem,
+		logger:     config.Logger,
+
+		// Internals.
+		files: map[int]requestFile{},
+		mutex: &sync.Mutex{},
+
+		// Settings.
+		directory:    config.Directory,
+		pollInterval: config.PollInterval,
+	}
+
+	return eventer, nil
+}
+
+// FileEventer is an implementation of the Eventer interface, that polls a
+// directory for YAML or JSON deployment request files, and writes
+// deployment statuses to a .status file next to every request file.
+type FileEventer struct {
+	// Dependencies.
+	fileSystem afero.Fs
+	logger     micrologger.Logger
+
+	// Internals.
+
+	// files holds the request files of events that were emitted, and are
+	// not finished yet, by event ID.
+	files map[int]requestFile
+	mutex *sync.Mutex
+
+	// Settings.
+	directory    string
+	pollInterval time.Duration
+}
+
+func (e *FileEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	e.logger.Log("debug", "starting polling for file deployment events", "interval", e.pollInterval, "directory", e.directory)
+
+	deploymentEventChannel := make(chan spec.DeploymentEvent)
+	ticker := time.NewTicker(e.pollInterval)
+
+	go func() {
+		defer close(deploymentEventChannel)
+		defer ticker.Stop()
+
+		for {
+			events, err := e.fetchDeploymentEvents()
+			if err != nil {
+				e.logger.Log("error", "could not fetch deployment events", "message", err.Error())
+			}
+
+			for _, event := range events {
+				select {
+				case deploymentEventChannel <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				e.logger.Log("debug", "stopped polling for file deployment events")
+				return
+			}
+		}
+	}()
+
+	return deploymentEventChannel, nil
+}
+
+func (e *FileEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	e.mutex.Lock()
+	file, ok := e.files[event.ID]
+	if ok && status.State.IsFinal() {
+		delete(e.files, event.ID)
+	}
+	e.mutex.Unlock()
+
+	if !ok {
+		return microerror.Maskf(notFoundError, "request file of event %d", event.ID)
+	}
+
+	err := e.writeStatus(file, event.ID, status)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// fetchDeploymentEvents returns the deployment events of request files that
+// were neither deployed nor emitted yet in their current content.
+func (e *FileEventer) fetchDeploymentEvents() ([]spec.DeploymentEvent, error) {
+	infos, err := afero.ReadDir(e.fileSystem, e.directory)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var events []spec.DeploymentEvent
+	for _, info := range infos {
+		if info.IsDir() || !requestFileExtensions[filepath.Ext(info.Name())] {
+			continue
+		}
+
+		path := filepath.Join(e.directory, info.Name())
+
+		event, ok, err := e.deploymentEvent(path)
+		if err != nil {
+			e.logger.Log("error", "could not read request file", "path", path, "message", err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// deploymentEvent returns the deployment event of the given request file,
+// if its content was neither deployed nor emitted yet. Invalid requests are
+// failed right away.
+func (e *FileEventer) deploymentEvent(path string) (spec.DeploymentEvent, bool, error) {
+	content, err := afero.ReadFile(e.fileSystem, path)
+	if err != nil {
+		return spec.DeploymentEvent{}, false, microerror.Mask(err)
+	}
+
+	sum := sha256.Sum256(content)
+	file := requestFile{
+		Checksum: hex.EncodeToString(sum[:]),
+		Path:     path,
+	}
+	id := eventID(file)
+
+	status, err := e.readStatus(path)
+	if err != nil {
+		return spec.DeploymentEvent{}, false, microerror.Mask(err)
+	}
+	if status.Checksum == file.Checksum && status.State.IsFinal() {
+		return spec.DeploymentEvent{}, false, nil
+	}
+
+	e.mutex.Lock()
+	_, emitted := e.files[id]
+	e.files[id] = file
+	e.mutex.Unlock()
+
+	if emitted {
+		return spec.DeploymentEvent{}, false, nil
+	}
+
+	event, err := deploymentEventFor(id, content)
+	if err != nil {
+		e.logger.Log("error", "invalid request file", "path", path, "message", err.Error())
+
+		err = e.SetStatus(event, spec.DeploymentStatus{State: spec.FailureState, Description: fmt.Sprintf("Invalid deployment: %s", err.Error())})
+		if err != nil {
+			return spec.DeploymentEvent{}, false, microerror.Mask(err)
+		}
+
+		return spec.DeploymentEvent{}, false, nil
+	}
+
+	e.logger.Log("debug", "found new deployment events", "path", path, "project", event.Name, "sha", event.Sha)
+
+	return event, true, nil
+}
+
+// readStatus reads the status file of the given request file. It returns
+// an empty status if there is none.
+func (e *FileEventer) readStatus(path string) (deploymentStatus, error) {
+	content, err := afero.ReadFile(e.fileSystem, path+statusFileSuffix)
+	if os.IsNotExist(err) {
+		return deploymentStatus{}, nil
+	} else if err != nil {
+		return deploymentStatus{}, microerror.Mask(err)
+	}
+
+	var status deploymentStatus
+	if err := json.Unmarshal(content, &status); err != nil {
+		// Status files are only written by draughtsman, so a broken one is
+		// treated like a missing one, and overwritten.
+		return deploymentStatus{}, nil
+	}
+
+	return status, nil
+}
+
+// writeStatus writes the status file of the given request file.
+func (e *FileEventer) writeStatus(file requestFile, id int, status spec.DeploymentStatus) error {
+	e.logger.Log("debug", "writing status file", "path", file.Path, "state", status.State)
+
+	s := deploymentStatus{
+		Checksum: file.Checksum,
+		ID:       id,
+
+		State:       status.State,
+		Description: status.Description,
+		LogURL:      status.LogURL,
+		UpdatedAt:   time.Now().UTC(),
+	}
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = afero.WriteFile(e.fileSystem, file.Path+statusFileSuffix, append(content, '\n'), 0644)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// deploymentEventFor returns the deployment event of the given request file
+// content, given as YAML or JSON.
+func deploymentEventFor(id int, content []byte) (spec.DeploymentEvent, error) {
+	var request deploymentRequest
+	if err := yaml.Unmarshal(content, &request); err != nil {
+		return spec.DeploymentEvent{ID: id}, microerror.Maskf(invalidRequestError, "%s", err.Error())
+	}
+
+	event := spec.DeploymentEvent{
+		ID:   id,
+		Name: request.Project,
+		Sha:  request.Sha,
+
+		Description: request.Description,
+		Payload: spec.DeploymentPayload{
+			DryRun:    request.DryRun,
+			Namespace: request.Namespace,
+			Values:    request.Values,
+		},
+		Task:    spec.DeploymentTask(request.Task),
+		Version: request.Version,
+	}
+
+	if event.Name == "" {
+		return event, microerror.Maskf(invalidRequestError, "project must not be empty")
+	}
+	if event.Sha == "" {
+		return event, microerror.Maskf(invalidRequestError, "sha must not be empty")
+	}
+
+	return event, nil
+}
+
+// eventID returns the ID of the events of the given request file content,
+// as a positive hash of its path and checksum.
+func eventID(file requestFile) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%s", file.Path, file.Checksum)
+
+	return int(h.Sum32() & 0x7fffffff)
+}
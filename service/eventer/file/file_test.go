@@ -0,0 +1,139 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// readTestStatus reads the status file of the given request file.
+func readTestStatus(t *testing.T, fs afero.Fs, path string) deploymentStatus {
+	content, err := afero.ReadFile(fs, path+statusFileSuffix)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	var status deploymentStatus
+	if err := json.Unmarshal(content, &status); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	return status
+}
+
+// TestFileEventer tests emitting events for request files, and writing
+// statuses to status files.
+func TestFileEventer(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	files := map[string]string{
+		"/deployments/api.yaml":    "project: api\nsha: 12345\nvalues:\n  image.tag: 1.2.3\n",
+		"/deployments/web.json":    `{"project": "web", "sha": "67890", "dryRun": true}`,
+		"/deployments/invalid.yml": "project: invalid\n",
+		"/deployments/README.md":   "Deployment requests.",
+	}
+	for path, content := range files {
+		if err := afero.WriteFile(fs, path, []byte(content), 0644); err != nil {
+			t.Fatalf("expected nil error, returned: %#v", err)
+		}
+	}
+
+	e := FileEventer{
+		fileSystem: fs,
+		logger:     microloggertest.New(),
+
+		files: map[int]requestFile{},
+		mutex: &sync.Mutex{},
+
+		directory: "/deployments",
+	}
+
+	events, err := e.fetchDeploymentEvents()
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected two deployment events, returned: %#v", events)
+	}
+	if events[0].Name != "api" || events[0].Sha != "12345" || events[0].Payload.Values["image.tag"] != "1.2.3" {
+		t.Fatalf("expected deployment event of api.yaml, returned: %#v", events[0])
+	}
+	if events[1].Name != "web" || events[1].Sha != "67890" || !events[1].Payload.DryRun {
+		t.Fatalf("expected deployment event of web.json, returned: %#v", events[1])
+	}
+
+	if status := readTestStatus(t, fs, "/deployments/invalid.yml"); status.State != spec.FailureState {
+		t.Fatalf("expected invalid request to fail, returned: %#v", status)
+	}
+
+	events2, err := e.fetchDeploymentEvents()
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if len(events2) != 0 {
+		t.Fatalf("expected deployment events to be emitted only once, returned: %#v", events2)
+	}
+
+	if err := e.SetStatus(events[0], spec.DeploymentStatus{State: spec.SuccessState, Description: "Deployed"}); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if status := readTestStatus(t, fs, "/deployments/api.yaml"); status.State != spec.SuccessState || status.Description != "Deployed" || status.ID != events[0].ID {
+		t.Fatalf("expected successful status, returned: %#v", status)
+	}
+
+	// A new event is emitted once the request file changes.
+	if err := afero.WriteFile(fs, "/deployments/api.yaml", []byte("project: api\nsha: abcdef\n"), 0644); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	events3, err := e.fetchDeploymentEvents()
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if len(events3) != 1 || events3[0].Sha != "abcdef" {
+		t.Fatalf("expected deployment event of changed api.yaml, returned: %#v", events3)
+	}
+}
+
+// TestNewDeploymentEventsCancel tests that polling stops, and the channel is
+// closed, once the context is cancelled.
+func TestNewDeploymentEventsCancel(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "/deployments/api.yaml", []byte("project: api\nsha: 12345\n"), 0644); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	e := FileEventer{
+		fileSystem: fs,
+		logger:     microloggertest.New(),
+
+		files: map[int]requestFile{},
+		mutex: &sync.Mutex{},
+
+		directory:    "/deployments",
+		pollInterval: time.Hour,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := e.NewDeploymentEvents(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	if event := <-events; event.Name != "api" {
+		t.Fatalf("expected deployment event of api.yaml, returned: %#v", event)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatalf("expected channel to be closed once the context is cancelled")
+	}
+}
@@ -0,0 +1,65 @@
+package file
+
+import (
+	"time"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// deploymentRequest represents a deployment request file.
+type deploymentRequest struct {
+	// Project is the name of the project to deploy, e.g: aws-operator.
+	Project string `json:"project"`
+
+	// Sha is the sha of the chart to deploy.
+	Sha string `json:"sha"`
+
+	// Version is the version of the chart to deploy. It defaults to the
+	// version the chart of Sha is published with.
+	Version string `json:"version,omitempty"`
+
+	// Description is a description of the deployment.
+	Description string `json:"description,omitempty"`
+
+	// DryRun requests the deployment to be simulated without being applied.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Namespace is the namespace to install the chart to.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Task is the task to perform, either deploy or rollback.
+	Task string `json:"task,omitempty"`
+
+	// Values are additional chart values, e.g: {"image.tag": "1.2.3"}.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// deploymentStatus represents a deployment status file, written next to
+// the request file.
+type deploymentStatus struct {
+	// Checksum is the checksum of the request file content the status
+	// belongs to. Changing the request file triggers a new deployment.
+	Checksum string `json:"checksum"`
+
+	// ID is the ID of the deployment event.
+	ID int `json:"id"`
+
+	// State is the state of the deployment, e.g: in_progress.
+	State spec.DeploymentState `json:"state"`
+
+	// Description is a description of the state.
+	Description string `json:"description,omitempty"`
+
+	// LogURL is the URL of the output of the deployment.
+	LogURL string `json:"logURL,omitempty"`
+
+	// UpdatedAt is the time the status was written.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// requestFile references the content of a request file an event was
+// emitted for.
+type requestFile struct {
+	Checksum string
+	Path     string
+}
@@ -0,0 +1,203 @@
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+const (
+	// apiPath is the path prefix under which Gitea serves its API.
+	apiPath = "/api/v1"
+
+	// releasesUrlFormat is the string format for the Gitea API call for
+	// Releases. Templated with the base URL, organisation and project.
+	releasesUrlFormat = "%s" + apiPath + "/repos/%s/%s/releases?limit=%d"
+
+	// tagUrlFormat is the string format for the Gitea API call for a Tag.
+	tagUrlFormat = "%s" + apiPath + "/repos/%s/%s/tags/%s"
+
+	// combinedStatusUrlFormat is the string format for the Gitea API call
+	// for the combined Commit Status of a ref.
+	combinedStatusUrlFormat = "%s" + apiPath + "/repos/%s/%s/commits/%s/status"
+
+	// statusesUrlFormat is the string format for the Gitea API call to
+	// create a Commit Status.
+	statusesUrlFormat = "%s" + apiPath + "/repos/%s/%s/statuses/%s"
+
+	// statusContextFormat is the format of the commit status context used
+	// for deployments. Templated with the environment.
+	statusContextFormat = "deploy/%s"
+
+	// releasesPerPoll is the number of newest releases searched for a
+	// published release.
+	releasesPerPoll = 10
+)
+
+// request makes an authenticated request, and returns the response body if
+// the expected status code was received.
+func (e *GiteaEventer) request(method, u, project string, payload interface{}, expectedStatusCode int) ([]byte, error) {
+	var body *bytes.Buffer
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		body = bytes.NewBuffer(b)
+	} else {
+		body = &bytes.Buffer{}
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", e.token))
+	req.Header.Set("Content-Type", "application/json")
+
+	startTime := time.Now()
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	updateRequestMetrics(method, e.organisation, project, resp.StatusCode, startTime)
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if resp.StatusCode != expectedStatusCode {
+		return nil, microerror.Maskf(unexpectedStatusCode, "received status code: %v, body: %q", resp.StatusCode, string(b))
+	}
+
+	return b, nil
+}
+
+// fetchReleaseDeploymentEvent returns a deployment event for the newest
+// published release of the project, unless it was deployed already.
+func (e *GiteaEventer) fetchReleaseDeploymentEvent(project string) (spec.DeploymentEvent, bool, error) {
+	body, err := e.request("GET", fmt.Sprintf(releasesUrlFormat, e.baseURL, e.organisation, project, releasesPerPoll), project, nil, http.StatusOK)
+	if err != nil {
+		return spec.DeploymentEvent{}, false, microerror.Mask(err)
+	}
+
+	var releases []release
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return spec.DeploymentEvent{}, false, microerror.Mask(err)
+	}
+
+	var newest *release
+	for index, release := range releases {
+		if !release.Draft && !release.Prerelease {
+			newest = &releases[index]
+			break
+		}
+	}
+	if newest == nil {
+		return spec.DeploymentEvent{}, false, nil
+	}
+
+	body, err = e.request("GET", fmt.Sprintf(tagUrlFormat, e.baseURL, e.organisation, project, url.PathEscape(newest.TagName)), project, nil, http.StatusOK)
+	if err != nil {
+		return spec.DeploymentEvent{}, false, microerror.Mask(err)
+	}
+
+	var t tag
+	if err := json.Unmarshal(body, &t); err != nil {
+		return spec.DeploymentEvent{}, false, microerror.Mask(err)
+	}
+
+	combined, err := e.fetchCombinedStatus(project, t.Commit.Sha)
+	if err != nil {
+		return spec.DeploymentEvent{}, false, microerror.Mask(err)
+	}
+
+	if status, ok := combined.findStatus(e.statusContext); ok && status.State.isFinished() {
+		return spec.DeploymentEvent{}, false, nil
+	}
+
+	e.logger.Log("debug", "found new deployment events", "project", project, "release", newest.TagName)
+
+	event := spec.DeploymentEvent{
+		ID:   newest.ID,
+		Name: project,
+		Sha:  t.Commit.Sha,
+
+		Description: newest.Name,
+		Ref:         newest.TagName,
+	}
+	if newest.Author != nil {
+		event.Creator = newest.Author.Login
+	}
+
+	return event, true, nil
+}
+
+// fetchStatusDeploymentEvent returns a deployment event for the head of the
+// configured branch of the project, if its deployment status is pending.
+func (e *GiteaEventer) fetchStatusDeploymentEvent(project string) (spec.DeploymentEvent, bool, error) {
+	combined, err := e.fetchCombinedStatus(project, e.branch)
+	if err != nil {
+		return spec.DeploymentEvent{}, false, microerror.Mask(err)
+	}
+
+	status, ok := combined.findStatus(e.statusContext)
+	if !ok || status.State != pendingState {
+		return spec.DeploymentEvent{}, false, nil
+	}
+
+	e.logger.Log("debug", "found new deployment events", "project", project, "sha", combined.Sha)
+
+	event := spec.DeploymentEvent{
+		ID:   status.ID,
+		Name: project,
+		Sha:  combined.Sha,
+
+		Description: status.Description,
+		Ref:         e.branch,
+	}
+	if status.Creator != nil {
+		event.Creator = status.Creator.Login
+	}
+
+	return event, true, nil
+}
+
+// fetchCombinedStatus fetches the combined Commit Status of the given ref.
+func (e *GiteaEventer) fetchCombinedStatus(project, ref string) (combinedStatus, error) {
+	body, err := e.request("GET", fmt.Sprintf(combinedStatusUrlFormat, e.baseURL, e.organisation, project, url.PathEscape(ref)), project, nil, http.StatusOK)
+	if err != nil {
+		return combinedStatus{}, microerror.Mask(err)
+	}
+
+	var combined combinedStatus
+	if err := json.Unmarshal(body, &combined); err != nil {
+		return combinedStatus{}, microerror.Mask(err)
+	}
+
+	return combined, nil
+}
+
+// postCommitStatus posts a Commit Status for the given commit.
+func (e *GiteaEventer) postCommitStatus(project, sha string, status commitStatus) error {
+	e.logger.Log("debug", "posting commit status", "project", project, "sha", sha, "state", status.State)
+
+	_, err := e.request("POST", fmt.Sprintf(statusesUrlFormat, e.baseURL, e.organisation, project, sha), project, status, http.StatusCreated)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
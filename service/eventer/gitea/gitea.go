@@ -0,0 +1,268 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/draughtsman/service/eventer/poller"
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+)
+
+// GiteaEventerType is an Eventer that uses Gitea, or Forgejo, releases and
+// commit statuses as a backend.
+var GiteaEventerType spec.EventerType = "GiteaEventer"
+
+// Config represents the configuration used to create a Gitea Eventer.
+type Config struct {
+	// Dependencies.
+	HTTPClient httpspec.Client
+	Logger     micrologger.Logger
+	// ProjectLister lists the projects to poll. It is optional, and
+	// ProjectList is used when empty.
+	ProjectLister spec.ProjectLister
+
+	// Settings.
+
+	// BaseURL is the URL of the Gitea instance, e.g: https://gitea.example.com.
+	BaseURL string
+	// Branch is the branch whose head is deployed by the status trigger.
+	Branch       string
+	Environment  string
+	Organisation string
+	PollInterval time.Duration
+	// PollJitter is the maximum factor of the poll interval added to every
+	// wait, so that replicas do not poll in lockstep.
+	PollJitter float64
+	// PollWorkers is the maximum number of projects polled concurrently.
+	PollWorkers int
+	ProjectList []string
+	// Token is the access token used to authenticate. It needs write access
+	// to the repositories.
+	Token   string
+	Trigger Trigger
+}
+
+// DefaultConfig provides a default configuration to create a new Gitea
+// Eventer by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		HTTPClient:    nil,
+		Logger:        nil,
+		ProjectLister: nil,
+
+		// Settings.
+		Branch:      "master",
+		PollWorkers: 1,
+		Trigger:     StatusTrigger,
+	}
+}
+
+// New creates a new configured Gitea Eventer.
+func New(config Config) (*GiteaEventer, error) {
+	if config.HTTPClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	if config.BaseURL == "" {
+		return nil, microerror.Maskf(invalidConfigError, "base url must not be empty")
+	}
+	if config.Branch == "" && config.Trigger == StatusTrigger {
+		return nil, microerror.Maskf(invalidConfigError, "branch must not be empty")
+	}
+	if config.Environment == "" {
+		return nil, microerror.Maskf(invalidConfigError, "environment must not be empty")
+	}
+	if config.Organisation == "" {
+		return nil, microerror.Maskf(invalidConfigError, "organisation must not be empty")
+	}
+	if config.PollInterval.Seconds() == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "interval must be greater than zero")
+	}
+	if config.ProjectLister == nil && len(config.ProjectList) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "project list must not be empty")
+	}
+	if config.Token == "" {
+		return nil, microerror.Maskf(invalidConfigError, "token must not be empty")
+	}
+	if config.Trigger != ReleaseTrigger && config.Trigger != StatusTrigger {
+		return nil, microerror.Maskf(invalidConfigError, "trigger must be %#q or %#q", ReleaseTrigger, StatusTrigger)
+	}
+
+	var projectPoller *poller.Poller
+	{
+		c := poller.DefaultConfig()
+
+		c.Eventer = string(GiteaEventerType)
+		c.Interval = config.PollInterval
+		c.Jitter = config.PollJitter
+		c.Workers = config.PollWorkers
+
+		var err error
+		projectPoller, err = poller.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	eventer := &GiteaEventer{
+		// Dependencies.
+		client:        config.HTTPClient,
+		logger:        config.Logger,
+		poller:        projectPoller,
+		projectLister: config.ProjectLister,
+
+		// Internals.
+		emitted: map[string]struct{}{},
+		mutex:   &sync.Mutex{},
+
+		// Settings.
+		baseURL:       strings.TrimSuffix(config.BaseURL, "/"),
+		branch:        config.Branch,
+		environment:   config.Environment,
+		organisation:  config.Organisation,
+		pollInterval:  config.PollInterval,
+		projectList:   config.ProjectList,
+		statusContext: fmt.Sprintf(statusContextFormat, config.Environment),
+		token:         config.Token,
+		trigger:       config.Trigger,
+	}
+
+	return eventer, nil
+}
+
+// GiteaEventer is an implementation of the Eventer interface, that uses
+// Gitea releases or commit statuses as a backend, as Gitea has no
+// deployments API. Deployment statuses are written back as commit statuses
+// of the deploy/<environment> context.
+type GiteaEventer struct {
+	// Dependencies.
+	client        httpspec.Client
+	logger        micrologger.Logger
+	poller        *poller.Poller
+	projectLister spec.ProjectLister
+
+	// Internals.
+
+	// emitted holds the project and sha of deployments that were emitted,
+	// and are not finished yet, as they keep triggering until then.
+	emitted map[string]struct{}
+	mutex   *sync.Mutex
+
+	// Settings.
+	baseURL       string
+	branch        string
+	environment   string
+	organisation  string
+	pollInterval  time.Duration
+	projectList   []string
+	statusContext string
+	token         string
+	trigger       Trigger
+}
+
+func (e *GiteaEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	e.logger.Log("debug", "starting polling for gitea deployment events", "interval", e.pollInterval, "trigger", e.trigger)
+
+	deploymentEventChannel := make(chan spec.DeploymentEvent)
+
+	go func() {
+		defer close(deploymentEventChannel)
+
+		for {
+			projectList := e.projects()
+
+			e.logger.Log("debug", "Fetching deployment events", "projectlist", projectList)
+			e.poller.Each(ctx, projectList, func(project string) {
+				event, ok, err := e.fetchDeploymentEvent(project)
+				if err != nil {
+					e.logger.Log("error", "could not fetch deployment events", "message", err.Error())
+					return
+				}
+				if !ok || !e.markEmitted(event) {
+					return
+				}
+
+				select {
+				case deploymentEventChannel <- event:
+				case <-ctx.Done():
+				}
+			})
+
+			if !e.poller.Wait(ctx) {
+				e.logger.Log("debug", "stopped polling for gitea deployment events")
+				return
+			}
+		}
+	}()
+
+	return deploymentEventChannel, nil
+}
+
+// projects returns the projects to poll, as listed by the project lister, if
+// there is one.
+func (e *GiteaEventer) projects() []string {
+	if e.projectLister != nil {
+		return e.projectLister.ProjectList()
+	}
+
+	return e.projectList
+}
+
+func (e *GiteaEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	if status.State.IsFinal() {
+		defer e.unmarkEmitted(event)
+	}
+
+	s := commitStatus{
+		Context:     e.statusContext,
+		Description: status.Description,
+		State:       commitStatusStateFor(status.State),
+		TargetURL:   status.LogURL,
+	}
+
+	return e.postCommitStatus(event.Name, event.Sha, s)
+}
+
+// fetchDeploymentEvent returns the deployment event of the project, if the
+// configured trigger requests a deployment.
+func (e *GiteaEventer) fetchDeploymentEvent(project string) (spec.DeploymentEvent, bool, error) {
+	if e.trigger == ReleaseTrigger {
+		return e.fetchReleaseDeploymentEvent(project)
+	}
+
+	return e.fetchStatusDeploymentEvent(project)
+}
+
+// markEmitted records the deployment as emitted. It returns false if the
+// deployment was already emitted.
+func (e *GiteaEventer) markEmitted(event spec.DeploymentEvent) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	key := event.Name + "@" + event.Sha
+	if _, ok := e.emitted[key]; ok {
+		return false
+	}
+	e.emitted[key] = struct{}{}
+
+	return true
+}
+
+// unmarkEmitted forgets about an emitted deployment once it is finished.
+func (e *GiteaEventer) unmarkEmitted(event spec.DeploymentEvent) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.emitted, event.Name+"@"+event.Sha)
+}
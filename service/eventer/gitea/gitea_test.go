@@ -0,0 +1,141 @@
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// TestGiteaEventer tests both deployment triggers, and writing back
+// statuses, against a fake Gitea API server.
+func TestGiteaEventer(t *testing.T) {
+	statuses := map[string]commitStatus{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/v1/repos/giantswarm/api/releases":
+			fmt.Fprint(w, `[
+				{"id": 3, "name": "Draft", "tag_name": "v1.2.0", "draft": true},
+				{"id": 2, "name": "Version 1.1.0", "tag_name": "v1.1.0", "author": {"login": "octocat"}},
+				{"id": 1, "name": "Version 1.0.0", "tag_name": "v1.0.0"}
+			]`)
+		case r.Method == "GET" && r.URL.Path == "/api/v1/repos/giantswarm/api/tags/v1.1.0":
+			fmt.Fprint(w, `{"name": "v1.1.0", "commit": {"sha": "12345"}}`)
+		case r.Method == "GET" && r.URL.Path == "/api/v1/repos/giantswarm/api/commits/12345/status":
+			fmt.Fprint(w, `{"sha": "12345", "statuses": [
+				{"id": 7, "context": "ci/build", "state": "success"}
+			]}`)
+		case r.Method == "GET" && r.URL.Path == "/api/v1/repos/giantswarm/api/commits/master/status":
+			fmt.Fprint(w, `{"sha": "67890", "statuses": [
+				{"id": 8, "context": "deploy/production", "state": "pending", "description": "Deploy", "creator": {"login": "octocat"}},
+				{"id": 9, "context": "deploy/staging", "state": "success"}
+			]}`)
+		case r.Method == "POST" && r.URL.Path == "/api/v1/repos/giantswarm/api/statuses/67890":
+			var status commitStatus
+			if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			statuses[r.URL.Path] = status
+
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	e := GiteaEventer{
+		client: server.Client(),
+		logger: microloggertest.New(),
+
+		emitted: map[string]struct{}{},
+		mutex:   &sync.Mutex{},
+
+		baseURL:       server.URL,
+		branch:        "master",
+		environment:   "production",
+		organisation:  "giantswarm",
+		statusContext: "deploy/production",
+		token:         "token",
+	}
+
+	event, ok, err := e.fetchReleaseDeploymentEvent("api")
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if !ok {
+		t.Fatalf("expected release deployment event")
+	}
+	expectedEvent := spec.DeploymentEvent{ID: 2, Name: "api", Sha: "12345", Creator: "octocat", Description: "Version 1.1.0", Ref: "v1.1.0"}
+	if !reflect.DeepEqual(expectedEvent, event) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedEvent, event)
+	}
+
+	event, ok, err = e.fetchStatusDeploymentEvent("api")
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if !ok {
+		t.Fatalf("expected status deployment event")
+	}
+	expectedEvent = spec.DeploymentEvent{ID: 8, Name: "api", Sha: "67890", Creator: "octocat", Description: "Deploy", Ref: "master"}
+	if !reflect.DeepEqual(expectedEvent, event) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedEvent, event)
+	}
+
+	if !e.markEmitted(event) {
+		t.Fatalf("expected deployment to be marked as emitted")
+	}
+	if e.markEmitted(event) {
+		t.Fatalf("expected deployment to be emitted only once")
+	}
+
+	status := spec.DeploymentStatus{State: spec.SuccessState, Description: "Deployed", LogURL: "https://draughtsman/deployments/api/8"}
+	if err := e.SetStatus(event, status); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	expectedStatus := commitStatus{Context: "deploy/production", Description: "Deployed", State: successState, TargetURL: "https://draughtsman/deployments/api/8"}
+	if returnedStatus := statuses["/api/v1/repos/giantswarm/api/statuses/67890"]; returnedStatus != expectedStatus {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedStatus, returnedStatus)
+	}
+
+	if !e.markEmitted(event) {
+		t.Fatalf("expected finished deployment to be unmarked")
+	}
+}
+
+// TestCommitStatusStateFor tests mapping deployment states to Commit Status
+// states.
+func TestCommitStatusStateFor(t *testing.T) {
+	tests := []struct {
+		state         spec.DeploymentState
+		expectedState commitStatusState
+	}{
+		{state: spec.QueuedState, expectedState: pendingState},
+		{state: spec.InProgressState, expectedState: pendingState},
+		{state: spec.SuccessState, expectedState: successState},
+		{state: spec.FailureState, expectedState: failureState},
+	}
+
+	for index, test := range tests {
+		if returnedState := commitStatusStateFor(test.state); returnedState != test.expectedState {
+			t.Fatalf("%v: expected: %v, returned: %v", index, test.expectedState, returnedState)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package gitea
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// prometheusNamespace is the namespace to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusNamespace = "draughtsman"
+
+	// prometheusSubsystem is the subsystem to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusSubsystem = "gitea_eventer"
+)
+
+var (
+	requestDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "gitea_duration_milliseconds",
+			Help:      "Time taken to request Gitea releases and commit statuses.",
+		},
+		[]string{"method", "organisation", "project", "code"},
+	)
+	responseCodeTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "gitea_response_code",
+			Help:      "Response codes of Gitea API requests.",
+		},
+		[]string{"method", "organisation", "project", "code"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(responseCodeTotal)
+}
+
+// updateRequestMetrics is a utility function for updating metrics related
+// to Gitea API calls.
+func updateRequestMetrics(method, organisation, project string, statusCode int, startTime time.Time) {
+	requestDuration.WithLabelValues(
+		method,
+		organisation,
+		project,
+		strconv.Itoa(statusCode),
+	).Set(
+		float64(time.Since(startTime) / time.Millisecond),
+	)
+
+	responseCodeTotal.WithLabelValues(
+		method,
+		organisation,
+		project,
+		strconv.Itoa(statusCode),
+	).Inc()
+}
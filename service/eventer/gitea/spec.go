@@ -0,0 +1,131 @@
+package gitea
+
+import (
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// Trigger represents what triggers deployments.
+type Trigger string
+
+var (
+	// ReleaseTrigger deploys the newest release of a project, unless its
+	// commit already has a finished deployment status.
+	ReleaseTrigger Trigger = "release"
+	// StatusTrigger deploys the head of the configured branch, when a
+	// pending deployment status was set on it.
+	StatusTrigger Trigger = "status"
+)
+
+// release represents a Gitea API Release.
+// See: https://try.gitea.io/api/swagger#/repository/repoListReleases
+type release struct {
+	// Author is the user who created the release.
+	Author *user `json:"author"`
+
+	// Draft is true for releases that were not published.
+	Draft bool `json:"draft"`
+
+	// ID is the ID of the release.
+	ID int `json:"id"`
+
+	// Name is the title of the release.
+	Name string `json:"name"`
+
+	// Prerelease is true for releases marked as pre-release.
+	Prerelease bool `json:"prerelease"`
+
+	// TagName is the name of the tag of the release.
+	TagName string `json:"tag_name"`
+}
+
+// tag represents a Gitea API Tag.
+// See: https://try.gitea.io/api/swagger#/repository/repoGetTag
+type tag struct {
+	// Commit is the commit the tag points to.
+	Commit struct {
+		Sha string `json:"sha"`
+	} `json:"commit"`
+}
+
+// combinedStatus represents the combined Gitea API Commit Statuses of a
+// commit, holding the latest status of every context.
+// See: https://try.gitea.io/api/swagger#/repository/repoGetCombinedStatusByRef
+type combinedStatus struct {
+	// Sha is the SHA hash of the commit.
+	Sha string `json:"sha"`
+
+	// Statuses are the latest statuses of every context.
+	Statuses []commitStatus `json:"statuses"`
+}
+
+// commitStatus represents a Gitea API Commit Status.
+// See: https://try.gitea.io/api/swagger#/repository/repoCreateStatus
+type commitStatus struct {
+	// Context is the context of the status, e.g: deploy/production.
+	Context string `json:"context"`
+
+	// Creator is the user who created the status.
+	Creator *user `json:"creator,omitempty"`
+
+	// Description is a short description of the status.
+	Description string `json:"description,omitempty"`
+
+	// ID is the ID of the status.
+	ID int `json:"id,omitempty"`
+
+	// State is the state of the status.
+	State commitStatusState `json:"state"`
+
+	// TargetURL is the URL linked from the status.
+	TargetURL string `json:"target_url,omitempty"`
+}
+
+// user represents a Gitea API User.
+type user struct {
+	// Login is the login name of the user.
+	Login string `json:"login"`
+}
+
+// commitStatusState represents possible Commit Status states.
+type commitStatusState string
+
+var (
+	// pendingState is the state of pending Commit Statuses.
+	pendingState commitStatusState = "pending"
+	// successState is the state of successful Commit Statuses.
+	successState commitStatusState = "success"
+	// failureState is the state of failed Commit Statuses.
+	failureState commitStatusState = "failure"
+	// errorState is the state of errored Commit Statuses.
+	errorState commitStatusState = "error"
+)
+
+// isFinished returns true if the state marks the deployment as finished.
+func (s commitStatusState) isFinished() bool {
+	return s == successState || s == failureState || s == errorState
+}
+
+// commitStatusStateFor returns the Commit Status state matching the given
+// deployment state. Gitea has no notion of queued or running statuses, so
+// they are reported as pending.
+func commitStatusStateFor(state spec.DeploymentState) commitStatusState {
+	switch state {
+	case spec.SuccessState:
+		return successState
+	case spec.FailureState:
+		return failureState
+	default:
+		return pendingState
+	}
+}
+
+// findStatus returns the status of the given context, if there is one.
+func (c combinedStatus) findStatus(context string) (commitStatus, bool) {
+	for _, status := range c.Statuses {
+		if status.Context == context {
+			return status, true
+		}
+	}
+
+	return commitStatus{}, false
+}
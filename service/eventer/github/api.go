@@ -0,0 +1,257 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/draughtsman/service/eventer/github/internal/appauth"
+	ratelimit "github.com/giantswarm/draughtsman/service/eventer/github/internal/ratelimit"
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+)
+
+// ClientConfig represents the configuration used to create a GitHub Client.
+type ClientConfig struct {
+	// Dependencies.
+	HTTPClient httpspec.Client
+	Logger     micrologger.Logger
+
+	// Settings.
+
+	// AppID, AppInstallationID and AppPrivateKey configure authentication
+	// as a GitHub App. They are used instead of OAuthToken when AppID is set.
+	// AppInstallationID is optional, and looked up for the organisation when
+	// empty.
+	AppID             int64
+	AppInstallationID int64
+	AppPrivateKey     string
+	// BaseURL is the URL of the GitHub API. For GitHub Enterprise Server,
+	// this is the URL of the instance, with or without the /api/v3 prefix.
+	BaseURL      string
+	OAuthToken   string
+	Organisation string
+}
+
+// DefaultClientConfig provides a default configuration to create a new
+// GitHub Client by best effort.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		// Dependencies.
+		HTTPClient: nil,
+		Logger:     nil,
+
+		// Settings.
+		BaseURL: DefaultBaseURL,
+	}
+}
+
+// NewClient creates a new configured GitHub Client.
+func NewClient(config ClientConfig) (*Client, error) {
+	if config.HTTPClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	if config.BaseURL == "" {
+		return nil, microerror.Maskf(invalidConfigError, "base url must not be empty")
+	}
+	if config.AppID == 0 && config.OAuthToken == "" {
+		return nil, microerror.Maskf(invalidConfigError, "oauth token or app id must not be empty")
+	}
+	if config.Organisation == "" {
+		return nil, microerror.Maskf(invalidConfigError, "organisation must not be empty")
+	}
+
+	baseURL, err := normaliseBaseURL(config.BaseURL)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	tokenSource, err := newTokenSource(config.HTTPClient, baseURL, config.AppID, config.AppInstallationID, config.Organisation, config.AppPrivateKey)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	client := &Client{
+		// Dependencies.
+		client:      config.HTTPClient,
+		logger:      config.Logger,
+		rateLimiter: ratelimit.New(),
+		tokenSource: tokenSource,
+
+		// Settings.
+		baseURL:      baseURL,
+		oauthToken:   config.OAuthToken,
+		organisation: config.Organisation,
+	}
+
+	return client, nil
+}
+
+// newTokenSource creates the token source authenticating as a GitHub App. It
+// returns nil if no App is configured, in which case the OAuth token is used.
+func newTokenSource(client httpspec.Client, baseURL string, appID, installationID int64, organisation, privateKey string) (*appauth.TokenSource, error) {
+	if appID == 0 {
+		return nil, nil
+	}
+
+	c := appauth.Config{
+		HTTPClient: client,
+
+		AppID:          appID,
+		BaseURL:        baseURL,
+		InstallationID: installationID,
+		Organisation:   organisation,
+		PrivateKey:     []byte(privateKey),
+	}
+
+	tokenSource, err := appauth.New(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return tokenSource, nil
+}
+
+// Client makes authenticated and rate limited requests to the GitHub API of
+// an organisation. The GitHub Eventers and the TopicLister share a Client, so
+// that they share a single rate limit as well.
+type Client struct {
+	// Dependencies.
+	client      httpspec.Client
+	logger      micrologger.Logger
+	rateLimiter *ratelimit.RateLimiter
+	// tokenSource is only set when authenticating as a GitHub App.
+	tokenSource *appauth.TokenSource
+
+	// Settings.
+	baseURL      string
+	oauthToken   string
+	organisation string
+}
+
+// request makes a request, handling any metrics and logging. It waits for
+// the rate limiter before every attempt, and retries requests that GitHub
+// rejected because of rate limiting.
+func (c *Client) request(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		c.rateLimiter.Wait()
+
+		token := c.oauthToken
+		if c.tokenSource != nil {
+			var err error
+			token, err = c.tokenSource.Token()
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", token))
+
+		// The body of a retried request has been consumed by the previous
+		// attempt already, so it has to be recreated.
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			req.Body = body
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		// Update rate limit metrics.
+		updateRateLimitMetrics(resp)
+
+		rateLimited, err := c.updateRateLimiter(resp)
+		if err != nil {
+			resp.Body.Close()
+			return nil, microerror.Mask(err)
+		}
+		if !rateLimited || attempt >= rateLimitMaxRetries {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+
+		c.logger.Log("debug", "github rate limit hit, retrying request", "url", req.URL.String(), "attempt", attempt+1)
+	}
+}
+
+// updateRateLimiter updates latest rate limiting token bucket values from
+// response received. It returns true if GitHub rejected the request because
+// of rate limiting, in which case the rate limiter is blocked until the
+// request may be retried.
+func (c *Client) updateRateLimiter(response *http.Response) (bool, error) {
+	rateLimitRemaining, hasRemaining := parseRateLimitRemaining(response)
+	rateLimitResetTime, hasResetTime := parseRateLimitResetTime(response)
+
+	rateLimited, err := isRateLimited(response)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if rateLimited {
+		// GitHub tells us how long to wait for secondary rate limits, or the
+		// time the bucket refills when the primary rate limit is exhausted.
+		// If neither is known, we back off exponentially.
+		var wait time.Duration
+		if retryAfter, ok := parseRetryAfter(response); ok {
+			wait = retryAfter
+			c.rateLimiter.Block(wait)
+		} else if hasRemaining && hasResetTime && rateLimitRemaining == 0 {
+			wait = time.Until(rateLimitResetTime) + rateLimitExtraWait
+			c.rateLimiter.Block(wait)
+		} else {
+			wait = c.rateLimiter.Backoff()
+		}
+
+		updateRateLimitedMetrics(response.StatusCode)
+
+		c.logger.Log("debug", "github rate limit hit", "code", response.StatusCode, "wait", wait)
+
+		return true, nil
+	}
+
+	c.rateLimiter.Reset()
+
+	// Missing headers mean the current rate limit is unknown, e.g. with
+	// proxies or GitHub Enterprise Server with rate limiting disabled. In
+	// this case the rate limiter is left untouched.
+	if !hasRemaining || !hasResetTime {
+		return false, nil
+	}
+
+	timeToRefill := rateLimitResetTime.Sub(time.Now())
+	// This is needed because rateLimiter accepts positive intervals only.
+	if timeToRefill <= 0 {
+		timeToRefill = 1 * time.Second
+	}
+
+	// If we are close to hit the rate limit, wait some extra time.
+	if rateLimitRemaining < rateLimitAlmostHitThreshold {
+		if rateLimitRemaining == 0 {
+			// We must have at least one token in bucket. Otherwise it would
+			// deadlock.
+			rateLimitRemaining = 1
+		}
+
+		// If all tokens have been spent from GitHub API, doubling the wait
+		// period + adding some extra is expected to make that 1 token be
+		// available when the GitHub bucket gets refilled. When there's more
+		// than 1 still left, this just makes spending of them slower.
+		timeToRefill = 2*timeToRefill + rateLimitExtraWait
+	}
+
+	c.rateLimiter.Update(timeToRefill, int64(rateLimitRemaining))
+
+	return false, nil
+}
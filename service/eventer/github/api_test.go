@@ -0,0 +1,86 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	ratelimit "github.com/giantswarm/draughtsman/service/eventer/github/internal/ratelimit"
+)
+
+// testClient returns a Client making requests to the given test server.
+func testClient(server *httptest.Server) *Client {
+	return &Client{
+		client:      server.Client(),
+		logger:      microloggertest.New(),
+		rateLimiter: ratelimit.New(),
+
+		baseURL:      server.URL,
+		oauthToken:   "token",
+		organisation: "giantswarm",
+	}
+}
+
+// TestRequestRateLimited tests that requests rejected because of rate
+// limiting are retried after the duration GitHub asks for.
+func TestRequestRateLimited(t *testing.T) {
+	tests := []struct {
+		header     http.Header
+		statusCode int
+		body       string
+	}{
+		// Test that a 429 with Retry-After is retried.
+		{
+			header:     http.Header{retryAfterHeader: []string{"0"}},
+			statusCode: http.StatusTooManyRequests,
+		},
+
+		// Test that a secondary rate limit 403 is retried.
+		{
+			header:     http.Header{retryAfterHeader: []string{"0"}},
+			statusCode: http.StatusForbidden,
+			body:       `{"message": "You have exceeded a secondary rate limit."}`,
+		},
+	}
+
+	for index, test := range tests {
+		requests := 0
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			if requests == 1 {
+				for key, values := range test.header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(test.statusCode)
+				fmt.Fprint(w, test.body)
+				return
+			}
+
+			fmt.Fprint(w, `[]`)
+		}))
+
+		c := testClient(server)
+
+		req, err := http.NewRequest("GET", server.URL, nil)
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v", index, err)
+		}
+
+		resp, err := c.request(req)
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v", index, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || requests != 2 {
+			t.Fatalf("%v\nexpected request to be retried, got status %d after %d requests", index, resp.StatusCode, requests)
+		}
+
+		server.Close()
+	}
+}
@@ -6,27 +6,62 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/giantswarm/microerror"
 )
 
 const (
+	// DefaultBaseURL is the URL of the public GitHub API.
+	DefaultBaseURL = "https://api.github.com"
+
+	// enterpriseAPIPath is the path prefix under which GitHub Enterprise
+	// Server serves the REST API.
+	// See: https://developer.github.com/enterprise/v3/#current-version
+	enterpriseAPIPath = "/api/v3"
+
 	// deploymentUrlFormat is the string format for the GitHub
 	// API call for Deployments.
 	// See: https://developer.github.com/v3/repos/deployments/#list-deployments
-	deploymentUrlFormat = "https://api.github.com/repos/%s/%s/deployments"
+	deploymentUrlFormat = "%s/repos/%s/%s/deployments"
 
 	// deploymentStatusUrlFormat is the string format for the
 	// GitHub API call for Deployment Statuses.
 	// See: https://developer.github.com/v3/repos/deployments/#create-a-deployment-status
-	deploymentStatusUrlFormat = "https://api.github.com/repos/%s/%s/deployments/%v/statuses"
+	deploymentStatusUrlFormat = "%s/repos/%s/%s/deployments/%v/statuses"
 
 	// etagHeader is the header used for etag.
 	// See: https://en.wikipedia.org/wiki/HTTP_ETag.
 	etagHeader = "Etag"
 
+	// statusMediaType is the media type enabling the queued and in_progress
+	// states, as well as the inactive state and the log_url and
+	// environment_url fields, of Deployment Statuses.
+	// See: https://developer.github.com/v3/previews/#deployment-statuses
+	// See: https://developer.github.com/v3/previews/#enhanced-deployments
+	statusMediaType = "application/vnd.github.flash-preview+json, application/vnd.github.ant-man-preview+json"
+
+	// statusDescriptionMaxLength is the maximum length of the description of
+	// a Deployment Status accepted by GitHub.
+	statusDescriptionMaxLength = 140
+
+	// linkHeader is the header used by GitHub for pagination.
+	// See: https://developer.github.com/v3/#pagination
+	linkHeader = "Link"
+
+	// deploymentsPerPage is the number of deployments requested per page.
+	// GitHub allows at most 100.
+	deploymentsPerPage = 100
+
+	// deploymentsMaxPages is the maximum number of pages of deployments that
+	// are fetched in a single poll, when following pages up to the previously
+	// seen deployment. The first poll of a project, when there is no
+	// previously seen deployment to stop at, only fetches the first page.
+	deploymentsMaxPages = 10
+
 	// rateLimitLimitHeader is the header set by GitHub to show the total
 	// rate limit value.
 	// See: https://developer.github.com/v3/#rate-limiting
@@ -42,6 +77,16 @@ const (
 	// See: https://developer.github.com/v3/#rate-limiting
 	rateLimitResetHeader = "X-RateLimit-Reset"
 
+	// retryAfterHeader is the header set by GitHub to show the number of
+	// seconds to wait before retrying a request, e.g. when the secondary rate
+	// limit was hit.
+	// See: https://developer.github.com/v3/#abuse-rate-limits
+	retryAfterHeader = "Retry-After"
+
+	// rateLimitMaxRetries is the number of times a request that was rejected
+	// because of rate limiting is retried.
+	rateLimitMaxRetries = 3
+
 	// rateLimitExtraWait is the additional duration that should be waited
 	// before rate limit bucket is expected to get refilled in GitHub API.
 	rateLimitExtraWait = 5 * time.Second
@@ -51,23 +96,6 @@ const (
 	rateLimitAlmostHitThreshold = 5
 )
 
-// request makes a request, handling any metrics and logging.
-func (e *GithubEventer) request(req *http.Request) (*http.Response, error) {
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", e.oauthToken))
-
-	resp, err := e.client.Do(req)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	// Update rate limit metrics.
-	if err := updateRateLimitMetrics(resp); err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	return resp, err
-}
-
 // filterDeploymentsByEnvironment filters out deployments that do not apply
 // to this environment.
 func (e *GithubEventer) filterDeploymentsByEnvironment(deployments []deployment) []deployment {
@@ -83,22 +111,23 @@ func (e *GithubEventer) filterDeploymentsByEnvironment(deployments []deployment)
 }
 
 // filterDeploymentsByStatus filters out deployments that are finished -
-// that is, there exists at least one status that is not pending.
+// that is, there exists at least one status that finishes the deployment.
+// Deployments that were queued or in progress before a restart are not
+// finished. They are listed again after it, as loadCursors moves the cursor
+// back to before them, and so pass this filter and are acted on again.
 func (e *GithubEventer) filterDeploymentsByStatus(deployments []deployment) []deployment {
 	matches := []deployment{}
 
 	for _, deployment := range deployments {
-		// If there are any statuses apart from pending, we consider the
-		// deployment finished, and do not act on it.
-		isPending := true
+		isFinished := false
 		for _, status := range deployment.Statuses {
-			if status.State != pendingState {
-				isPending = false
+			if status.State.isFinished() {
+				isFinished = true
 				break
 			}
 		}
 
-		if isPending {
+		if !isFinished {
 			matches = append(matches, deployment)
 		}
 	}
@@ -107,90 +136,170 @@ func (e *GithubEventer) filterDeploymentsByStatus(deployments []deployment) []de
 }
 
 // fetchNewDeploymentEvents fetches any new GitHub Deployment Events for the
-// given project.
-func (e *GithubEventer) fetchNewDeploymentEvents(project string, etagMap map[string]string) ([]deployment, error) {
-	url := fmt.Sprintf(
+// given project, and returns the moved cursor of the project. Deployments are
+// listed newest first, so pages are followed until a deployment is found that
+// was already seen in a previous poll. Without such a deployment, only the
+// first page is fetched, as every deployment fetched costs another request
+// for its statuses.
+func (e *GithubEventer) fetchNewDeploymentEvents(project string, cursor projectCursor) ([]deployment, projectCursor, error) {
+	query := url.Values{}
+	query.Set("environment", e.environment)
+	query.Set("per_page", strconv.Itoa(deploymentsPerPage))
+
+	pageURL := fmt.Sprintf(
 		deploymentUrlFormat,
+		e.baseURL,
 		e.organisation,
 		project,
-	)
+	) + "?" + query.Encode()
 
-	req, err := http.NewRequest("GET", url, nil)
+	var etag string
+	newestID := cursor.LastDeploymentID
+	oldestID := 0
+	reachedCursor := cursor.LastDeploymentID == 0
+	deployments := []deployment{}
+
+	maxPages := deploymentsMaxPages
+	if cursor.LastDeploymentID == 0 {
+		maxPages = 1
+	}
+
+	for pageNumber := 0; pageURL != "" && pageNumber < maxPages; pageNumber++ {
+		// Only the first page is requested with the etag, as it is the one
+		// that changes when new deployments are created.
+		var ifNoneMatch string
+		if pageNumber == 0 {
+			ifNoneMatch = cursor.ETag
+		}
+
+		page, err := e.fetchDeploymentsPage(project, pageURL, ifNoneMatch)
+		if err != nil {
+			return nil, projectCursor{}, microerror.Mask(err)
+		}
+
+		if pageNumber == 0 {
+			if page.NotModified {
+				return []deployment{}, cursor, nil
+			}
+			etag = page.ETag
+		}
+
+		for _, deployment := range page.Deployments {
+			if deployment.ID <= cursor.LastDeploymentID {
+				reachedCursor = true
+				break
+			}
+			if deployment.ID > newestID {
+				newestID = deployment.ID
+			}
+			if oldestID == 0 || deployment.ID < oldestID {
+				oldestID = deployment.ID
+			}
+
+			deployments = append(deployments, deployment)
+		}
+
+		if reachedCursor {
+			break
+		}
+
+		pageURL = page.NextURL
+	}
+
+	// Deployments between the cursor and the oldest fetched deployment are
+	// skipped if more of them were created since the last poll than fit into
+	// the maximum number of pages, as the cursor moves past them anyway.
+	if !reachedCursor && pageURL != "" {
+		e.logger.Log("error", "skipping deployments beyond the maximum number of pages", "project", project, "from", cursor.LastDeploymentID+1, "to", oldestID-1, "pages", maxPages)
+	}
+
+	deployments = e.filterDeploymentsByEnvironment(deployments)
+
+	for index, deployment := range deployments {
+		deploymentStatuses, err := e.fetchDeploymentStatus(project, deployment)
+		if err != nil {
+			return nil, projectCursor{}, microerror.Mask(err)
+		}
+
+		deployments[index].Statuses = deploymentStatuses
+	}
+
+	deployments = e.filterDeploymentsByStatus(deployments)
+
+	if len(deployments) > 0 {
+		e.logger.Log("debug", "found new deployment events", "project", project)
+	}
+
+	// The cursor is only moved once all deployments have been fetched
+	// successfully, so that failed polls are retried.
+	cursor = projectCursor{
+		ETag:             etag,
+		LastDeploymentID: newestID,
+	}
+
+	return deployments, cursor, nil
+}
+
+// fetchDeploymentsPage fetches a single page of GitHub Deployments. If
+// ifNoneMatch is set, and the page did not change since, the returned page
+// is marked as not modified.
+func (e *GithubEventer) fetchDeploymentsPage(project, pageURL, ifNoneMatch string) (deploymentsPage, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
 	if err != nil {
-		return nil, microerror.Mask(err)
+		return deploymentsPage{}, microerror.Mask(err)
 	}
 
 	// If we have an etag header for this project, then we have already
 	// requested deployment events for it.
 	// So, set the header so we only get notified of new events.
-	if val, ok := etagMap[project]; ok {
-		req.Header.Set("If-None-Match", val)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
 	}
 
-	e.rateLimiter.Wait()
-
 	startTime := time.Now()
 
-	resp, err := e.request(req)
+	resp, err := e.client.request(req)
 	if err != nil {
-		return nil, microerror.Mask(err)
+		return deploymentsPage{}, microerror.Mask(err)
 	}
 	defer resp.Body.Close()
 
 	updateDeploymentMetrics(e.organisation, project, resp.StatusCode, startTime)
 
-	err = e.updateRateLimiter(resp)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	// Save the new etag header, so we don't get these deployment events again.
-	etagMap[project] = resp.Header.Get(etagHeader)
-
 	if resp.StatusCode == http.StatusNotModified {
-		return []deployment{}, nil
+		return deploymentsPage{NotModified: true}, nil
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		e.logger.Log("error", "Error fetching deployment events", "project", project)
 		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, microerror.Maskf(unexpectedStatusCode, fmt.Sprintf("received non-200 status code: %v, body: %q", resp.StatusCode, string(body)))
+		return deploymentsPage{}, microerror.Maskf(unexpectedStatusCode, fmt.Sprintf("received non-200 status code: %v, body: %q", resp.StatusCode, string(body)))
 	}
 
 	bytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, microerror.Mask(err)
+		return deploymentsPage{}, microerror.Mask(err)
 	}
 
 	var deployments []deployment
 	if err := json.Unmarshal(bytes, &deployments); err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	deployments = e.filterDeploymentsByEnvironment(deployments)
-
-	for index, deployment := range deployments {
-		deploymentStatuses, err := e.fetchDeploymentStatus(project, deployment)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-
-		deployments[index].Statuses = deploymentStatuses
+		return deploymentsPage{}, microerror.Mask(err)
 	}
 
-	deployments = e.filterDeploymentsByStatus(deployments)
-
-	if len(deployments) > 0 {
-		e.logger.Log("debug", "found new deployment events", "project", project)
+	page := deploymentsPage{
+		Deployments: deployments,
+		ETag:        resp.Header.Get(etagHeader),
+		NextURL:     parseNextPageURL(resp),
 	}
 
-	return deployments, nil
+	return page, nil
 }
 
 // fetchDeploymentStatus fetches Deployment Statuses for the given Deployment.
 func (e *GithubEventer) fetchDeploymentStatus(project string, deployment deployment) ([]deploymentStatus, error) {
 	url := fmt.Sprintf(
 		deploymentStatusUrlFormat,
+		e.baseURL,
 		e.organisation,
 		project,
 		deployment.ID,
@@ -201,11 +310,11 @@ func (e *GithubEventer) fetchDeploymentStatus(project string, deployment deploym
 		return nil, microerror.Mask(err)
 	}
 
-	e.rateLimiter.Wait()
+	req.Header.Set("Accept", statusMediaType)
 
 	startTime := time.Now()
 
-	resp, err := e.request(req)
+	resp, err := e.client.request(req)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
@@ -213,11 +322,6 @@ func (e *GithubEventer) fetchDeploymentStatus(project string, deployment deploym
 
 	updateDeploymentStatusMetrics("GET", e.organisation, project, resp.StatusCode, startTime)
 
-	err = e.updateRateLimiter(resp)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
 	if resp.StatusCode != http.StatusOK {
 		body, _ := ioutil.ReadAll(resp.Body)
 		return nil, microerror.Maskf(unexpectedStatusCode, fmt.Sprintf("received non-200 status code: %v, body: %q", resp.StatusCode, string(body)))
@@ -237,19 +341,18 @@ func (e *GithubEventer) fetchDeploymentStatus(project string, deployment deploym
 }
 
 // postDeploymentStatus posts a Deployment Status for the given Deployment.
-func (e *GithubEventer) postDeploymentStatus(project string, id int, state deploymentStatusState) error {
-	e.logger.Log("debug", "posting deployment status", "project", project, "id", id, "state", state)
+func (e *GithubEventer) postDeploymentStatus(project string, id int, status deploymentStatus) error {
+	e.logger.Log("debug", "posting deployment status", "project", project, "id", id, "state", status.State)
 
 	url := fmt.Sprintf(
 		deploymentStatusUrlFormat,
+		e.baseURL,
 		e.organisation,
 		project,
 		id,
 	)
 
-	status := deploymentStatus{
-		State: state,
-	}
+	status.Description = truncateDescription(status.Description)
 
 	payload, err := json.Marshal(status)
 	if err != nil {
@@ -261,11 +364,11 @@ func (e *GithubEventer) postDeploymentStatus(project string, id int, state deplo
 		return microerror.Mask(err)
 	}
 
-	e.rateLimiter.Wait()
+	req.Header.Set("Accept", statusMediaType)
 
 	startTime := time.Now()
 
-	resp, err := e.request(req)
+	resp, err := e.client.request(req)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -273,11 +376,6 @@ func (e *GithubEventer) postDeploymentStatus(project string, id int, state deplo
 
 	updateDeploymentStatusMetrics("POST", e.organisation, project, resp.StatusCode, startTime)
 
-	err = e.updateRateLimiter(resp)
-	if err != nil {
-		return microerror.Mask(err)
-	}
-
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := ioutil.ReadAll(resp.Body)
 		return microerror.Maskf(unexpectedStatusCode, fmt.Sprintf("received non-200 status code: %v, body: %q", resp.StatusCode, string(body)))
@@ -286,85 +384,136 @@ func (e *GithubEventer) postDeploymentStatus(project string, id int, state deplo
 	return nil
 }
 
-// updateRateLimiter updates latest rate limiting token bucket values from
-// response received.
-func (e *GithubEventer) updateRateLimiter(response *http.Response) error {
-	rateLimitRemaining, err := parseRateLimitRemaining(response)
+// isRateLimited returns true if the response is GitHub rejecting a request
+// because of the primary or secondary rate limit. GitHub answers 429, or 403
+// with either rate limit headers or a message telling so in the body.
+// See: https://developer.github.com/v3/#abuse-rate-limits
+func isRateLimited(response *http.Response) (bool, error) {
+	if response.StatusCode == http.StatusTooManyRequests {
+		return true, nil
+	}
+	if response.StatusCode != http.StatusForbidden {
+		return false, nil
+	}
+
+	if response.Header.Get(retryAfterHeader) != "" || response.Header.Get(rateLimitRemainingHeader) == "0" {
+		return true, nil
+	}
+
+	// The body is read to look for the rate limit message, and then put back
+	// for the caller to consume.
+	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return microerror.Mask(err)
+		return false, microerror.Mask(err)
 	}
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
 
-	rateLimitResetTime, err := parseRateLimitResetTime(response)
+	return strings.Contains(strings.ToLower(string(body)), "rate limit"), nil
+}
+
+// normaliseBaseURL returns the GitHub API URL to use for the given base URL.
+// For GitHub Enterprise Server, the base URL may be given with or without
+// the API path prefix, e.g: https://github.example.com or
+// https://github.example.com/api/v3.
+func normaliseBaseURL(baseURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
 	if err != nil {
-		return microerror.Mask(err)
+		return "", microerror.Mask(err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", microerror.Maskf(invalidConfigError, "base url %#q must be absolute", baseURL)
 	}
 
-	timeToRefill := rateLimitResetTime.Sub(time.Now())
-	// This is needed because rateLimiter accepts positive intervals only.
-	if timeToRefill <= 0 {
-		timeToRefill = 1 * time.Second
+	if u.Host != "api.github.com" && !strings.HasSuffix(u.Path, enterpriseAPIPath) {
+		u.Path += enterpriseAPIPath
 	}
 
-	// If we are close to hit the rate limit, wait some extra time.
-	if rateLimitRemaining < rateLimitAlmostHitThreshold {
-		if rateLimitRemaining == 0 {
-			// We must have at least one token in bucket. Otherwise it would
-			// deadlock.
-			rateLimitRemaining = 1
+	return u.String(), nil
+}
+
+// parseNextPageURL parses the URL of the next page from the Link header of a
+// paginated GitHub API response. It returns an empty string on the last page.
+// See: https://developer.github.com/v3/#pagination
+func parseNextPageURL(response *http.Response) string {
+	for _, link := range strings.Split(response.Header.Get(linkHeader), ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
 		}
 
-		// If all tokens have been spent from GitHub API, doubling the wait
-		// period + adding some extra is expected to make that 1 token be
-		// available when the GitHub bucket gets refilled. When there's more
-		// than 1 still left, this just makes spending of them slower.
-		timeToRefill = 2*timeToRefill + rateLimitExtraWait
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
 	}
 
-	e.rateLimiter.Update(timeToRefill, int64(rateLimitRemaining))
-
-	return nil
+	return ""
 }
 
 // parseRateLimitValue parses GitHub API rate limit value from response
-// headers.
-func parseRateLimitValue(response *http.Response) (float64, error) {
-	value := response.Header.Get(rateLimitLimitHeader)
-	if value == "" {
-		return 0.0, microerror.Maskf(executionFailedError, "%#q header is missing", rateLimitLimitHeader)
-	}
-	rateLimitLimitValue, err := strconv.ParseFloat(value, 64)
+// headers. The second return value is false if the value is unknown.
+func parseRateLimitValue(response *http.Response) (float64, bool) {
+	rateLimitLimitValue, err := strconv.ParseFloat(response.Header.Get(rateLimitLimitHeader), 64)
 	if err != nil {
-		return 0.0, microerror.Mask(err)
+		return 0.0, false
 	}
-	return rateLimitLimitValue, nil
+
+	return rateLimitLimitValue, true
 }
 
 // parseRateLimitRemaining parses remaining GitHub API request tokens before
-// rate limiting prevents further requests.
-func parseRateLimitRemaining(response *http.Response) (float64, error) {
-	value := response.Header.Get(rateLimitRemainingHeader)
-	if value == "" {
-		return 0.0, microerror.Maskf(executionFailedError, "%#q header is missing", rateLimitRemainingHeader)
-	}
-	rateLimitRemainingValue, err := strconv.ParseFloat(value, 64)
+// rate limiting prevents further requests. The second return value is false
+// if the value is unknown.
+func parseRateLimitRemaining(response *http.Response) (float64, bool) {
+	rateLimitRemainingValue, err := strconv.ParseFloat(response.Header.Get(rateLimitRemainingHeader), 64)
 	if err != nil {
-		return 0.0, microerror.Mask(err)
+		return 0.0, false
 	}
 
-	return rateLimitRemainingValue, nil
+	return rateLimitRemainingValue, true
 }
 
 // parseRateLimitResetTime parses time when GitHub API's rate limit bucket gets
-// refilled.
-func parseRateLimitResetTime(response *http.Response) (time.Time, error) {
-	value := response.Header.Get(rateLimitResetHeader)
+// refilled. The second return value is false if the value is unknown.
+func parseRateLimitResetTime(response *http.Response) (time.Time, bool) {
+	rateLimitResetValue, err := strconv.ParseInt(response.Header.Get(rateLimitResetHeader), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(rateLimitResetValue, 0), true
+}
+
+// parseRetryAfter parses the duration to wait before retrying a request. The
+// header holds either a number of seconds, or an HTTP date. The second return
+// value is false if the header is missing or malformed.
+// See: https://tools.ietf.org/html/rfc7231#section-7.1.3
+func parseRetryAfter(response *http.Response) (time.Duration, bool) {
+	value := response.Header.Get(retryAfterHeader)
 	if value == "" {
-		return time.Time{}, microerror.Maskf(executionFailedError, "%#q header is missing", rateLimitResetHeader)
+		return 0, false
 	}
-	rateLimitResetValue, err := strconv.ParseInt(value, 10, 64)
-	if err != nil {
-		return time.Time{}, microerror.Mask(err)
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date), true
+	}
+
+	return 0, false
+}
+
+// truncateDescription truncates a Deployment Status description to the
+// maximum length accepted by GitHub, marking it as truncated.
+func truncateDescription(description string) string {
+	runes := []rune(description)
+	if len(runes) <= statusDescriptionMaxLength {
+		return description
 	}
 
-	return time.Unix(rateLimitResetValue, 0), nil
+	return string(runes[:statusDescriptionMaxLength-1]) + "…"
 }
@@ -1,8 +1,18 @@
 package github
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
 )
 
 // TestFilterDeploymentsByEnvironment tests the filterDeployments method.
@@ -138,6 +148,39 @@ func TestFilterDeploymentsByStatus(t *testing.T) {
 			},
 			expectedDeployments: []deployment{},
 		},
+
+		// Test that a deployment that was in progress, e.g. before a restart, is kept.
+		{
+			deployments: []deployment{
+				{
+					Statuses: []deploymentStatus{
+						{State: inProgressState},
+						{State: queuedState},
+					},
+				},
+			},
+			expectedDeployments: []deployment{
+				{
+					Statuses: []deploymentStatus{
+						{State: inProgressState},
+						{State: queuedState},
+					},
+				},
+			},
+		},
+
+		// Test that a deployment that was superseded is not kept.
+		{
+			deployments: []deployment{
+				{
+					Statuses: []deploymentStatus{
+						{State: inactiveState},
+						{State: pendingState},
+					},
+				},
+			},
+			expectedDeployments: []deployment{},
+		},
 	}
 
 	for index, test := range tests {
@@ -153,3 +196,384 @@ func TestFilterDeploymentsByStatus(t *testing.T) {
 		}
 	}
 }
+
+// TestNormaliseBaseURL tests the normaliseBaseURL function.
+func TestNormaliseBaseURL(t *testing.T) {
+	tests := []struct {
+		baseURL         string
+		expectedBaseURL string
+	}{
+		// Test that the public GitHub API URL is kept.
+		{
+			baseURL:         "https://api.github.com",
+			expectedBaseURL: "https://api.github.com",
+		},
+
+		// Test that a trailing slash is removed.
+		{
+			baseURL:         "https://api.github.com/",
+			expectedBaseURL: "https://api.github.com",
+		},
+
+		// Test that the API path is added for GitHub Enterprise Server.
+		{
+			baseURL:         "https://github.example.com",
+			expectedBaseURL: "https://github.example.com/api/v3",
+		},
+
+		// Test that an existing API path is not added twice.
+		{
+			baseURL:         "https://github.example.com/api/v3/",
+			expectedBaseURL: "https://github.example.com/api/v3",
+		},
+	}
+
+	for index, test := range tests {
+		returnedBaseURL, err := normaliseBaseURL(test.baseURL)
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+		}
+
+		if returnedBaseURL != test.expectedBaseURL {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedBaseURL, returnedBaseURL,
+			)
+		}
+	}
+}
+
+// TestFetchNewDeploymentEventsEnterprise tests that deployments can be
+// fetched from a GitHub Enterprise Server, which does not send rate limit
+// headers.
+func TestFetchNewDeploymentEventsEnterprise(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/giantswarm/api/deployments":
+			fmt.Fprint(w, `[{"id": 1, "sha": "12345", "environment": "production"}]`)
+		case "/api/v3/repos/giantswarm/api/deployments/1/statuses":
+			fmt.Fprint(w, `[]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	baseURL, err := normaliseBaseURL(server.URL)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	e := GithubEventer{
+		client: testClient(server),
+		logger: microloggertest.New(),
+
+		baseURL:      baseURL,
+		environment:  "production",
+		organisation: "giantswarm",
+	}
+
+	deployments, _, err := e.fetchNewDeploymentEvents("api", projectCursor{})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	expectedDeployments := []deployment{
+		{ID: 1, Sha: "12345", Environment: "production", Statuses: []deploymentStatus{}},
+	}
+	if !reflect.DeepEqual(expectedDeployments, deployments) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedDeployments, deployments)
+	}
+}
+
+// TestIsRateLimited tests the isRateLimited function.
+func TestIsRateLimited(t *testing.T) {
+	tests := []struct {
+		header              http.Header
+		statusCode          int
+		body                string
+		expectedRateLimited bool
+	}{
+		// Test that a successful response is not rate limited.
+		{
+			header:              http.Header{},
+			statusCode:          http.StatusOK,
+			expectedRateLimited: false,
+		},
+
+		// Test that a 429 response is rate limited.
+		{
+			header:              http.Header{},
+			statusCode:          http.StatusTooManyRequests,
+			expectedRateLimited: true,
+		},
+
+		// Test that a 403 with an exhausted primary rate limit is rate limited.
+		{
+			header:              http.Header{rateLimitRemainingHeader: []string{"0"}},
+			statusCode:          http.StatusForbidden,
+			expectedRateLimited: true,
+		},
+
+		// Test that a 403 for the secondary rate limit is rate limited.
+		{
+			header:              http.Header{},
+			statusCode:          http.StatusForbidden,
+			body:                `{"message": "You have exceeded a secondary rate limit."}`,
+			expectedRateLimited: true,
+		},
+
+		// Test that a 403 for missing permissions is not rate limited.
+		{
+			header:              http.Header{rateLimitRemainingHeader: []string{"4999"}},
+			statusCode:          http.StatusForbidden,
+			body:                `{"message": "Resource not accessible by integration"}`,
+			expectedRateLimited: false,
+		},
+	}
+
+	for index, test := range tests {
+		header := http.Header{}
+		for key, values := range test.header {
+			header[http.CanonicalHeaderKey(key)] = values
+		}
+
+		response := &http.Response{
+			Body:       ioutil.NopCloser(strings.NewReader(test.body)),
+			Header:     header,
+			StatusCode: test.statusCode,
+		}
+
+		returnedRateLimited, err := isRateLimited(response)
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+		}
+
+		if returnedRateLimited != test.expectedRateLimited {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedRateLimited, returnedRateLimited,
+			)
+		}
+
+		// The body must still be readable by the caller.
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil || string(body) != test.body {
+			t.Fatalf("%v\nexpected body to be kept, returned: %q\n", index, string(body))
+		}
+	}
+}
+
+// TestFetchNewDeploymentEventsPagination tests that deployment pages are
+// followed until the last seen deployment, and that the etag of the first
+// page is kept.
+func TestFetchNewDeploymentEventsPagination(t *testing.T) {
+	var server *httptest.Server
+	requestedPages := map[string]int{}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/statuses") {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+
+		page := r.URL.Query().Get("page")
+		requestedPages[page]++
+
+		switch page {
+		case "":
+			if r.Header.Get("If-None-Match") == `"first"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set(etagHeader, `"first"`)
+			w.Header().Set(linkHeader, fmt.Sprintf(`<%s%s?page=2>; rel="next", <%s%s?page=3>; rel="last"`, server.URL, r.URL.Path, server.URL, r.URL.Path))
+			fmt.Fprint(w, `[{"id": 6, "environment": "production"}, {"id": 5, "environment": "production"}]`)
+		case "2":
+			w.Header().Set(linkHeader, fmt.Sprintf(`<%s%s?page=3>; rel="next", <%s%s?page=3>; rel="last"`, server.URL, r.URL.Path, server.URL, r.URL.Path))
+			fmt.Fprint(w, `[{"id": 4, "environment": "production"}, {"id": 3, "environment": "production"}]`)
+		case "3":
+			fmt.Fprint(w, `[{"id": 2, "environment": "production"}, {"id": 1, "environment": "production"}]`)
+		}
+	}))
+	defer server.Close()
+
+	e := GithubEventer{
+		client: testClient(server),
+		logger: microloggertest.New(),
+
+		baseURL:      server.URL,
+		environment:  "production",
+		organisation: "giantswarm",
+	}
+
+	deployments, cursor, err := e.fetchNewDeploymentEvents("api", projectCursor{LastDeploymentID: 3})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	var returnedIDs []int
+	for _, deployment := range deployments {
+		returnedIDs = append(returnedIDs, deployment.ID)
+	}
+	if !reflect.DeepEqual([]int{6, 5, 4}, returnedIDs) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", []int{6, 5, 4}, returnedIDs)
+	}
+	if requestedPages["3"] != 0 {
+		t.Fatalf("expected pages after the last seen deployment not to be requested")
+	}
+
+	expectedCursor := projectCursor{ETag: `"first"`, LastDeploymentID: 6}
+	if !reflect.DeepEqual(expectedCursor, cursor) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedCursor, cursor)
+	}
+
+	// Polling again without changes must only request the first page.
+	deployments, cursor, err = e.fetchNewDeploymentEvents("api", cursor)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if len(deployments) != 0 || requestedPages[""] != 2 || requestedPages["2"] != 1 {
+		t.Fatalf("expected no deployments from unchanged first page, got %#v, pages %#v", deployments, requestedPages)
+	}
+	if !reflect.DeepEqual(expectedCursor, cursor) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedCursor, cursor)
+	}
+}
+
+// TestFetchNewDeploymentEventsWithoutCursor tests that only the first page of
+// deployments is fetched when no deployment was seen before.
+func TestFetchNewDeploymentEventsWithoutCursor(t *testing.T) {
+	var server *httptest.Server
+	requestedPages := map[string]int{}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/statuses") {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+
+		page := r.URL.Query().Get("page")
+		requestedPages[page]++
+
+		switch page {
+		case "":
+			w.Header().Set(linkHeader, fmt.Sprintf(`<%s%s?page=2>; rel="next"`, server.URL, r.URL.Path))
+			fmt.Fprint(w, `[{"id": 4, "environment": "production"}, {"id": 3, "environment": "production"}]`)
+		case "2":
+			fmt.Fprint(w, `[{"id": 2, "environment": "production"}, {"id": 1, "environment": "production"}]`)
+		}
+	}))
+	defer server.Close()
+
+	e := GithubEventer{
+		client: testClient(server),
+		logger: microloggertest.New(),
+
+		baseURL:      server.URL,
+		environment:  "production",
+		organisation: "giantswarm",
+	}
+
+	deployments, cursor, err := e.fetchNewDeploymentEvents("api", projectCursor{})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if len(deployments) != 2 || requestedPages["2"] != 0 {
+		t.Fatalf("expected only the first page, got %#v, pages %#v", deployments, requestedPages)
+	}
+	if cursor.LastDeploymentID != 4 {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", 4, cursor.LastDeploymentID)
+	}
+}
+
+// TestTruncateDescription tests the truncateDescription function.
+func TestTruncateDescription(t *testing.T) {
+	tests := []struct {
+		description         string
+		expectedDescription string
+	}{
+		{
+			description:         "Installed 12345",
+			expectedDescription: "Installed 12345",
+		},
+		{
+			description:         strings.Repeat("a", statusDescriptionMaxLength),
+			expectedDescription: strings.Repeat("a", statusDescriptionMaxLength),
+		},
+		{
+			description:         strings.Repeat("a", statusDescriptionMaxLength+1),
+			expectedDescription: strings.Repeat("a", statusDescriptionMaxLength-1) + "…",
+		},
+	}
+
+	for index, test := range tests {
+		returnedDescription := truncateDescription(test.description)
+
+		if returnedDescription != test.expectedDescription {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedDescription, returnedDescription,
+			)
+		}
+	}
+}
+
+// TestParsePayload tests the parsePayload function.
+func TestParsePayload(t *testing.T) {
+	tests := []struct {
+		data            string
+		errorMatcher    func(error) bool
+		expectedPayload spec.DeploymentPayload
+	}{
+		// Test that a missing payload decodes to an empty payload.
+		{
+			data:            `null`,
+			expectedPayload: spec.DeploymentPayload{},
+		},
+
+		// Test that an empty string payload decodes to an empty payload.
+		{
+			data:            `""`,
+			expectedPayload: spec.DeploymentPayload{},
+		},
+
+		// Test that an object payload is decoded.
+		{
+			data:            `{"namespace": "giantswarm", "values": {"replicas": "2"}}`,
+			expectedPayload: spec.DeploymentPayload{Namespace: "giantswarm", Values: map[string]string{"replicas": "2"}},
+		},
+
+		// Test that a string encoded payload is decoded.
+		{
+			data:            `"{\"dryRun\": true}"`,
+			expectedPayload: spec.DeploymentPayload{DryRun: true},
+		},
+
+		// Test that a payload that is not an object is rejected.
+		{
+			data:         `"not json"`,
+			errorMatcher: IsInvalidPayload,
+		},
+	}
+
+	for index, test := range tests {
+		returnedPayload, err := parsePayload(json.RawMessage(test.data))
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected matching error, returned: %#v\n", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+		}
+
+		if !reflect.DeepEqual(test.expectedPayload, returnedPayload) {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedPayload, returnedPayload,
+			)
+		}
+	}
+}
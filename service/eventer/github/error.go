@@ -4,10 +4,6 @@ import (
 	"github.com/giantswarm/microerror"
 )
 
-var executionFailedError = &microerror.Error{
-	Kind: "executionFailedError",
-}
-
 var invalidConfigError = &microerror.Error{
 	Kind: "invalidConfigError",
 }
@@ -25,3 +21,30 @@ var unexpectedStatusCode = &microerror.Error{
 func IsUnexpectedStatusCode(err error) bool {
 	return microerror.Cause(err) == unexpectedStatusCode
 }
+
+var invalidSignatureError = &microerror.Error{
+	Kind: "invalidSignatureError",
+}
+
+// IsInvalidSignature asserts invalidSignatureError.
+func IsInvalidSignature(err error) bool {
+	return microerror.Cause(err) == invalidSignatureError
+}
+
+var queueFullError = &microerror.Error{
+	Kind: "queueFullError",
+}
+
+// IsQueueFull asserts queueFullError.
+func IsQueueFull(err error) bool {
+	return microerror.Cause(err) == queueFullError
+}
+
+var invalidPayloadError = &microerror.Error{
+	Kind: "invalidPayloadError",
+}
+
+// IsInvalidPayload asserts invalidPayloadError.
+func IsInvalidPayload(err error) bool {
+	return microerror.Cause(err) == invalidPayloadError
+}
@@ -1,14 +1,18 @@
 package github
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 
-	ratelimit "github.com/giantswarm/draughtsman/service/eventer/github/internal/ratelimit"
+	"github.com/giantswarm/draughtsman/service/eventer/poller"
 	"github.com/giantswarm/draughtsman/service/eventer/spec"
-	httpspec "github.com/giantswarm/draughtsman/service/http"
+	"github.com/giantswarm/draughtsman/service/eventer/state"
 )
 
 // GithubEventerType is an Eventer that uses Github Deployment Events as a backend.
@@ -17,15 +21,28 @@ var GithubEventerType spec.EventerType = "GithubEventer"
 // Config represents the configuration used to create a GitHub Eventer.
 type Config struct {
 	// Dependencies.
-	HTTPClient httpspec.Client
-	Logger     micrologger.Logger
 
+	// Client makes the requests to the GitHub API. It may be shared with a
+	// TopicLister.
+	Client *Client
+	Logger micrologger.Logger
+	// ProjectLister lists the projects to poll, e.g. a TopicLister. It is
+	// optional, and ProjectList is used when empty.
+	ProjectLister spec.ProjectLister
+	// StateStore persists the cursors of the projects across restarts. It is
+	// optional, and cursors are only kept in memory when empty.
+	StateStore state.Store
+
+	// Settings.
 	Environment  string
-	OAuthToken   string
-	Organisation string
 	PollInterval time.Duration
-	ProjectList  []string
-	Provider     string
+	// PollJitter is the maximum factor of the poll interval added to every
+	// wait, so that replicas do not poll in lockstep.
+	PollJitter float64
+	// PollWorkers is the maximum number of projects polled concurrently.
+	PollWorkers int
+	ProjectList []string
+	Provider    string
 }
 
 // DefaultConfig provides a default configuration to create a new GitHub
@@ -33,15 +50,20 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		// Dependencies.
-		HTTPClient: nil,
-		Logger:     nil,
+		Client:        nil,
+		Logger:        nil,
+		ProjectLister: nil,
+		StateStore:    nil,
+
+		// Settings.
+		PollWorkers: 1,
 	}
 }
 
 // New creates a new configured GitHub Eventer.
 func New(config Config) (*GithubEventer, error) {
-	if config.HTTPClient == nil {
-		return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+	if config.Client == nil {
+		return nil, microerror.Maskf(invalidConfigError, "client must not be empty")
 	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
@@ -50,32 +72,50 @@ func New(config Config) (*GithubEventer, error) {
 	if config.Environment == "" {
 		return nil, microerror.Maskf(invalidConfigError, "environment must not be empty")
 	}
-	if config.OAuthToken == "" {
-		return nil, microerror.Maskf(invalidConfigError, "oauth token must not be empty")
-	}
-	if config.Organisation == "" {
-		return nil, microerror.Maskf(invalidConfigError, "organisation must not be empty")
-	}
 	if config.PollInterval.Seconds() == 0 {
 		return nil, microerror.Maskf(invalidConfigError, "interval must be greater than zero")
 	}
-	if len(config.ProjectList) == 0 {
+	if config.ProjectLister == nil && len(config.ProjectList) == 0 {
 		return nil, microerror.Maskf(invalidConfigError, "project list must not be empty")
 	}
 	if config.Provider == "" {
 		return nil, microerror.Maskf(invalidConfigError, "provider must not be empty")
 	}
 
+	var projectPoller *poller.Poller
+	{
+		c := poller.DefaultConfig()
+
+		c.Eventer = string(GithubEventerType)
+		c.Interval = config.PollInterval
+		c.Jitter = config.PollJitter
+		c.Workers = config.PollWorkers
+
+		var err error
+		projectPoller, err = poller.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	eventer := &GithubEventer{
 		// Dependencies.
-		client:      config.HTTPClient,
-		logger:      config.Logger,
-		rateLimiter: ratelimit.New(),
+		client:        config.Client,
+		logger:        config.Logger,
+		poller:        projectPoller,
+		projectLister: config.ProjectLister,
+		stateStore:    config.StateStore,
+
+		// Internals.
+		cursors:           map[string]projectCursor{},
+		cursorsMutex:      &sync.Mutex{},
+		newestDeployments: map[string]int{},
+		newestMutex:       &sync.Mutex{},
 
 		// Settings.
+		baseURL:      config.Client.baseURL,
 		environment:  config.Environment,
-		oauthToken:   config.OAuthToken,
-		organisation: config.Organisation,
+		organisation: config.Client.organisation,
 		pollInterval: config.PollInterval,
 		projectList:  config.ProjectList,
 		provider:     config.Provider,
@@ -88,39 +128,79 @@ func New(config Config) (*GithubEventer, error) {
 // that uses GitHub Deployment Events as a backend.
 type GithubEventer struct {
 	// Dependencies.
-	client      httpspec.Client
-	logger      micrologger.Logger
-	rateLimiter *ratelimit.RateLimiter
+	client        *Client
+	logger        micrologger.Logger
+	poller        *poller.Poller
+	projectLister spec.ProjectLister
+	stateStore    state.Store
+
+	// Internals.
+
+	// cursors holds the cursor of every project polled. They are shared by
+	// polling, which moves them, and SetStatus, which forgets about finished
+	// deployments.
+	cursors      map[string]projectCursor
+	cursorsMutex *sync.Mutex
+	// newestDeployments holds the ID of the newest deployment emitted for
+	// every project, so that older deployments are never emitted after it.
+	newestDeployments map[string]int
+	newestMutex       *sync.Mutex
 
 	// Settings.
+	baseURL      string
 	environment  string
-	oauthToken   string
 	organisation string
 	pollInterval time.Duration
 	projectList  []string
 	provider     string
 }
 
-func (e *GithubEventer) NewDeploymentEvents() (<-chan spec.DeploymentEvent, error) {
+func (e *GithubEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
 	e.logger.Log("debug", "starting polling for github deployment events", "interval", e.pollInterval)
 
 	deploymentEventChannel := make(chan spec.DeploymentEvent)
-	ticker := time.NewTicker(e.pollInterval)
 
 	go func() {
-		etagMap := make(map[string]string)
+		defer close(deploymentEventChannel)
+
+		e.loadCursors()
+
+		for {
+			projectList := e.projects()
 
-		for c := ticker.C; ; <-c {
-			e.logger.Log("debug", "Fetching deployment events", "projectlist", e.projectList)
-			for _, project := range e.projectList {
-				deployments, err := e.fetchNewDeploymentEvents(project, etagMap)
+			e.logger.Log("debug", "Fetching deployment events", "projectlist", projectList)
+			e.poller.Each(ctx, projectList, func(project string) {
+				deployments, cursor, err := e.fetchNewDeploymentEvents(project, e.cursor(project))
 				if err != nil {
 					e.logger.Log("error", "could not fetch deployment events", "message", err.Error())
 				}
 
-				for _, deployment := range deployments {
-					deploymentEventChannel <- deployment.DeploymentEvent(project)
+				deployments = e.supersedeDeployments(project, deployments)
+				events := e.deploymentEvents(project, deployments)
+
+				// The cursor is only moved once the emitted deployments are
+				// marked as unfinished, so that it is never saved past them.
+				for _, event := range events {
+					e.markUnfinished(project, event.ID)
+				}
+				if err == nil {
+					e.moveCursor(project, cursor)
 				}
+
+				for _, event := range events {
+					select {
+					case deploymentEventChannel <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+			})
+
+			e.saveCursors()
+
+			if !e.poller.Wait(ctx) {
+				e.logger.Log("debug", "stopped polling for github deployment events")
+				return
 			}
 		}
 	}()
@@ -128,14 +208,196 @@ func (e *GithubEventer) NewDeploymentEvents() (<-chan spec.DeploymentEvent, erro
 	return deploymentEventChannel, nil
 }
 
-func (e *GithubEventer) SetPending(event spec.DeploymentEvent) error {
-	return e.postDeploymentStatus(event.Name, event.ID, pendingState)
+func (e *GithubEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	s := deploymentStatus{
+		Description:    status.Description,
+		EnvironmentURL: status.EnvironmentURL,
+		LogURL:         status.LogURL,
+		State:          deploymentStatusState(status.State),
+	}
+
+	err := e.postDeploymentStatus(event.Name, event.ID, s)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if status.State.IsFinal() {
+		e.markFinished(event.Name, event.ID)
+		e.saveCursors()
+	}
+
+	return nil
 }
 
-func (e *GithubEventer) SetSuccess(event spec.DeploymentEvent) error {
-	return e.postDeploymentStatus(event.Name, event.ID, successState)
+// projects returns the projects to poll, as listed by the project lister, if
+// there is one.
+func (e *GithubEventer) projects() []string {
+	if e.projectLister != nil {
+		return e.projectLister.ProjectList()
+	}
+
+	return e.projectList
 }
 
-func (e *GithubEventer) SetFailed(event spec.DeploymentEvent) error {
-	return e.postDeploymentStatus(event.Name, event.ID, failureState)
+// deploymentEvents converts the deployments of a project to
+// DeploymentEvents. Deployments that can not be converted, e.g. because of
+// an invalid payload, are marked as failed, instead of blocking the project.
+func (e *GithubEventer) deploymentEvents(project string, deployments []deployment) []spec.DeploymentEvent {
+	events := []spec.DeploymentEvent{}
+
+	for _, deployment := range deployments {
+		event, err := deployment.DeploymentEvent(project)
+		if err != nil {
+			e.logger.Log("error", "could not convert deployment", "project", project, "id", deployment.ID, "message", err.Error())
+
+			status := deploymentStatus{
+				Description: fmt.Sprintf("Invalid deployment: %s", err.Error()),
+				State:       failureState,
+			}
+
+			err := e.postDeploymentStatus(project, deployment.ID, status)
+			if err != nil {
+				e.logger.Log("error", "could not mark deployment as failed", "project", project, "id", deployment.ID, "message", err.Error())
+			}
+
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// loadCursors loads the cursors persisted in the state store. If there is
+// no state store, or the state can not be loaded, all deployments are
+// fetched again. Cursors of projects with unfinished deployments, e.g. ones
+// that were queued or in progress when draughtsman stopped, are moved back
+// to before the oldest of them, so that they are listed, and emitted, again.
+func (e *GithubEventer) loadCursors() {
+	if e.stateStore == nil {
+		return
+	}
+
+	cursors := make(map[string]projectCursor)
+
+	ok, err := e.stateStore.Load(&cursors)
+	if err != nil {
+		e.logger.Log("error", "could not load eventer state", "message", err.Error())
+		return
+	}
+	if ok {
+		e.logger.Log("debug", "loaded eventer state", "projects", len(cursors))
+	}
+
+	e.newestMutex.Lock()
+	for project, cursor := range cursors {
+		if len(cursor.Unfinished) > 0 {
+			e.logger.Log("debug", "listing unfinished deployments again", "project", project, "ids", fmt.Sprint(cursor.Unfinished))
+
+			// The unfinished deployments are marked again once they are
+			// emitted. Deployments that were finished in the meantime, e.g.
+			// by another instance, are not.
+			cursor.ETag = ""
+			cursor.LastDeploymentID = cursor.Unfinished[0] - 1
+			cursor.Unfinished = nil
+			cursors[project] = cursor
+		}
+
+		// Unfinished deployments that are older than the newest deployment
+		// emitted before the restart are superseded by it.
+		newestID := cursor.LastDeploymentID
+		if cursor.NewestDeploymentID > newestID {
+			newestID = cursor.NewestDeploymentID
+		}
+		if newestID > e.newestDeployments[project] {
+			e.newestDeployments[project] = newestID
+		}
+	}
+	e.newestMutex.Unlock()
+
+	e.cursorsMutex.Lock()
+	e.cursors = cursors
+	e.cursorsMutex.Unlock()
+}
+
+// saveCursors persists the cursors in the state store, if there is one.
+func (e *GithubEventer) saveCursors() {
+	if e.stateStore == nil {
+		return
+	}
+
+	e.cursorsMutex.Lock()
+	defer e.cursorsMutex.Unlock()
+
+	err := e.stateStore.Save(e.cursors)
+	if err != nil {
+		e.logger.Log("error", "could not save eventer state", "message", err.Error())
+	}
+}
+
+// cursor returns the cursor of the project.
+func (e *GithubEventer) cursor(project string) projectCursor {
+	e.cursorsMutex.Lock()
+	defer e.cursorsMutex.Unlock()
+
+	return e.cursors[project]
+}
+
+// moveCursor moves the cursor of the project to the etag and deployment of
+// the given cursor. The unfinished deployments of the project are kept, as
+// they may have changed since the given cursor was read.
+func (e *GithubEventer) moveCursor(project string, moved projectCursor) {
+	e.cursorsMutex.Lock()
+	defer e.cursorsMutex.Unlock()
+
+	cursor := e.cursors[project]
+	cursor.ETag = moved.ETag
+	cursor.LastDeploymentID = moved.LastDeploymentID
+	e.cursors[project] = cursor
+}
+
+// markUnfinished records the deployment as emitted, and not finished yet, in
+// the cursor of the project. It also becomes the newest deployment emitted,
+// if it is newer.
+func (e *GithubEventer) markUnfinished(project string, id int) {
+	e.cursorsMutex.Lock()
+	defer e.cursorsMutex.Unlock()
+
+	cursor := e.cursors[project]
+	if id > cursor.NewestDeploymentID {
+		cursor.NewestDeploymentID = id
+	}
+	for _, unfinished := range cursor.Unfinished {
+		if unfinished == id {
+			e.cursors[project] = cursor
+			return
+		}
+	}
+	cursor.Unfinished = append(cursor.Unfinished, id)
+	sort.Ints(cursor.Unfinished)
+	e.cursors[project] = cursor
+}
+
+// markFinished forgets about the deployment once it is finished.
+func (e *GithubEventer) markFinished(project string, id int) {
+	e.cursorsMutex.Lock()
+	defer e.cursorsMutex.Unlock()
+
+	cursor, ok := e.cursors[project]
+	if !ok {
+		return
+	}
+
+	unfinished := []int{}
+	for _, u := range cursor.Unfinished {
+		if u != id {
+			unfinished = append(unfinished, u)
+		}
+	}
+	if len(unfinished) == 0 {
+		unfinished = nil
+	}
+	cursor.Unfinished = unfinished
+	e.cursors[project] = cursor
 }
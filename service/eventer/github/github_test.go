@@ -0,0 +1,84 @@
+package github
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+// testStateStore is a state.Store that keeps the state in memory.
+type testStateStore struct {
+	data []byte
+}
+
+func (s *testStateStore) Load(v interface{}) (bool, error) {
+	if s.data == nil {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(s.data, v)
+}
+
+func (s *testStateStore) Save(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.data = data
+
+	return nil
+}
+
+func testEventer(stateStore *testStateStore) *GithubEventer {
+	return &GithubEventer{
+		logger:     microloggertest.New(),
+		stateStore: stateStore,
+
+		cursors:           map[string]projectCursor{},
+		cursorsMutex:      &sync.Mutex{},
+		newestDeployments: map[string]int{},
+		newestMutex:       &sync.Mutex{},
+	}
+}
+
+// TestCursorsUnfinished tests that cursors are moved back to before the
+// oldest unfinished deployment when they are loaded again, e.g. after a
+// restart.
+func TestCursorsUnfinished(t *testing.T) {
+	stateStore := &testStateStore{}
+
+	e := testEventer(stateStore)
+	e.moveCursor("api", projectCursor{ETag: `"api"`, LastDeploymentID: 8})
+	e.markUnfinished("api", 5)
+	e.markUnfinished("api", 7)
+	e.markFinished("api", 7)
+	e.moveCursor("web", projectCursor{ETag: `"web"`, LastDeploymentID: 3})
+	e.markUnfinished("web", 3)
+	e.markFinished("web", 3)
+	e.saveCursors()
+
+	e = testEventer(stateStore)
+	e.loadCursors()
+
+	expectedCursors := map[string]projectCursor{
+		// The api project is listed again from its unfinished deployment,
+		// which is superseded by the newer deployment emitted before.
+		"api": {LastDeploymentID: 4, NewestDeploymentID: 7},
+		// The web project has no unfinished deployments, so it is kept.
+		"web": {ETag: `"web"`, LastDeploymentID: 3, NewestDeploymentID: 3},
+	}
+	if !reflect.DeepEqual(expectedCursors, e.cursors) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedCursors, e.cursors)
+	}
+
+	expectedNewestDeployments := map[string]int{
+		"api": 7,
+		"web": 3,
+	}
+	if !reflect.DeepEqual(expectedNewestDeployments, e.newestDeployments) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedNewestDeployments, e.newestDeployments)
+	}
+}
@@ -0,0 +1,350 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+const (
+	// graphqlPath is the path of the GitHub GraphQL API, relative to the
+	// GitHub API URL.
+	// See: https://developer.github.com/v4/guides/forming-calls/#the-graphql-endpoint
+	graphqlPath = "/graphql"
+
+	// enterpriseGraphQLPath is the path of the GitHub GraphQL API on GitHub
+	// Enterprise Server, which is not served below the REST API path.
+	// See: https://developer.github.com/enterprise/v4/guides/forming-calls/#the-graphql-endpoint
+	enterpriseGraphQLPath = "/api/graphql"
+
+	// graphqlDeploymentsPerProject is the number of newest deployments that
+	// are queried for every project per page.
+	graphqlDeploymentsPerProject = 20
+
+	// graphqlMaxPages is the maximum number of pages of deployments that are
+	// queried for a project in a single poll, when following pages up to the
+	// previously seen deployment. The first poll of a project, when there is
+	// no previously seen deployment to stop at, only queries the first page.
+	graphqlMaxPages = 10
+
+	// graphqlDeploymentsFragment selects the fields of the deployments of a
+	// repository. It is formatted with the arguments of the deployments
+	// connection.
+	graphqlDeploymentsFragment = `deployments(%s, environments: [$environment], orderBy: {field: CREATED_AT, direction: DESC}) {
+      pageInfo {
+        endCursor
+        hasNextPage
+      }
+      nodes {
+        databaseId
+        commitOid
+        creator {
+          login
+        }
+        description
+        environment
+        latestStatus {
+          state
+        }
+        payload
+        ref {
+          name
+        }
+        task
+      }
+    }`
+)
+
+// GithubGraphQLEventerType is an Eventer that uses the GitHub GraphQL API to
+// fetch deployments of all projects with a single query per poll.
+var GithubGraphQLEventerType spec.EventerType = "GithubGraphQLEventer"
+
+// NewGraphQL creates a new configured GitHub GraphQL Eventer.
+func NewGraphQL(config Config) (*GithubGraphQLEventer, error) {
+	githubEventer, err := New(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	eventer := &GithubGraphQLEventer{
+		GithubEventer: githubEventer,
+
+		// Settings.
+		graphqlURL: graphqlURL(githubEventer.baseURL),
+	}
+
+	return eventer, nil
+}
+
+// GithubGraphQLEventer is an implementation of the Eventer interface, that
+// queries GitHub Deployments and their latest statuses for all projects with
+// a single GraphQL query, instead of a REST call per project and deployment.
+// Deployment statuses are still posted via the REST API.
+type GithubGraphQLEventer struct {
+	*GithubEventer
+
+	// Settings.
+	graphqlURL string
+}
+
+func (e *GithubGraphQLEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	e.logger.Log("debug", "starting polling for github deployment events via graphql", "interval", e.pollInterval)
+
+	deploymentEventChannel := make(chan spec.DeploymentEvent)
+
+	go func() {
+		defer close(deploymentEventChannel)
+
+		e.loadCursors()
+
+		for {
+			projectList := e.projects()
+
+			e.logger.Log("debug", "Fetching deployment events", "projectlist", projectList)
+
+			projectDeployments, err := e.fetchProjectDeployments(projectList)
+			if err != nil {
+				e.logger.Log("error", "could not fetch deployment events", "message", err.Error())
+			} else {
+				for _, project := range projectList {
+					deployments := e.filterNewDeployments(projectDeployments[project], project)
+					deployments = e.supersedeDeployments(project, deployments)
+					events := e.deploymentEvents(project, deployments)
+
+					for _, event := range events {
+						e.markUnfinished(project, event.ID)
+					}
+					e.moveCursor(project, projectCursor{LastDeploymentID: newestDeploymentID(projectDeployments[project], e.cursor(project))})
+
+					for _, event := range events {
+						select {
+						case deploymentEventChannel <- event:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}
+
+				e.saveCursors()
+			}
+
+			if !e.poller.Wait(ctx) {
+				e.logger.Log("debug", "stopped polling for github deployment events via graphql")
+				return
+			}
+		}
+	}()
+
+	return deploymentEventChannel, nil
+}
+
+// filterNewDeployments filters out deployments that were already seen in a
+// previous poll, or that are finished.
+func (e *GithubGraphQLEventer) filterNewDeployments(deployments []deployment, project string) []deployment {
+	cursor := e.cursor(project)
+
+	matches := []deployment{}
+	for _, deployment := range deployments {
+		if deployment.ID <= cursor.LastDeploymentID {
+			continue
+		}
+
+		matches = append(matches, deployment)
+	}
+
+	matches = e.filterDeploymentsByEnvironment(matches)
+	matches = e.filterDeploymentsByStatus(matches)
+
+	if len(matches) > 0 {
+		e.logger.Log("debug", "found new deployment events", "project", project)
+	}
+
+	return matches
+}
+
+// fetchProjectDeployments queries the newest deployments, including their
+// latest status, of the given projects, and returns them keyed by project.
+// All projects are queried with a single query. Projects with more new
+// deployments than fit on a page are queried again for the next page, until
+// the deployment their cursor points at is reached.
+func (e *GithubGraphQLEventer) fetchProjectDeployments(projectList []string) (map[string][]deployment, error) {
+	projectDeployments := map[string][]deployment{}
+
+	after := map[string]string{}
+	pending := projectList
+
+	for pageNumber := 0; len(pending) > 0 && pageNumber < graphqlMaxPages; pageNumber++ {
+		repositories, err := e.queryDeployments(pending, after)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var next []string
+		for index, project := range pending {
+			repository := repositories[graphqlAlias(index)]
+			if repository == nil {
+				continue
+			}
+
+			nodes := repository.Deployments.Nodes
+			for _, node := range nodes {
+				projectDeployments[project] = append(projectDeployments[project], node.deployment())
+			}
+
+			lastDeploymentID := e.cursor(project).LastDeploymentID
+			if lastDeploymentID == 0 || !repository.Deployments.PageInfo.HasNextPage || len(nodes) == 0 {
+				continue
+			}
+			if nodes[len(nodes)-1].DatabaseID <= lastDeploymentID {
+				continue
+			}
+
+			after[project] = repository.Deployments.PageInfo.EndCursor
+			next = append(next, project)
+		}
+
+		pending = next
+	}
+
+	return projectDeployments, nil
+}
+
+// newestDeploymentID returns the ID of the newest of the given deployments,
+// or the deployment the cursor points at, if it is newer.
+func newestDeploymentID(deployments []deployment, cursor projectCursor) int {
+	newestID := cursor.LastDeploymentID
+	for _, deployment := range deployments {
+		if deployment.ID > newestID {
+			newestID = deployment.ID
+		}
+	}
+
+	return newestID
+}
+
+// queryDeployments queries a page of deployments of the given projects, and
+// returns the repositories keyed by alias. Projects with an entry in after are
+// queried for the page after the given cursor.
+func (e *GithubGraphQLEventer) queryDeployments(projectList []string, after map[string]string) (map[string]*graphqlRepository, error) {
+	// A query without repositories is invalid, e.g. before any project was
+	// discovered.
+	if len(projectList) == 0 {
+		return map[string]*graphqlRepository{}, nil
+	}
+
+	query, variables := e.deploymentsQuery(projectList, after)
+
+	payload, err := json.Marshal(graphqlRequest{
+		Query:     query,
+		Variables: variables,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	req, err := http.NewRequest("POST", e.graphqlURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	startTime := time.Now()
+
+	resp, err := e.client.request(req)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	updateGraphQLMetrics(e.organisation, resp.StatusCode, startTime)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, microerror.Maskf(unexpectedStatusCode, fmt.Sprintf("received non-200 status code: %v, body: %q", resp.StatusCode, string(body)))
+	}
+
+	var response graphqlDeploymentsResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// Errors for single repositories, e.g. when one does not exist, do not
+	// prevent deployments of other projects from being processed.
+	for _, graphqlError := range response.Errors {
+		e.logger.Log("error", "github graphql query returned error", "message", graphqlError.Message)
+	}
+	if response.Data == nil {
+		return nil, microerror.Maskf(unexpectedStatusCode, "received no data, body: %q", string(body))
+	}
+
+	return response.Data, nil
+}
+
+// deploymentsQuery builds the GraphQL query for the deployments of the given
+// projects, with one aliased repository field per project. Projects with an
+// entry in after are queried for the page after the given cursor.
+func (e *GithubGraphQLEventer) deploymentsQuery(projectList []string, after map[string]string) (string, map[string]string) {
+	variables := map[string]string{
+		"environment": e.environment,
+		"owner":       e.organisation,
+	}
+
+	var declarations, fields []string
+	for index, project := range projectList {
+		alias := graphqlAlias(index)
+
+		variables[alias] = project
+		declarations = append(declarations, fmt.Sprintf("$%s: String!", alias))
+
+		arguments := fmt.Sprintf("first: %d", graphqlDeploymentsPerProject)
+		if cursor, ok := after[project]; ok {
+			variables[alias+"After"] = cursor
+			declarations = append(declarations, fmt.Sprintf("$%sAfter: String!", alias))
+			arguments += fmt.Sprintf(", after: $%sAfter", alias)
+		}
+
+		fields = append(fields, fmt.Sprintf(
+			"  %s: repository(owner: $owner, name: $%s) {\n    "+graphqlDeploymentsFragment+"\n  }",
+			alias,
+			alias,
+			arguments,
+		))
+	}
+
+	query := fmt.Sprintf(
+		"query($owner: String!, $environment: String!, %s) {\n%s\n}",
+		strings.Join(declarations, ", "),
+		strings.Join(fields, "\n"),
+	)
+
+	return query, variables
+}
+
+// graphqlAlias returns the alias used for the project with the given index.
+// Project names can not be used as aliases, as they may contain characters
+// that are not allowed in GraphQL names.
+func graphqlAlias(index int) string {
+	return fmt.Sprintf("project%d", index)
+}
+
+// graphqlURL returns the URL of the GraphQL API for the given, normalised,
+// GitHub API URL.
+func graphqlURL(baseURL string) string {
+	if strings.HasSuffix(baseURL, enterpriseAPIPath) {
+		return strings.TrimSuffix(baseURL, enterpriseAPIPath) + enterpriseGraphQLPath
+	}
+
+	return baseURL + graphqlPath
+}
@@ -0,0 +1,205 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+// TestGraphQLURL tests the graphqlURL function.
+func TestGraphQLURL(t *testing.T) {
+	tests := []struct {
+		baseURL            string
+		expectedGraphQLURL string
+	}{
+		{
+			baseURL:            "https://api.github.com",
+			expectedGraphQLURL: "https://api.github.com/graphql",
+		},
+		{
+			baseURL:            "https://github.example.com/api/v3",
+			expectedGraphQLURL: "https://github.example.com/api/graphql",
+		},
+	}
+
+	for index, test := range tests {
+		returnedGraphQLURL := graphqlURL(test.baseURL)
+
+		if returnedGraphQLURL != test.expectedGraphQLURL {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedGraphQLURL, returnedGraphQLURL,
+			)
+		}
+	}
+}
+
+// TestGraphQLDeployments tests that deployments of all projects are fetched
+// with a single query, and that only new pending deployments are kept.
+func TestGraphQLDeployments(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		var request graphqlRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path != "/graphql" || request.Variables["project0"] != "api" || request.Variables["project1"] != "missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		fmt.Fprint(w, `{
+			"data": {
+				"project0": {"deployments": {"nodes": [
+					{"databaseId": 3, "commitOid": "c", "environment": "production", "latestStatus": null},
+					{"databaseId": 2, "commitOid": "b", "environment": "production", "latestStatus": {"state": "SUCCESS"}},
+					{"databaseId": 1, "commitOid": "a", "environment": "production", "latestStatus": {"state": "PENDING"}}
+				]}},
+				"project1": null
+			},
+			"errors": [{"message": "Could not resolve to a Repository with the name 'missing'."}]
+		}`)
+	}))
+	defer server.Close()
+
+	e := GithubGraphQLEventer{
+		GithubEventer: &GithubEventer{
+			client: testClient(server),
+			logger: microloggertest.New(),
+
+			cursors: map[string]projectCursor{
+				"api": {LastDeploymentID: 1},
+			},
+			cursorsMutex: &sync.Mutex{},
+
+			environment:  "production",
+			organisation: "giantswarm",
+			projectList:  []string{"api", "missing"},
+		},
+
+		graphqlURL: server.URL + graphqlPath,
+	}
+
+	projectDeployments, err := e.fetchProjectDeployments(e.projectList)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected a single request, got %d", requests)
+	}
+
+	returnedDeployments := e.filterNewDeployments(projectDeployments["api"], "api")
+
+	expectedDeployments := []deployment{
+		{ID: 3, Sha: "c", Environment: "production", Statuses: []deploymentStatus{}},
+	}
+	if !reflect.DeepEqual(expectedDeployments, returnedDeployments) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedDeployments, returnedDeployments)
+	}
+	if newestID := newestDeploymentID(projectDeployments["api"], e.cursor("api")); newestID != 3 {
+		t.Fatalf("expected cursor to move to 3, returned: %d", newestID)
+	}
+
+	e.moveCursor("api", projectCursor{LastDeploymentID: 3})
+	if len(e.filterNewDeployments(projectDeployments["api"], "api")) != 0 {
+		t.Fatalf("expected deployments not to be returned twice")
+	}
+	if len(projectDeployments["missing"]) != 0 {
+		t.Fatalf("expected no deployments for missing project")
+	}
+}
+
+// TestGraphQLDeploymentsPagination tests that pages of deployments are
+// followed until the last seen deployment, only for the projects that need
+// it.
+func TestGraphQLDeploymentsPagination(t *testing.T) {
+	var requests []graphqlRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request graphqlRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, request)
+
+		switch {
+		case request.Variables["project0"] == "api" && request.Variables["project0After"] == "":
+			fmt.Fprint(w, `{"data": {
+				"project0": {"deployments": {"pageInfo": {"endCursor": "first", "hasNextPage": true}, "nodes": [
+					{"databaseId": 6, "environment": "production"},
+					{"databaseId": 5, "environment": "production"}
+				]}},
+				"project1": {"deployments": {"pageInfo": {"endCursor": "other", "hasNextPage": true}, "nodes": [
+					{"databaseId": 9, "environment": "production"},
+					{"databaseId": 8, "environment": "production"}
+				]}}
+			}}`)
+		case request.Variables["project0"] == "api" && request.Variables["project0After"] == "first":
+			fmt.Fprint(w, `{"data": {
+				"project0": {"deployments": {"pageInfo": {"endCursor": "second", "hasNextPage": true}, "nodes": [
+					{"databaseId": 4, "environment": "production"},
+					{"databaseId": 3, "environment": "production"}
+				]}}
+			}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	e := GithubGraphQLEventer{
+		GithubEventer: &GithubEventer{
+			client: testClient(server),
+			logger: microloggertest.New(),
+
+			cursors: map[string]projectCursor{
+				"api": {LastDeploymentID: 3},
+			},
+			cursorsMutex: &sync.Mutex{},
+
+			environment:  "production",
+			organisation: "giantswarm",
+		},
+
+		graphqlURL: server.URL + graphqlPath,
+	}
+
+	// The api project is followed up to its cursor. The web project was never
+	// polled before, so only its first page is queried.
+	projectDeployments, err := e.fetchProjectDeployments([]string{"api", "web"})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected two requests, got %#v", requests)
+	}
+	if _, ok := requests[1].Variables["project1"]; ok {
+		t.Fatalf("expected second request to only query api, got %#v", requests[1])
+	}
+
+	returnedIDs := map[string][]int{}
+	for project, deployments := range projectDeployments {
+		for _, deployment := range deployments {
+			returnedIDs[project] = append(returnedIDs[project], deployment.ID)
+		}
+	}
+
+	expectedIDs := map[string][]int{
+		"api": {6, 5, 4, 3},
+		"web": {9, 8},
+	}
+	if !reflect.DeepEqual(expectedIDs, returnedIDs) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedIDs, returnedIDs)
+	}
+}
@@ -0,0 +1,289 @@
+// Package appauth implements authentication as a GitHub App installation.
+// See: https://developer.github.com/apps/building-github-apps/authenticating-with-github-apps/
+package appauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+)
+
+const (
+	// installationUrlFormat is the string format for the GitHub API call to
+	// find the installation of the App for an organisation.
+	// See: https://developer.github.com/v3/apps/#get-an-organization-installation
+	installationUrlFormat = "%s/orgs/%s/installation"
+
+	// accessTokenUrlFormat is the string format for the GitHub API call to
+	// create an installation access token.
+	// See: https://developer.github.com/v3/apps/#create-a-new-installation-token
+	accessTokenUrlFormat = "%s/app/installations/%d/access_tokens"
+
+	// mediaType is the media type required by the GitHub Apps API.
+	mediaType = "application/vnd.github.machine-man-preview+json"
+
+	// jwtClockSkew is the duration the JWT issue time is moved into the past,
+	// to allow for clock drift between us and GitHub.
+	jwtClockSkew = 60 * time.Second
+
+	// jwtLifetime is the lifetime of the JWT. GitHub allows at most ten
+	// minutes.
+	jwtLifetime = 9 * time.Minute
+
+	// refreshBefore is the duration before expiry at which a cached
+	// installation access token gets refreshed.
+	refreshBefore = 5 * time.Minute
+)
+
+// Config represents the configuration used to create a TokenSource.
+type Config struct {
+	// Dependencies.
+	HTTPClient httpspec.Client
+
+	// Settings.
+
+	// AppID is the ID of the GitHub App.
+	AppID int64
+	// BaseURL is the URL of the GitHub API, e.g: https://api.github.com.
+	BaseURL string
+	// InstallationID is the ID of the installation of the GitHub App. If it
+	// is zero, the installation is looked up for the organisation.
+	InstallationID int64
+	// Organisation is the organisation the GitHub App is installed in.
+	Organisation string
+	// PrivateKey is the PEM encoded private key of the GitHub App.
+	PrivateKey []byte
+}
+
+// New creates a new configured TokenSource.
+func New(config Config) (*TokenSource, error) {
+	if config.HTTPClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+	}
+
+	if config.AppID == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "app id must not be empty")
+	}
+	if config.BaseURL == "" {
+		return nil, microerror.Maskf(invalidConfigError, "base url must not be empty")
+	}
+	if config.InstallationID == 0 && config.Organisation == "" {
+		return nil, microerror.Maskf(invalidConfigError, "installation id or organisation must not be empty")
+	}
+	if len(config.PrivateKey) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "private key must not be empty")
+	}
+
+	privateKey, err := parsePrivateKey(config.PrivateKey)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	t := &TokenSource{
+		// Dependencies.
+		client: config.HTTPClient,
+
+		// Internals.
+		mutex: &sync.Mutex{},
+
+		// Settings.
+		appID:          config.AppID,
+		baseURL:        config.BaseURL,
+		installationID: config.InstallationID,
+		organisation:   config.Organisation,
+		privateKey:     privateKey,
+	}
+
+	return t, nil
+}
+
+// TokenSource mints GitHub App JWTs, exchanges them for installation access
+// tokens, and caches the latter until shortly before they expire.
+type TokenSource struct {
+	// Dependencies.
+	client httpspec.Client
+
+	// Internals.
+	expiresAt time.Time
+	mutex     *sync.Mutex
+	token     string
+
+	// Settings.
+	appID          int64
+	baseURL        string
+	installationID int64
+	organisation   string
+	privateKey     *rsa.PrivateKey
+}
+
+// Token returns a valid installation access token, refreshing it if needed.
+func (t *TokenSource) Token() (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.token != "" && time.Now().Add(refreshBefore).Before(t.expiresAt) {
+		return t.token, nil
+	}
+
+	jwt, err := t.jwt(time.Now())
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	if t.installationID == 0 {
+		t.installationID, err = t.fetchInstallationID(jwt)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+	}
+
+	token, expiresAt, err := t.fetchAccessToken(jwt)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	t.token = token
+	t.expiresAt = expiresAt
+
+	return t.token, nil
+}
+
+// jwt mints a JWT signed with the App's private key, used to authenticate as
+// the App itself.
+func (t *TokenSource) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": strconv.FormatInt(t.appID, 10),
+	})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// fetchInstallationID looks up the installation of the App for the
+// configured organisation.
+func (t *TokenSource) fetchInstallationID(jwt string) (int64, error) {
+	url := fmt.Sprintf(installationUrlFormat, t.baseURL, t.organisation)
+
+	body, err := t.do("GET", url, jwt, http.StatusOK)
+	if err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(body, &installation); err != nil {
+		return 0, microerror.Mask(err)
+	}
+
+	return installation.ID, nil
+}
+
+// fetchAccessToken exchanges the JWT for an installation access token.
+func (t *TokenSource) fetchAccessToken(jwt string) (string, time.Time, error) {
+	url := fmt.Sprintf(accessTokenUrlFormat, t.baseURL, t.installationID)
+
+	body, err := t.do("POST", url, jwt, http.StatusCreated)
+	if err != nil {
+		return "", time.Time{}, microerror.Mask(err)
+	}
+
+	var accessToken struct {
+		ExpiresAt time.Time `json:"expires_at"`
+		Token     string    `json:"token"`
+	}
+	if err := json.Unmarshal(body, &accessToken); err != nil {
+		return "", time.Time{}, microerror.Mask(err)
+	}
+
+	return accessToken.Token, accessToken.ExpiresAt, nil
+}
+
+// do makes a request authenticated as the App, and returns the response body
+// if the expected status code was received.
+func (t *TokenSource) do(method, url, jwt string, expectedStatusCode int) ([]byte, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	req.Header.Set("Accept", mediaType)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if resp.StatusCode != expectedStatusCode {
+		return nil, microerror.Maskf(unexpectedStatusCodeError, "received status code: %v, body: %q", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+// parsePrivateKey parses a PEM encoded RSA private key, as generated by
+// GitHub in PKCS#1 format, or converted to PKCS#8.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, microerror.Maskf(invalidConfigError, "private key must be PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "could not parse private key: %s", err.Error())
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, microerror.Maskf(invalidConfigError, "private key must be an RSA key")
+	}
+
+	return rsaKey, nil
+}
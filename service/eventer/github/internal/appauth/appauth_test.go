@@ -0,0 +1,121 @@
+package appauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestToken tests the Token method against a fake GitHub API server.
+func TestToken(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate private key: %#v", err)
+	}
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+
+	var installationRequests, tokenRequests int
+
+	// verifyJWT checks the request is authenticated with a JWT signed by the
+	// App's private key.
+	verifyJWT := func(r *http.Request) bool {
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		if len(parts) != 3 {
+			return false
+		}
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return false
+		}
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, hash[:], signature) != nil {
+			return false
+		}
+
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return false
+		}
+
+		return strings.Contains(string(claims), `"iss":"1234"`)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !verifyJWT(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/orgs/giantswarm/installation":
+			installationRequests++
+			fmt.Fprint(w, `{"id": 42}`)
+		case r.Method == "POST" && r.URL.Path == "/app/installations/42/access_tokens":
+			tokenRequests++
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"token":      fmt.Sprintf("token-%d", tokenRequests),
+				"expires_at": time.Now().Add(1 * time.Hour),
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tokenSource, err := New(Config{
+		HTTPClient: server.Client(),
+
+		AppID:        1234,
+		BaseURL:      server.URL,
+		Organisation: "giantswarm",
+		PrivateKey:   privateKeyPEM,
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := tokenSource.Token()
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v", i, err)
+		}
+		if token != "token-1" {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", i, "token-1", token)
+		}
+	}
+
+	if installationRequests != 1 || tokenRequests != 1 {
+		t.Fatalf("expected one installation and one token request, got %d and %d", installationRequests, tokenRequests)
+	}
+
+	// Expire the cached token, so that it gets refreshed.
+	tokenSource.expiresAt = time.Now().Add(refreshBefore / 2)
+
+	token, err := tokenSource.Token()
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if token != "token-2" {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", "token-2", token)
+	}
+	if installationRequests != 1 {
+		t.Fatalf("expected installation id to be cached, got %d requests", installationRequests)
+	}
+}
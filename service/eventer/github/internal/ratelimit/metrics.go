@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// prometheusNamespace is the namespace to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusNamespace = "draughtsman"
+
+	// prometheusSubsystem is the subsystem to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusSubsystem = "github_rate_limiter"
+
+	// blockedReason labels time spent waiting for a block, e.g. after a
+	// Retry-After header or exponential backoff.
+	blockedReason = "blocked"
+	// bucketReason labels time spent waiting for a token from the bucket.
+	bucketReason = "bucket"
+)
+
+var (
+	throttledSeconds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "throttled_seconds_total",
+			Help:      "Time spent waiting before making GitHub API requests.",
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(throttledSeconds)
+}
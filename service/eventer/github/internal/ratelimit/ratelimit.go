@@ -15,13 +15,26 @@ const (
 	// defaultCapacity is the default number of tokens initially filled in
 	// token bucket and which gets refilled after defaultFillInterval.
 	defaultCapacity = 60
+
+	// backoffInitial is the duration waited after the first rate limited
+	// response that did not say how long to wait.
+	backoffInitial = 1 * time.Minute
+
+	// backoffMax is the maximum duration waited after consecutive rate
+	// limited responses.
+	backoffMax = 15 * time.Minute
 )
 
 // RateLimiter is wrapper type to encapsulate a 3rd party rate limiting
-// implementation and provide functionality to reconfigure it.
+// implementation and provide functionality to reconfigure it. On top of the
+// token bucket, it can be blocked for a given duration, e.g. when an API
+// asks to retry after some time, and backs off exponentially when it is
+// rate limited repeatedly.
 type RateLimiter struct {
-	bucket *jujuratelimit.Bucket
-	mutex  *sync.Mutex
+	blockedUntil time.Time
+	bucket       *jujuratelimit.Bucket
+	failures     uint
+	mutex        *sync.Mutex
 }
 
 // New initializes RateLimiter with default values.
@@ -36,9 +49,22 @@ func New() *RateLimiter {
 }
 
 // Wait takes a value from token bucket when available. If bucket is empty,
-// call blocks until token is available.
+// call blocks until token is available. If the RateLimiter is blocked, call
+// blocks until the block has passed first.
 func (rl *RateLimiter) Wait() {
-	rl.bucket.Wait(1)
+	rl.mutex.Lock()
+	blockedFor := time.Until(rl.blockedUntil)
+	bucket := rl.bucket
+	rl.mutex.Unlock()
+
+	if blockedFor > 0 {
+		time.Sleep(blockedFor)
+		throttledSeconds.WithLabelValues(blockedReason).Add(blockedFor.Seconds())
+	}
+
+	startTime := time.Now()
+	bucket.Wait(1)
+	throttledSeconds.WithLabelValues(bucketReason).Add(time.Since(startTime).Seconds())
 }
 
 // Update reconfigures RateLimiter's token bucket with given fill interval and
@@ -51,3 +77,51 @@ func (rl *RateLimiter) Update(fillInterval time.Duration, capacity int64) {
 
 	rl.bucket = jujuratelimit.NewBucket(fillInterval, capacity)
 }
+
+// Block makes calls to Wait block for at least the given duration.
+func (rl *RateLimiter) Block(duration time.Duration) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.block(duration)
+}
+
+// Backoff blocks the RateLimiter for an exponentially growing duration,
+// based on the number of consecutive calls to Backoff since the last call to
+// Reset. It returns the duration blocked for.
+func (rl *RateLimiter) Backoff() time.Duration {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	// The duration is doubled step by step and compared before each step,
+	// so that it never overflows however many failures there were.
+	duration := backoffInitial
+	for i := uint(0); i < rl.failures && duration < backoffMax; i++ {
+		duration *= 2
+	}
+	if duration > backoffMax {
+		duration = backoffMax
+	}
+	rl.failures++
+
+	rl.block(duration)
+
+	return duration
+}
+
+// Reset resets the exponential backoff, e.g. after a successful request.
+func (rl *RateLimiter) Reset() {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.failures = 0
+}
+
+// block extends the current block to the given duration from now. The mutex
+// must be held by the caller.
+func (rl *RateLimiter) block(duration time.Duration) {
+	blockedUntil := time.Now().Add(duration)
+	if blockedUntil.After(rl.blockedUntil) {
+		rl.blockedUntil = blockedUntil
+	}
+}
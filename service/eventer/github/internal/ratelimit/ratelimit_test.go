@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBackoff tests the Backoff method.
+func TestBackoff(t *testing.T) {
+	rl := New()
+
+	expectedDurations := []time.Duration{
+		1 * time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		8 * time.Minute,
+		15 * time.Minute,
+		15 * time.Minute,
+	}
+
+	for index, expectedDuration := range expectedDurations {
+		returnedDuration := rl.Backoff()
+
+		if returnedDuration != expectedDuration {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, expectedDuration, returnedDuration,
+			)
+		}
+	}
+
+	if time.Until(rl.blockedUntil) <= 14*time.Minute {
+		t.Fatalf("expected rate limiter to be blocked, blocked until: %v", rl.blockedUntil)
+	}
+
+	rl.Reset()
+
+	if returnedDuration := rl.Backoff(); returnedDuration != backoffInitial {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", backoffInitial, returnedDuration)
+	}
+}
+
+// TestBackoffManyFailures tests that Backoff keeps returning the maximum
+// duration after many consecutive failures, instead of overflowing.
+func TestBackoffManyFailures(t *testing.T) {
+	rl := New()
+
+	for index := 0; index < 100; index++ {
+		returnedDuration := rl.Backoff()
+
+		if returnedDuration <= 0 || returnedDuration > backoffMax {
+			t.Fatalf("%v\nreturned: %#v\n", index, returnedDuration)
+		}
+	}
+
+	if returnedDuration := rl.Backoff(); returnedDuration != backoffMax {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", backoffMax, returnedDuration)
+	}
+}
+
+// TestBlock tests that Block never shortens an existing block.
+func TestBlock(t *testing.T) {
+	rl := New()
+
+	rl.Block(10 * time.Minute)
+	rl.Block(1 * time.Minute)
+
+	if time.Until(rl.blockedUntil) <= 9*time.Minute {
+		t.Fatalf("expected block to be kept, blocked until: %v", rl.blockedUntil)
+	}
+}
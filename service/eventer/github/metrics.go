@@ -6,8 +6,6 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-
-	"github.com/giantswarm/microerror"
 )
 
 const (
@@ -33,6 +31,15 @@ var (
 		Name:      "rate_limit_remaining",
 		Help:      "Rate limit remaining for GitHub API requests.",
 	})
+	rateLimitedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "rate_limited_total",
+			Help:      "Number of GitHub API requests rejected because of rate limiting.",
+		},
+		[]string{"code"},
+	)
 
 	deploymentRequestDuration = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -53,6 +60,45 @@ var (
 		[]string{"organisation", "project", "code"},
 	)
 
+	supersededTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "superseded_deployments_total",
+			Help:      "Number of pending deployments that were superseded by a newer deployment.",
+		},
+		[]string{"organisation", "project"},
+	)
+
+	graphqlRequestDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "github_graphql_duration_milliseconds",
+			Help:      "Time taken to query GitHub deployments via GraphQL.",
+		},
+		[]string{"organisation", "code"},
+	)
+	graphqlResponseCodeTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "github_graphql_response_code",
+			Help:      "Response codes of GitHub GraphQL API requests for deployments.",
+		},
+		[]string{"organisation", "code"},
+	)
+
+	discoveredProjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "discovered_projects",
+			Help:      "Number of projects discovered via repository topics.",
+		},
+		[]string{"organisation", "topic"},
+	)
+
 	deploymentStatusRequestDuration = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: prometheusNamespace,
@@ -76,30 +122,49 @@ var (
 func init() {
 	prometheus.MustRegister(rateLimitLimit)
 	prometheus.MustRegister(rateLimitRemaining)
+	prometheus.MustRegister(rateLimitedTotal)
 
 	prometheus.MustRegister(deploymentRequestDuration)
 	prometheus.MustRegister(deploymentResponseCodeTotal)
+	prometheus.MustRegister(supersededTotal)
+
+	prometheus.MustRegister(graphqlRequestDuration)
+	prometheus.MustRegister(graphqlResponseCodeTotal)
+
+	prometheus.MustRegister(discoveredProjects)
 
 	prometheus.MustRegister(deploymentStatusRequestDuration)
 	prometheus.MustRegister(deploymentStatusResponseCodeTotal)
 }
 
 // updateRateLimitMetrics is a utility function that takes a Response
-// containing rate limit headers, and updates the rate limit metrics.
-func updateRateLimitMetrics(response *http.Response) error {
-	rateLimitLimitValue, err := parseRateLimitValue(response)
-	if err != nil {
-		return microerror.Mask(err)
+// containing rate limit headers, and updates the rate limit metrics. Metrics
+// for missing headers are left untouched.
+func updateRateLimitMetrics(response *http.Response) {
+	if rateLimitLimitValue, ok := parseRateLimitValue(response); ok {
+		rateLimitLimit.Set(rateLimitLimitValue)
 	}
-	rateLimitLimit.Set(rateLimitLimitValue)
 
-	rateLimitRemainingValue, err := parseRateLimitRemaining(response)
-	if err != nil {
-		return microerror.Mask(err)
+	if rateLimitRemainingValue, ok := parseRateLimitRemaining(response); ok {
+		rateLimitRemaining.Set(rateLimitRemainingValue)
 	}
-	rateLimitRemaining.Set(rateLimitRemainingValue)
+}
+
+// updateRateLimitedMetrics is a utility function for counting requests that
+// GitHub rejected because of rate limiting.
+func updateRateLimitedMetrics(statusCode int) {
+	rateLimitedTotal.WithLabelValues(
+		strconv.Itoa(statusCode),
+	).Inc()
+}
 
-	return nil
+// updateSupersededMetrics is a utility function for counting deployments
+// that were superseded by a newer deployment.
+func updateSupersededMetrics(organisation, project string) {
+	supersededTotal.WithLabelValues(
+		organisation,
+		project,
+	).Inc()
 }
 
 // updateDeploymentMetrics is a utility function for updating metrics related
@@ -120,6 +185,33 @@ func updateDeploymentMetrics(organisation, project string, statusCode int, start
 	).Inc()
 }
 
+// updateGraphQLMetrics is a utility function for updating metrics related
+// to GraphQL API calls.
+func updateGraphQLMetrics(organisation string, statusCode int, startTime time.Time) {
+	graphqlRequestDuration.WithLabelValues(
+		organisation,
+		strconv.Itoa(statusCode),
+	).Set(
+		float64(time.Since(startTime) / time.Millisecond),
+	)
+
+	graphqlResponseCodeTotal.WithLabelValues(
+		organisation,
+		strconv.Itoa(statusCode),
+	).Inc()
+}
+
+// updateDiscoveredProjectsMetrics is a utility function for updating the
+// number of projects discovered via repository topics.
+func updateDiscoveredProjectsMetrics(organisation, topic string, count int) {
+	discoveredProjects.WithLabelValues(
+		organisation,
+		topic,
+	).Set(
+		float64(count),
+	)
+}
+
 // updateDeploymentStatusMetrics is a utility function for updating metrics
 // related to Deployment Status API calls.
 func updateDeploymentStatusMetrics(method, organisation, project string, statusCode int, startTime time.Time) {
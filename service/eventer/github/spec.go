@@ -1,37 +1,256 @@
 package github
 
 import (
+	"encoding/json"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
 	"github.com/giantswarm/draughtsman/service/eventer/spec"
 )
 
 // deployment represents a GitHub API Deployment.
 // See: https://developer.github.com/v3/repos/deployments/#create-a-deployment
 type deployment struct {
+	// Creator is the user who created the GitHub deployment.
+	Creator *user `json:"creator"`
+
+	// Description is the description field of the GitHub deployment.
+	Description string `json:"description"`
+
 	// Environment is the environment field of the GitHub deployment.
 	Environment string `json:"environment"`
 
 	// ID is the ID field of the GitHub deployment.
 	ID int `json:"id"`
 
+	// Payload is the payload field of the GitHub deployment. It is decoded
+	// when the deployment is converted to a DeploymentEvent.
+	Payload json.RawMessage `json:"payload"`
+
+	// Ref is the ref field of the GitHub deployment.
+	Ref string `json:"ref"`
+
 	// Sha is the SHA hash of the commit the deployment references.
 	Sha string `json:"sha"`
 
 	// Statuses is the deployment statuses of this deployment.
 	Statuses []deploymentStatus
+
+	// Task is the task field of the GitHub deployment, e.g: deploy.
+	Task string `json:"task"`
 }
 
-// Deploymespec.ntEvent returns the deployment as a DeploymentEvent.
-func (d deployment) DeploymentEvent(project string) spec.DeploymentEvent {
-	return spec.DeploymentEvent{
+// DeploymentEvent returns the deployment as a DeploymentEvent. It fails if
+// the payload of the deployment is invalid.
+func (d deployment) DeploymentEvent(project string) (spec.DeploymentEvent, error) {
+	payload, err := parsePayload(d.Payload)
+	if err != nil {
+		return spec.DeploymentEvent{}, microerror.Mask(err)
+	}
+
+	event := spec.DeploymentEvent{
 		ID:   d.ID,
 		Name: project,
 		Sha:  d.Sha,
+
+		Description: d.Description,
+		Payload:     payload,
+		Ref:         d.Ref,
+		Task:        spec.DeploymentTask(d.Task),
+	}
+	if d.Creator != nil {
+		event.Creator = d.Creator.Login
+	}
+
+	return event, nil
+}
+
+// parsePayload decodes the payload of a deployment, which GitHub returns
+// either as a JSON object, or as a string holding a JSON object, depending on
+// how the deployment was created.
+func parsePayload(data json.RawMessage) (spec.DeploymentPayload, error) {
+	var payload spec.DeploymentPayload
+
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err == nil {
+		data = json.RawMessage(encoded)
+	}
+
+	if len(data) == 0 || string(data) == "null" {
+		return payload, nil
+	}
+
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return spec.DeploymentPayload{}, microerror.Maskf(invalidPayloadError, err.Error())
+	}
+
+	return payload, nil
+}
+
+// user represents a GitHub API User.
+type user struct {
+	// Login is the login name of the user, e.g: octocat.
+	Login string `json:"login"`
+}
+
+// deploymentsPage represents a single page of GitHub API Deployments.
+type deploymentsPage struct {
+	// Deployments are the deployments on the page.
+	Deployments []deployment
+
+	// ETag is the etag of the page.
+	ETag string
+
+	// NextURL is the URL of the next page, empty on the last page.
+	NextURL string
+
+	// NotModified is true if the page did not change since it was requested
+	// with the given etag.
+	NotModified bool
+}
+
+// projectCursor represents the state of polling the deployments of a single
+// project.
+type projectCursor struct {
+	// ETag is the etag of the first page of deployments of the project.
+	ETag string `json:"etag,omitempty"`
+
+	// LastDeploymentID is the ID of the newest deployment that was seen.
+	LastDeploymentID int `json:"lastDeploymentID"`
+
+	// NewestDeploymentID is the ID of the newest deployment that was
+	// emitted.
+	NewestDeploymentID int `json:"newestDeploymentID,omitempty"`
+
+	// Unfinished are the IDs of the deployments that were emitted, but are
+	// not finished yet, oldest first. They are listed again after a restart.
+	Unfinished []int `json:"unfinished,omitempty"`
+}
+
+// graphqlRequest represents a request to the GitHub GraphQL API.
+// See: https://developer.github.com/v4/guides/forming-calls/#communicating-with-graphql
+type graphqlRequest struct {
+	// Query is the GraphQL query document.
+	Query string `json:"query"`
+
+	// Variables are the values of the variables used in the query.
+	Variables map[string]string `json:"variables"`
+}
+
+// graphqlDeploymentsResponse represents the response of the GitHub GraphQL
+// API to the deployments query, with one aliased repository per project.
+type graphqlDeploymentsResponse struct {
+	// Data holds the repositories, keyed by alias. Repositories that could
+	// not be queried are nil.
+	Data map[string]*graphqlRepository `json:"data"`
+
+	// Errors are the errors that occurred while executing the query.
+	Errors []graphqlError `json:"errors"`
+}
+
+// graphqlError represents an error returned by the GitHub GraphQL API.
+type graphqlError struct {
+	// Message is the description of the error.
+	Message string `json:"message"`
+}
+
+// graphqlRepository represents a GitHub GraphQL API Repository.
+// See: https://developer.github.com/v4/object/repository/
+type graphqlRepository struct {
+	// Deployments are the deployments of the repository.
+	Deployments struct {
+		Nodes    []graphqlDeployment `json:"nodes"`
+		PageInfo graphqlPageInfo     `json:"pageInfo"`
+	} `json:"deployments"`
+}
+
+// graphqlPageInfo represents the pagination information of a GitHub GraphQL
+// API connection.
+// See: https://developer.github.com/v4/object/pageinfo/
+type graphqlPageInfo struct {
+	// EndCursor is the cursor of the last node on the page.
+	EndCursor string `json:"endCursor"`
+
+	// HasNextPage is true if there are more nodes after the page.
+	HasNextPage bool `json:"hasNextPage"`
+}
+
+// graphqlDeployment represents a GitHub GraphQL API Deployment.
+// See: https://developer.github.com/v4/object/deployment/
+type graphqlDeployment struct {
+	// CommitOid is the SHA hash of the commit the deployment references.
+	CommitOid string `json:"commitOid"`
+
+	// Creator is the user who created the deployment.
+	Creator *user `json:"creator"`
+
+	// DatabaseID is the ID of the deployment, as used by the REST API.
+	DatabaseID int `json:"databaseId"`
+
+	// Description is the description of the deployment.
+	Description string `json:"description"`
+
+	// Environment is the environment of the deployment.
+	Environment string `json:"environment"`
+
+	// LatestStatus is the latest status of the deployment, if any.
+	LatestStatus *struct {
+		State string `json:"state"`
+	} `json:"latestStatus"`
+
+	// Payload is the payload of the deployment, encoded as JSON string.
+	Payload json.RawMessage `json:"payload"`
+
+	// Ref is the ref the deployment was created for, if any.
+	Ref *struct {
+		Name string `json:"name"`
+	} `json:"ref"`
+
+	// Task is the task of the deployment.
+	Task string `json:"task"`
+}
+
+// deployment returns the GraphQL deployment as a REST API deployment, with
+// the latest status as its only status.
+func (d graphqlDeployment) deployment() deployment {
+	statuses := []deploymentStatus{}
+	if d.LatestStatus != nil {
+		statuses = append(statuses, deploymentStatus{
+			State: deploymentStatusState(strings.ToLower(d.LatestStatus.State)),
+		})
+	}
+
+	var ref string
+	if d.Ref != nil {
+		ref = d.Ref.Name
+	}
+
+	return deployment{
+		Creator:     d.Creator,
+		Description: d.Description,
+		Environment: d.Environment,
+		ID:          d.DatabaseID,
+		Payload:     d.Payload,
+		Ref:         ref,
+		Sha:         d.CommitOid,
+		Statuses:    statuses,
+		Task:        d.Task,
 	}
 }
 
 // deploymentStatus represents a GitHub API Deployment Status.
 // See: https://developer.github.com/v3/repos/deployments/#create-a-deployment-status
 type deploymentStatus struct {
+	// Description is a short description of the deployment status.
+	Description string `json:"description,omitempty"`
+
+	// EnvironmentURL is the URL of the environment that was deployed to.
+	EnvironmentURL string `json:"environment_url,omitempty"`
+
+	// LogURL is the URL of the output of the deployment.
+	LogURL string `json:"log_url,omitempty"`
+
 	// State is the state of the deployment status.
 	State deploymentStatusState `json:"state"`
 }
@@ -40,10 +259,88 @@ type deploymentStatus struct {
 type deploymentStatusState string
 
 var (
+	// queuedState is the state for queued Deployment Status states.
+	queuedState deploymentStatusState = "queued"
+	// inProgressState is the state for in progress Deployment Status states.
+	inProgressState deploymentStatusState = "in_progress"
 	// pendingState is the state for pending Deployment Status states.
 	pendingState deploymentStatusState = "pending"
 	// successState is the state for successful Deployment Status states.
 	successState deploymentStatusState = "success"
 	// failureState is the state for failed Deployment Status states.
 	failureState deploymentStatusState = "failure"
+	// errorState is the state for errored Deployment Status states.
+	errorState deploymentStatusState = "error"
+	// inactiveState is the state for inactive Deployment Status states.
+	inactiveState deploymentStatusState = "inactive"
 )
+
+// isFinished returns true if the state marks the deployment as finished, so
+// that it is not acted on anymore.
+func (s deploymentStatusState) isFinished() bool {
+	switch s {
+	case successState, failureState, errorState, inactiveState:
+		return true
+	default:
+		return false
+	}
+}
+
+// deploymentWebhook represents the payload of a GitHub deployment webhook.
+// See: https://developer.github.com/v3/activity/events/types/#deploymentevent
+type deploymentWebhook struct {
+	// Deployment is the deployment that was created.
+	Deployment deployment `json:"deployment"`
+
+	// Repository is the repository the deployment was created for.
+	Repository repository `json:"repository"`
+}
+
+// webhookDelivery represents a deployment received via webhook, queued to be
+// looked up at GitHub.
+type webhookDelivery struct {
+	// Deployment is the deployment that was created.
+	Deployment deployment
+
+	// Project is the project the deployment was created for.
+	Project string
+}
+
+// repository represents a GitHub API Repository.
+type repository struct {
+	// Name is the name of the repository, e.g: aws-app-collection.
+	Name string `json:"name"`
+
+	// Owner is the user or organisation owning the repository.
+	Owner repositoryOwner `json:"owner"`
+}
+
+// repositoryOwner represents the owner of a GitHub API Repository.
+type repositoryOwner struct {
+	// Login is the login name of the owner, e.g: giantswarm.
+	Login string `json:"login"`
+}
+
+// topicRepository represents a GitHub API Repository, including its topics.
+// See: https://developer.github.com/v3/repos/#list-organization-repositories
+type topicRepository struct {
+	// Archived is true if the repository is archived.
+	Archived bool `json:"archived"`
+
+	// Name is the name of the repository.
+	Name string `json:"name"`
+
+	// Topics are the topics of the repository.
+	Topics []string `json:"topics"`
+}
+
+// hasTopic returns true if the repository carries the given topic.
+func (r topicRepository) hasTopic(topic string) bool {
+	for _, t := range r.Topics {
+		if strings.EqualFold(t, topic) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+package github
+
+import (
+	"fmt"
+)
+
+const (
+	// supersededDescriptionFormat is the string format for the description
+	// of the status of deployments that were superseded by a newer one.
+	supersededDescriptionFormat = "superseded by #%d"
+)
+
+// supersedeDeployments collapses the pending deployments of a project to the
+// newest one. Older deployments, as well as deployments older than one that
+// was already emitted, are marked as inactive, so that an older sha is never
+// installed after a newer one, whatever order deployments are received in.
+func (e *GithubEventer) supersedeDeployments(project string, deployments []deployment) []deployment {
+	if len(deployments) == 0 {
+		return deployments
+	}
+
+	e.newestMutex.Lock()
+	newestID := e.newestDeployments[project]
+	for _, deployment := range deployments {
+		if deployment.ID > newestID {
+			newestID = deployment.ID
+		}
+	}
+	e.newestDeployments[project] = newestID
+	e.newestMutex.Unlock()
+
+	matches := []deployment{}
+	for _, deployment := range deployments {
+		if deployment.ID == newestID {
+			matches = append(matches, deployment)
+			continue
+		}
+
+		e.logger.Log("debug", "superseding deployment", "project", project, "id", deployment.ID, "newest", newestID)
+
+		status := deploymentStatus{
+			Description: fmt.Sprintf(supersededDescriptionFormat, newestID),
+			State:       inactiveState,
+		}
+
+		err := e.postDeploymentStatus(project, deployment.ID, status)
+		if err != nil {
+			e.logger.Log("error", "could not mark deployment as superseded", "project", project, "id", deployment.ID, "message", err.Error())
+		}
+
+		updateSupersededMetrics(e.organisation, project)
+	}
+
+	return matches
+}
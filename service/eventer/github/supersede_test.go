@@ -0,0 +1,88 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+// TestSupersedeDeployments tests that only the newest pending deployment of
+// a project is kept, and that older ones are marked as superseded.
+func TestSupersedeDeployments(t *testing.T) {
+	tests := []struct {
+		newestID            int
+		deployments         []deployment
+		expectedDeployments []deployment
+		expectedStatuses    map[string]deploymentStatus
+	}{
+		// Test that a single deployment is kept.
+		{
+			deployments:         []deployment{{ID: 1}},
+			expectedDeployments: []deployment{{ID: 1}},
+			expectedStatuses:    map[string]deploymentStatus{},
+		},
+
+		// Test that only the newest of several deployments is kept,
+		// regardless of their order.
+		{
+			deployments:         []deployment{{ID: 2}, {ID: 3}, {ID: 1}},
+			expectedDeployments: []deployment{{ID: 3}},
+			expectedStatuses: map[string]deploymentStatus{
+				"/repos/giantswarm/api/deployments/1/statuses": {State: inactiveState, Description: "superseded by #3"},
+				"/repos/giantswarm/api/deployments/2/statuses": {State: inactiveState, Description: "superseded by #3"},
+			},
+		},
+
+		// Test that deployments older than an already emitted deployment
+		// are not kept.
+		{
+			newestID:            5,
+			deployments:         []deployment{{ID: 4}},
+			expectedDeployments: []deployment{},
+			expectedStatuses: map[string]deploymentStatus{
+				"/repos/giantswarm/api/deployments/4/statuses": {State: inactiveState, Description: "superseded by #5"},
+			},
+		},
+	}
+
+	for index, test := range tests {
+		statuses := map[string]deploymentStatus{}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var status deploymentStatus
+			if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			statuses[r.URL.Path] = status
+
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		e := GithubEventer{
+			client: testClient(server),
+			logger: microloggertest.New(),
+
+			newestDeployments: map[string]int{"api": test.newestID},
+			newestMutex:       &sync.Mutex{},
+
+			baseURL:      server.URL,
+			organisation: "giantswarm",
+		}
+
+		returnedDeployments := e.supersedeDeployments("api", test.deployments)
+		server.Close()
+
+		if !reflect.DeepEqual(test.expectedDeployments, returnedDeployments) {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedDeployments, returnedDeployments)
+		}
+		if !reflect.DeepEqual(test.expectedStatuses, statuses) {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedStatuses, statuses)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// repositoriesUrlFormat is the string format for the GitHub API call to
+	// list the repositories of an organisation.
+	// See: https://developer.github.com/v3/repos/#list-organization-repositories
+	repositoriesUrlFormat = "%s/orgs/%s/repos"
+
+	// topicsMediaType is the media type including the topics of
+	// repositories in responses.
+	// See: https://developer.github.com/v3/previews/#repository-topics
+	topicsMediaType = "application/vnd.github.mercy-preview+json"
+
+	// repositoriesPerPage is the number of repositories requested per page.
+	// GitHub allows at most 100.
+	repositoriesPerPage = 100
+)
+
+// TopicListerConfig represents the configuration used to create a
+// TopicLister.
+type TopicListerConfig struct {
+	// Dependencies.
+
+	// Client makes the requests to the GitHub API. It may be shared with a
+	// GitHub Eventer.
+	Client *Client
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// RefreshInterval is the duration for which a discovered project list
+	// is used before it is listed again.
+	RefreshInterval time.Duration
+	// Topic is the topic repositories carry to be deployed, e.g:
+	// draughtsman-aws.
+	Topic string
+}
+
+// DefaultTopicListerConfig provides a default configuration to create a new
+// TopicLister by best effort.
+func DefaultTopicListerConfig() TopicListerConfig {
+	return TopicListerConfig{
+		// Dependencies.
+		Client: nil,
+		Logger: nil,
+
+		// Settings.
+		RefreshInterval: 10 * time.Minute,
+	}
+}
+
+// NewTopicLister creates a new configured TopicLister.
+func NewTopicLister(config TopicListerConfig) (*TopicLister, error) {
+	if config.Client == nil {
+		return nil, microerror.Maskf(invalidConfigError, "client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	if config.RefreshInterval.Seconds() == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "refresh interval must be greater than zero")
+	}
+	if config.Topic == "" {
+		return nil, microerror.Maskf(invalidConfigError, "topic must not be empty")
+	}
+
+	lister := &TopicLister{
+		// Dependencies.
+		client: config.Client,
+		logger: config.Logger,
+
+		// Internals.
+		mutex: &sync.Mutex{},
+
+		// Settings.
+		refreshInterval: config.RefreshInterval,
+		topic:           config.Topic,
+	}
+
+	return lister, nil
+}
+
+// TopicLister is an implementation of the ProjectLister interface, that
+// discovers the projects to deploy as the repositories of the organisation
+// carrying a topic. The project list is listed again once it is older than
+// the refresh interval, so that new projects are picked up without a
+// release of draughtsman.
+type TopicLister struct {
+	// Dependencies.
+
+	client *Client
+	logger micrologger.Logger
+
+	// Internals.
+	mutex       *sync.Mutex
+	projectList []string
+	refreshed   time.Time
+
+	// Settings.
+	refreshInterval time.Duration
+	topic           string
+}
+
+// ProjectList returns the projects discovered by their topic. If listing the
+// repositories fails, the previously discovered projects are returned, and
+// listing is retried on the next call.
+func (l *TopicLister) ProjectList() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if time.Since(l.refreshed) >= l.refreshInterval {
+		projectList, err := l.fetchProjectList()
+		if err != nil {
+			l.logger.Log("error", "could not discover projects", "topic", l.topic, "message", err.Error())
+		} else {
+			l.logger.Log("debug", "discovered projects", "topic", l.topic, "projectlist", projectList)
+
+			l.projectList = projectList
+			l.refreshed = time.Now()
+
+			updateDiscoveredProjectsMetrics(l.client.organisation, l.topic, len(projectList))
+		}
+	}
+
+	return append([]string(nil), l.projectList...)
+}
+
+// fetchProjectList lists the repositories of the organisation, and returns
+// the names of the ones carrying the topic, that are not archived.
+func (l *TopicLister) fetchProjectList() ([]string, error) {
+	query := url.Values{}
+	query.Set("per_page", strconv.Itoa(repositoriesPerPage))
+
+	pageURL := fmt.Sprintf(
+		repositoriesUrlFormat,
+		l.client.baseURL,
+		l.client.organisation,
+	) + "?" + query.Encode()
+
+	projectList := []string{}
+
+	for pageURL != "" {
+		repositories, nextURL, err := l.fetchRepositoriesPage(pageURL)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, repository := range repositories {
+			if repository.Archived || !repository.hasTopic(l.topic) {
+				continue
+			}
+
+			projectList = append(projectList, repository.Name)
+		}
+
+		pageURL = nextURL
+	}
+
+	sort.Strings(projectList)
+
+	return projectList, nil
+}
+
+// fetchRepositoriesPage fetches a single page of repositories, and returns
+// them together with the URL of the next page, which is empty on the last
+// page.
+func (l *TopicLister) fetchRepositoriesPage(pageURL string) ([]topicRepository, string, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, "", microerror.Mask(err)
+	}
+	req.Header.Set("Accept", topicsMediaType)
+
+	resp, err := l.client.request(req)
+	if err != nil {
+		return nil, "", microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", microerror.Mask(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", microerror.Maskf(unexpectedStatusCode, fmt.Sprintf("received non-200 status code: %v, body: %q", resp.StatusCode, string(body)))
+	}
+
+	var repositories []topicRepository
+	if err := json.Unmarshal(body, &repositories); err != nil {
+		return nil, "", microerror.Mask(err)
+	}
+
+	return repositories, parseNextPageURL(resp), nil
+}
@@ -0,0 +1,103 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+)
+
+// TestTopicListerProjectList tests that projects are discovered by their
+// topic across pages, and only listed again once the refresh interval passed.
+func TestTopicListerProjectList(t *testing.T) {
+	var requests int
+	failing := false
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/orgs/giantswarm/repos" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Accept") != topicsMediaType {
+			t.Errorf("expected topics media type, returned: %#v", r.Header.Get("Accept"))
+		}
+
+		requests++
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set(linkHeader, fmt.Sprintf(`<%s/api/v3/orgs/giantswarm/repos?page=2>; rel="next"`, server.URL))
+			fmt.Fprint(w, `[
+				{"name": "web", "topics": ["draughtsman-aws"]},
+				{"name": "docs", "topics": ["documentation"]},
+				{"name": "legacy", "archived": true, "topics": ["draughtsman-aws"]}
+			]`)
+		case "2":
+			fmt.Fprint(w, `[{"name": "api", "topics": ["go", "draughtsman-aws"]}]`)
+		}
+	}))
+	defer server.Close()
+
+	var client *Client
+	{
+		c := DefaultClientConfig()
+
+		c.HTTPClient = server.Client()
+		c.Logger = microloggertest.New()
+
+		c.BaseURL = server.URL
+		c.OAuthToken = "token"
+		c.Organisation = "giantswarm"
+
+		var err error
+		client, err = NewClient(c)
+		if err != nil {
+			t.Fatalf("expected nil error, returned: %#v", err)
+		}
+	}
+
+	c := DefaultTopicListerConfig()
+
+	c.Client = client
+	c.Logger = microloggertest.New()
+
+	c.RefreshInterval = time.Hour
+	c.Topic = "draughtsman-aws"
+
+	l, err := NewTopicLister(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	expectedProjectList := []string{"api", "web"}
+
+	if projectList := l.ProjectList(); !reflect.DeepEqual(expectedProjectList, projectList) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedProjectList, projectList)
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, returned: %v", requests)
+	}
+
+	// The project list is not listed again within the refresh interval.
+	l.ProjectList()
+	if requests != 2 {
+		t.Fatalf("expected no further requests within the refresh interval, returned: %v", requests)
+	}
+
+	// Failures to list the repositories keep the previous project list.
+	failing = true
+	l.refreshed = time.Time{}
+
+	if projectList := l.ProjectList(); !reflect.DeepEqual(expectedProjectList, projectList) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedProjectList, projectList)
+	}
+}
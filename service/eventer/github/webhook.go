@@ -0,0 +1,330 @@
+package github
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+const (
+	// eventHeader is the header set by GitHub to name the event type of a
+	// webhook delivery.
+	// See: https://developer.github.com/webhooks/#delivery-headers
+	eventHeader = "X-GitHub-Event"
+
+	// signatureHeader is the header set by GitHub holding the HMAC SHA256
+	// hex digest of the webhook payload, keyed with the webhook secret.
+	// See: https://developer.github.com/webhooks/securing/
+	signatureHeader = "X-Hub-Signature-256"
+
+	// signaturePrefix is the prefix of the signature header value.
+	signaturePrefix = "sha256="
+
+	// deploymentEvent is the webhook event type for created deployments.
+	deploymentEvent = "deployment"
+
+	// webhookQueueSize is the number of webhook deliveries that can be
+	// queued up while the deployer is busy.
+	webhookQueueSize = 100
+
+	// finishedSize is the number of finished deployments remembered, so that
+	// copies of them that were fetched before they finished are not emitted
+	// again.
+	finishedSize = 1000
+)
+
+// GithubWebhookEventerType is an Eventer that uses GitHub Deployment webhooks
+// as a backend, and polls GitHub as a reconciliation fallback.
+var GithubWebhookEventerType spec.EventerType = "GithubWebhookEventer"
+
+// WebhookConfig represents the configuration used to create a GitHub Webhook
+// Eventer.
+type WebhookConfig struct {
+	Config
+
+	// WebhookSecret is the secret configured for the GitHub webhook, used to
+	// verify the signature of webhook deliveries.
+	WebhookSecret string
+}
+
+// DefaultWebhookConfig provides a default configuration to create a new
+// GitHub Webhook Eventer by best effort.
+func DefaultWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		Config: DefaultConfig(),
+
+		WebhookSecret: "",
+	}
+}
+
+// NewWebhook creates a new configured GitHub Webhook Eventer.
+func NewWebhook(config WebhookConfig) (*GithubWebhookEventer, error) {
+	if config.WebhookSecret == "" {
+		return nil, microerror.Maskf(invalidConfigError, "webhook secret must not be empty")
+	}
+
+	githubEventer, err := New(config.Config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	eventer := &GithubWebhookEventer{
+		GithubEventer: githubEventer,
+
+		// Internals.
+		emitted:           map[int]struct{}{},
+		finished:          map[int]struct{}{},
+		mutex:             &sync.Mutex{},
+		webhookDeliveries: make(chan webhookDelivery, webhookQueueSize),
+
+		// Settings.
+		webhookSecret: config.WebhookSecret,
+	}
+
+	return eventer, nil
+}
+
+// GithubWebhookEventer is an implementation of the Eventer interface,
+// that receives GitHub Deployment Events via webhooks. Polling is kept as a
+// reconciliation fallback, to catch any webhook deliveries that were missed.
+type GithubWebhookEventer struct {
+	*GithubEventer
+
+	// Internals.
+
+	// emitted holds the deployments that were emitted, and are not finished
+	// yet. finished holds the latest finished deployments, oldest first in
+	// finishedOrder.
+	emitted       map[int]struct{}
+	finished      map[int]struct{}
+	finishedOrder []int
+	mutex         *sync.Mutex
+	// webhookDeliveries holds the deliveries acknowledged, but not yet
+	// looked up at GitHub.
+	webhookDeliveries chan webhookDelivery
+
+	// Settings.
+	webhookSecret string
+}
+
+func (e *GithubWebhookEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	e.logger.Log("debug", "starting listening for github deployment webhooks")
+
+	pollEvents, err := e.GithubEventer.NewDeploymentEvents(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	deploymentEventChannel := make(chan spec.DeploymentEvent)
+
+	go func() {
+		defer close(deploymentEventChannel)
+
+		for {
+			var events []spec.DeploymentEvent
+			select {
+			case polled, ok := <-pollEvents:
+				if !ok {
+					// Polling stops once the context is cancelled.
+					return
+				}
+				events = []spec.DeploymentEvent{polled}
+			case delivery := <-e.webhookDeliveries:
+				events = e.webhookDeploymentEvents(delivery)
+			case <-ctx.Done():
+				return
+			}
+
+			for _, event := range events {
+				// Both webhooks and polling may report the same deployment,
+				// so only emit deployments we are not already processing, or
+				// have finished since they were fetched.
+				if !e.markEmitted(event.ID) {
+					e.logger.Log("debug", "skipping already emitted deployment event", "project", event.Name, "id", event.ID)
+					continue
+				}
+				e.markUnfinished(event.Name, event.ID)
+
+				select {
+				case deploymentEventChannel <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return deploymentEventChannel, nil
+}
+
+func (e *GithubWebhookEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	err := e.GithubEventer.SetStatus(event, status)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if status.State.IsFinal() {
+		e.rememberFinished(event.ID)
+	}
+
+	return nil
+}
+
+// HandleWebhook verifies and parses a GitHub webhook delivery, and queues
+// the deployment it references, if it applies to this environment. Whether
+// the deployment is finished is looked up once it is taken off the queue, so
+// that deliveries are acknowledged right away, even while GitHub requests
+// are rate limited.
+func (e *GithubWebhookEventer) HandleWebhook(ctx context.Context, header http.Header, body []byte) error {
+	if err := e.verifySignature(header.Get(signatureHeader), body); err != nil {
+		return microerror.Mask(err)
+	}
+
+	eventType := header.Get(eventHeader)
+	if eventType != deploymentEvent {
+		e.logger.Log("debug", "ignoring github webhook", "event", eventType)
+		return nil
+	}
+
+	var webhook deploymentWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return microerror.Mask(err)
+	}
+
+	if !e.isWatchedRepository(webhook.Repository) {
+		e.logger.Log("debug", "ignoring github webhook for unknown project", "organisation", webhook.Repository.Owner.Login, "project", webhook.Repository.Name)
+		return nil
+	}
+
+	deployments := e.filterDeploymentsByEnvironment([]deployment{webhook.Deployment})
+	if len(deployments) == 0 {
+		e.logger.Log("debug", "ignoring github webhook for other environment", "project", webhook.Repository.Name, "environment", webhook.Deployment.Environment)
+		return nil
+	}
+
+	e.logger.Log("debug", "received deployment event via webhook", "project", webhook.Repository.Name, "id", webhook.Deployment.ID)
+
+	delivery := webhookDelivery{
+		Deployment: deployments[0],
+		Project:    webhook.Repository.Name,
+	}
+
+	select {
+	case e.webhookDeliveries <- delivery:
+	default:
+		return microerror.Maskf(queueFullError, "could not queue deployment %d", webhook.Deployment.ID)
+	}
+
+	return nil
+}
+
+// webhookDeploymentEvents returns the deployment event of the delivery, if
+// the deployment is not finished yet.
+func (e *GithubWebhookEventer) webhookDeploymentEvents(delivery webhookDelivery) []spec.DeploymentEvent {
+	project := delivery.Project
+	delivered := delivery.Deployment
+
+	// GitHub redelivers webhooks, e.g. when redelivered manually, so the
+	// statuses are checked to not install finished deployments again.
+	statuses, err := e.fetchDeploymentStatus(project, delivered)
+	if err != nil {
+		e.logger.Log("error", "could not fetch deployment status, leaving the deployment to polling", "project", project, "id", delivered.ID, "message", err.Error())
+		return nil
+	}
+	delivered.Statuses = statuses
+
+	deployments := e.filterDeploymentsByStatus([]deployment{delivered})
+	if len(deployments) == 0 {
+		e.logger.Log("debug", "ignoring github webhook for finished deployment", "project", project, "id", delivered.ID)
+		return nil
+	}
+
+	deployments = e.supersedeDeployments(project, deployments)
+
+	return e.deploymentEvents(project, deployments)
+}
+
+// verifySignature checks that the given signature header value matches the
+// HMAC SHA256 digest of the body, keyed with the webhook secret.
+func (e *GithubWebhookEventer) verifySignature(signature string, body []byte) error {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return microerror.Maskf(invalidSignatureError, "%#q header is missing or malformed", signatureHeader)
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return microerror.Maskf(invalidSignatureError, "%#q header is not hex encoded", signatureHeader)
+	}
+
+	mac := hmac.New(sha256.New, []byte(e.webhookSecret))
+	mac.Write(body)
+
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return microerror.Maskf(invalidSignatureError, "signature does not match payload")
+	}
+
+	return nil
+}
+
+// isWatchedRepository returns true if the repository belongs to the
+// configured organisation, and is in the project list.
+func (e *GithubWebhookEventer) isWatchedRepository(repository repository) bool {
+	if !strings.EqualFold(repository.Owner.Login, e.organisation) {
+		return false
+	}
+
+	for _, project := range e.projects() {
+		if project == repository.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// markEmitted records the deployment as emitted, and returns false if it had
+// already been emitted before, and is not finished yet, or was finished.
+func (e *GithubWebhookEventer) markEmitted(id int) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, ok := e.emitted[id]; ok {
+		return false
+	}
+	if _, ok := e.finished[id]; ok {
+		return false
+	}
+	e.emitted[id] = struct{}{}
+
+	return true
+}
+
+// rememberFinished moves an emitted deployment to the finished deployments
+// once its final status is set. Only the latest finishedSize deployments are
+// remembered.
+func (e *GithubWebhookEventer) rememberFinished(id int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.emitted, id)
+
+	if _, ok := e.finished[id]; ok {
+		return
+	}
+	e.finished[id] = struct{}{}
+	e.finishedOrder = append(e.finishedOrder, id)
+
+	if len(e.finishedOrder) > finishedSize {
+		delete(e.finished, e.finishedOrder[0])
+		e.finishedOrder = e.finishedOrder[1:]
+	}
+}
@@ -0,0 +1,284 @@
+package github
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// sign returns the GitHub signature header value for the given body.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// TestHandleWebhook tests the HandleWebhook method, and the lookup of the
+// deliveries it queues.
+func TestHandleWebhook(t *testing.T) {
+	deploymentBody := []byte(`{
+		"deployment": {"id": 1, "sha": "12345", "environment": "production"},
+		"repository": {"name": "api", "owner": {"login": "GiantSwarm"}}
+	}`)
+
+	tests := []struct {
+		event         string
+		body          []byte
+		signature     string
+		errorMatcher  func(error) bool
+		expectedEvent *spec.DeploymentEvent
+	}{
+		// Test that a valid deployment webhook is queued.
+		{
+			event:         deploymentEvent,
+			body:          deploymentBody,
+			signature:     sign("secret", deploymentBody),
+			expectedEvent: &spec.DeploymentEvent{ID: 1, Name: "api", Sha: "12345"},
+		},
+
+		// Test that the task, payload and creator of a deployment are kept.
+		{
+			event: deploymentEvent,
+			body: []byte(`{
+				"deployment": {"id": 4, "sha": "12345", "ref": "master", "task": "rollback", "environment": "production", "creator": {"login": "octocat"}, "payload": {"dryRun": true, "values": {"image.tag": "1.2.3"}}},
+				"repository": {"name": "api", "owner": {"login": "giantswarm"}}
+			}`),
+			signature: sign("secret", []byte(`{
+				"deployment": {"id": 4, "sha": "12345", "ref": "master", "task": "rollback", "environment": "production", "creator": {"login": "octocat"}, "payload": {"dryRun": true, "values": {"image.tag": "1.2.3"}}},
+				"repository": {"name": "api", "owner": {"login": "giantswarm"}}
+			}`)),
+			expectedEvent: &spec.DeploymentEvent{
+				ID:      4,
+				Name:    "api",
+				Sha:     "12345",
+				Creator: "octocat",
+				Payload: spec.DeploymentPayload{
+					DryRun: true,
+					Values: map[string]string{"image.tag": "1.2.3"},
+				},
+				Ref:  "master",
+				Task: spec.RollbackTask,
+			},
+		},
+
+		// Test that redeliveries of finished deployments are ignored.
+		{
+			event: deploymentEvent,
+			body: []byte(`{
+				"deployment": {"id": 5, "sha": "12345", "environment": "production"},
+				"repository": {"name": "api", "owner": {"login": "giantswarm"}}
+			}`),
+			signature: sign("secret", []byte(`{
+				"deployment": {"id": 5, "sha": "12345", "environment": "production"},
+				"repository": {"name": "api", "owner": {"login": "giantswarm"}}
+			}`)),
+		},
+
+		// Test that a missing signature is rejected.
+		{
+			event:        deploymentEvent,
+			body:         deploymentBody,
+			signature:    "",
+			errorMatcher: IsInvalidSignature,
+		},
+
+		// Test that a signature made with another secret is rejected.
+		{
+			event:        deploymentEvent,
+			body:         deploymentBody,
+			signature:    sign("other", deploymentBody),
+			errorMatcher: IsInvalidSignature,
+		},
+
+		// Test that other event types are ignored.
+		{
+			event:     "ping",
+			body:      []byte(`{}`),
+			signature: sign("secret", []byte(`{}`)),
+		},
+
+		// Test that deployments for other environments are ignored.
+		{
+			event: deploymentEvent,
+			body: []byte(`{
+				"deployment": {"id": 2, "sha": "12345", "environment": "staging"},
+				"repository": {"name": "api", "owner": {"login": "giantswarm"}}
+			}`),
+			signature: sign("secret", []byte(`{
+				"deployment": {"id": 2, "sha": "12345", "environment": "staging"},
+				"repository": {"name": "api", "owner": {"login": "giantswarm"}}
+			}`)),
+		},
+
+		// Test that deployments for projects not in the project list are ignored.
+		{
+			event: deploymentEvent,
+			body: []byte(`{
+				"deployment": {"id": 3, "sha": "12345", "environment": "production"},
+				"repository": {"name": "other", "owner": {"login": "giantswarm"}}
+			}`),
+			signature: sign("secret", []byte(`{
+				"deployment": {"id": 3, "sha": "12345", "environment": "production"},
+				"repository": {"name": "other", "owner": {"login": "giantswarm"}}
+			}`)),
+		},
+	}
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		switch r.URL.Path {
+		case "/repos/giantswarm/api/deployments/5/statuses":
+			fmt.Fprint(w, `[{"state": "success"}, {"state": "in_progress"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	for index, test := range tests {
+		e := &GithubWebhookEventer{
+			GithubEventer: &GithubEventer{
+				client: testClient(server),
+				logger: microloggertest.New(),
+
+				baseURL:      server.URL,
+				environment:  "production",
+				organisation: "giantswarm",
+				projectList:  []string{"api"},
+
+				newestDeployments: map[string]int{},
+				newestMutex:       &sync.Mutex{},
+			},
+
+			emitted:           map[int]struct{}{},
+			finished:          map[int]struct{}{},
+			mutex:             &sync.Mutex{},
+			webhookDeliveries: make(chan webhookDelivery, 1),
+
+			webhookSecret: "secret",
+		}
+
+		header := http.Header{}
+		header.Set(eventHeader, test.event)
+		header.Set(signatureHeader, test.signature)
+
+		requests = 0
+		err := e.HandleWebhook(context.Background(), header, test.body)
+		if requests != 0 {
+			t.Fatalf("%v\nexpected deliveries to be acknowledged without requests, returned: %d requests\n", index, requests)
+		}
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected matching error, returned: %#v\n", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+		}
+
+		var returnedEvent *spec.DeploymentEvent
+		select {
+		case delivery := <-e.webhookDeliveries:
+			events := e.webhookDeploymentEvents(delivery)
+			if len(events) > 0 {
+				returnedEvent = &events[0]
+			}
+		default:
+		}
+
+		if !reflect.DeepEqual(test.expectedEvent, returnedEvent) {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedEvent, returnedEvent,
+			)
+		}
+	}
+}
+
+// TestWebhookAndPollEmitOnce tests that a deployment reported by both a
+// webhook and a poll is only emitted once, even if the poll fetched it
+// before the copy of the webhook was finished.
+func TestWebhookAndPollEmitOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/repos/giantswarm/api/deployments":
+			fmt.Fprint(w, `[{"id": 1, "sha": "12345", "environment": "production"}]`)
+		default:
+			// Statuses are always stale, as if they were fetched before the
+			// deployment finished.
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	c := DefaultWebhookConfig()
+
+	c.Client = testClient(server)
+	c.Logger = microloggertest.New()
+
+	c.Environment = "production"
+	c.PollInterval = time.Hour
+	c.ProjectList = []string{"api"}
+	c.Provider = "aws"
+	c.WebhookSecret = "secret"
+
+	e, err := NewWebhook(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	body := []byte(`{
+		"deployment": {"id": 1, "sha": "12345", "environment": "production"},
+		"repository": {"name": "api", "owner": {"login": "giantswarm"}}
+	}`)
+	header := http.Header{}
+	header.Set(eventHeader, deploymentEvent)
+	header.Set(signatureHeader, sign("secret", body))
+
+	err = e.HandleWebhook(context.Background(), header, body)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := e.NewDeploymentEvents(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	event := <-events
+	if event.ID != 1 {
+		t.Fatalf("expected deployment 1, returned: %#v", event)
+	}
+
+	err = e.SetStatus(event, spec.DeploymentStatus{State: spec.SuccessState})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	select {
+	case event := <-events:
+		t.Fatalf("expected deployment to be emitted once, returned: %#v", event)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
@@ -0,0 +1,157 @@
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// DefaultBaseURL is the URL of GitLab.com.
+	DefaultBaseURL = "https://gitlab.com"
+
+	// deploymentsUrlFormat is the string format for the GitLab API call for
+	// Deployments. Templated with the base URL and the escaped project path.
+	// See: https://docs.gitlab.com/ee/api/deployments.html#list-project-deployments
+	deploymentsUrlFormat = "%s/api/v4/projects/%s/deployments"
+
+	// deploymentUrlFormat is the string format for the GitLab API call to
+	// update a Deployment.
+	// See: https://docs.gitlab.com/ee/api/deployments.html#update-a-deployment
+	deploymentUrlFormat = "%s/api/v4/projects/%s/deployments/%d"
+
+	// tokenHeader is the header used by GitLab for token authentication.
+	// See: https://docs.gitlab.com/ee/api/#personalprojectgroup-access-tokens
+	tokenHeader = "PRIVATE-TOKEN"
+
+	// deploymentsPerPage is the number of deployments requested per poll.
+	// GitLab allows at most 100.
+	deploymentsPerPage = 100
+)
+
+// projectID returns the URL encoded path of the project, which GitLab
+// accepts in place of the numeric project ID.
+func (e *GitlabEventer) projectID(project string) string {
+	return url.PathEscape(e.group + "/" + project)
+}
+
+// request makes an authenticated request, and updates the request metrics.
+func (e *GitlabEventer) request(req *http.Request, project string) (*http.Response, error) {
+	req.Header.Set(tokenHeader, e.token)
+
+	startTime := time.Now()
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	updateRequestMetrics(req.Method, e.group, project, resp.StatusCode, startTime)
+
+	return resp, nil
+}
+
+// fetchCreatedDeployments fetches the deployments of the given project in
+// the configured environment, that were created and not started yet, oldest
+// first.
+func (e *GitlabEventer) fetchCreatedDeployments(project string) ([]deployment, error) {
+	e.logger.Log("debug", "fetching deployments", "project", project)
+
+	query := url.Values{}
+	query.Set("environment", e.environment)
+	query.Set("order_by", "id")
+	query.Set("per_page", strconv.Itoa(deploymentsPerPage))
+	query.Set("sort", "asc")
+	query.Set("status", string(createdStatus))
+
+	u := fmt.Sprintf(deploymentsUrlFormat, e.baseURL, e.projectID(project)) + "?" + query.Encode()
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	resp, err := e.request(req, project)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, microerror.Maskf(unexpectedStatusCode, "received non-200 status code: %v, body: %q", resp.StatusCode, string(body))
+	}
+
+	var deployments []deployment
+	if err := json.Unmarshal(body, &deployments); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The environment and status are filtered by GitLab already. They are
+	// checked again, as older GitLab versions ignore unknown filters.
+	matches := []deployment{}
+	for _, deployment := range deployments {
+		if deployment.Environment.Name != e.environment || deployment.Status != createdStatus {
+			continue
+		}
+
+		// Deployments of CI jobs are skipped, as their status follows the
+		// job, and GitLab rejects status updates for them.
+		if deployment.Deployable != nil {
+			e.logger.Log("debug", "skipping deployment created by ci job", "project", project, "id", deployment.ID, "job", deployment.Deployable.ID)
+			continue
+		}
+
+		matches = append(matches, deployment)
+	}
+
+	if len(matches) > 0 {
+		e.logger.Log("debug", "found new deployment events", "project", project)
+	}
+
+	return matches, nil
+}
+
+// updateDeploymentStatus updates the status of the given Deployment.
+func (e *GitlabEventer) updateDeploymentStatus(project string, id int, status deploymentStatus) error {
+	e.logger.Log("debug", "updating deployment status", "project", project, "id", id, "status", status)
+
+	payload, err := json.Marshal(deploymentUpdate{
+		Status: status,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	u := fmt.Sprintf(deploymentUrlFormat, e.baseURL, e.projectID(project), id)
+
+	req, err := http.NewRequest("PUT", u, bytes.NewBuffer(payload))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.request(req, project)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return microerror.Maskf(unexpectedStatusCode, "received non-200 status code: %v, body: %q", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,280 @@
+package gitlab
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/draughtsman/service/eventer/poller"
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+)
+
+const (
+	// finishedSize is the number of finished deployments remembered, so that
+	// copies of them that were fetched before they finished are not emitted
+	// again.
+	finishedSize = 1000
+)
+
+// GitlabEventerType is an Eventer that uses GitLab Deployments as a backend.
+var GitlabEventerType spec.EventerType = "GitlabEventer"
+
+// Config represents the configuration used to create a GitLab Eventer.
+type Config struct {
+	// Dependencies.
+	HTTPClient httpspec.Client
+	Logger     micrologger.Logger
+	// ProjectLister lists the projects to poll. It is optional, and
+	// ProjectList is used when empty.
+	ProjectLister spec.ProjectLister
+
+	// Settings.
+
+	// BaseURL is the URL of the GitLab instance, e.g: https://gitlab.com.
+	BaseURL     string
+	Environment string
+	// Group is the group, or user, the projects belong to, e.g: giantswarm.
+	Group        string
+	PollInterval time.Duration
+	// PollJitter is the maximum factor of the poll interval added to every
+	// wait, so that replicas do not poll in lockstep.
+	PollJitter float64
+	// PollWorkers is the maximum number of projects polled concurrently.
+	PollWorkers int
+	ProjectList []string
+	// Token is the personal, group or project access token used to
+	// authenticate. It needs the api scope.
+	Token string
+}
+
+// DefaultConfig provides a default configuration to create a new GitLab
+// Eventer by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		HTTPClient:    nil,
+		Logger:        nil,
+		ProjectLister: nil,
+
+		// Settings.
+		BaseURL:     DefaultBaseURL,
+		PollWorkers: 1,
+	}
+}
+
+// New creates a new configured GitLab Eventer.
+func New(config Config) (*GitlabEventer, error) {
+	if config.HTTPClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	if config.BaseURL == "" {
+		return nil, microerror.Maskf(invalidConfigError, "base url must not be empty")
+	}
+	if config.Environment == "" {
+		return nil, microerror.Maskf(invalidConfigError, "environment must not be empty")
+	}
+	if config.Group == "" {
+		return nil, microerror.Maskf(invalidConfigError, "group must not be empty")
+	}
+	if config.PollInterval.Seconds() == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "interval must be greater than zero")
+	}
+	if config.ProjectLister == nil && len(config.ProjectList) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "project list must not be empty")
+	}
+	if config.Token == "" {
+		return nil, microerror.Maskf(invalidConfigError, "token must not be empty")
+	}
+
+	var projectPoller *poller.Poller
+	{
+		c := poller.DefaultConfig()
+
+		c.Eventer = string(GitlabEventerType)
+		c.Interval = config.PollInterval
+		c.Jitter = config.PollJitter
+		c.Workers = config.PollWorkers
+
+		var err error
+		projectPoller, err = poller.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	eventer := &GitlabEventer{
+		// Dependencies.
+		client:        config.HTTPClient,
+		logger:        config.Logger,
+		poller:        projectPoller,
+		projectLister: config.ProjectLister,
+
+		// Internals.
+		emitted:  map[int]struct{}{},
+		finished: map[int]struct{}{},
+		mutex:    &sync.Mutex{},
+
+		// Settings.
+		baseURL:      strings.TrimSuffix(config.BaseURL, "/"),
+		environment:  config.Environment,
+		group:        config.Group,
+		pollInterval: config.PollInterval,
+		projectList:  config.ProjectList,
+		token:        config.Token,
+	}
+
+	return eventer, nil
+}
+
+// GitlabEventer is an implementation of the Eventer interface, that uses
+// GitLab Deployments as a backend. Deployments created via the GitLab API are
+// picked up while their status is created. Deployments created by CI jobs are
+// skipped, as GitLab only accepts status updates for API-created ones.
+type GitlabEventer struct {
+	// Dependencies.
+	client        httpspec.Client
+	logger        micrologger.Logger
+	poller        *poller.Poller
+	projectLister spec.ProjectLister
+
+	// Internals.
+
+	// emitted holds the IDs of deployments that were emitted, and are not
+	// finished yet. Deployments keep the created status until they are
+	// installed, so they would be emitted again on every poll otherwise.
+	// finished holds the IDs of the latest finished deployments, oldest first
+	// in finishedOrder, as a poll may have fetched a deployment before it
+	// finished.
+	emitted       map[int]struct{}
+	finished      map[int]struct{}
+	finishedOrder []int
+	mutex         *sync.Mutex
+
+	// Settings.
+	baseURL      string
+	environment  string
+	group        string
+	pollInterval time.Duration
+	projectList  []string
+	token        string
+}
+
+func (e *GitlabEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	e.logger.Log("debug", "starting polling for gitlab deployment events", "interval", e.pollInterval)
+
+	deploymentEventChannel := make(chan spec.DeploymentEvent)
+
+	go func() {
+		defer close(deploymentEventChannel)
+
+		for {
+			projectList := e.projects()
+
+			e.logger.Log("debug", "Fetching deployment events", "projectlist", projectList)
+			e.poller.Each(ctx, projectList, func(project string) {
+				deployments, err := e.fetchCreatedDeployments(project)
+				if err != nil {
+					e.logger.Log("error", "could not fetch deployment events", "message", err.Error())
+					return
+				}
+
+				for _, deployment := range deployments {
+					if !e.markEmitted(deployment.ID) {
+						continue
+					}
+
+					select {
+					case deploymentEventChannel <- deployment.DeploymentEvent(project):
+					case <-ctx.Done():
+						return
+					}
+				}
+			})
+
+			if !e.poller.Wait(ctx) {
+				e.logger.Log("debug", "stopped polling for gitlab deployment events")
+				return
+			}
+		}
+	}()
+
+	return deploymentEventChannel, nil
+}
+
+// projects returns the projects to poll, as listed by the project lister, if
+// there is one.
+func (e *GitlabEventer) projects() []string {
+	if e.projectLister != nil {
+		return e.projectLister.ProjectList()
+	}
+
+	return e.projectList
+}
+
+func (e *GitlabEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	gitlabStatus, ok := deploymentStatusFor(status.State)
+	if !ok {
+		e.logger.Log("debug", "skipping deployment status not supported by gitlab", "project", event.Name, "id", event.ID, "state", status.State)
+		return nil
+	}
+
+	err := e.updateDeploymentStatus(event.Name, event.ID, gitlabStatus)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// The deployment is only forgotten once GitLab knows it is finished, as
+	// it would be polled, and installed, again otherwise.
+	if status.State.IsFinal() {
+		e.rememberFinished(event.ID)
+	}
+
+	return nil
+}
+
+// markEmitted records the deployment as emitted. It returns false if the
+// deployment was already emitted, and is not finished yet, or was finished.
+func (e *GitlabEventer) markEmitted(id int) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, ok := e.emitted[id]; ok {
+		return false
+	}
+	if _, ok := e.finished[id]; ok {
+		return false
+	}
+	e.emitted[id] = struct{}{}
+
+	return true
+}
+
+// rememberFinished moves an emitted deployment to the finished deployments
+// once its final status is set. Only the latest finishedSize deployments are
+// remembered.
+func (e *GitlabEventer) rememberFinished(id int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	delete(e.emitted, id)
+
+	if _, ok := e.finished[id]; ok {
+		return
+	}
+	e.finished[id] = struct{}{}
+	e.finishedOrder = append(e.finishedOrder, id)
+
+	if len(e.finishedOrder) > finishedSize {
+		delete(e.finished, e.finishedOrder[0])
+		e.finishedOrder = e.finishedOrder[1:]
+	}
+}
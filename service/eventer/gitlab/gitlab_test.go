@@ -0,0 +1,154 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// TestGitlabEventer tests fetching created deployments, and updating their
+// statuses, against a fake GitLab API server.
+func TestGitlabEventer(t *testing.T) {
+	updates := map[string]deploymentUpdate{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(tokenHeader) != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == "GET" && r.URL.EscapedPath() == "/api/v4/projects/giantswarm%2Fapi/deployments":
+			query := r.URL.Query()
+			if query.Get("environment") != "production" || query.Get("status") != "created" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			fmt.Fprint(w, `[
+				{"id": 1, "ref": "master", "sha": "12345", "status": "created", "environment": {"name": "production"}, "user": {"username": "octocat"}},
+				{"id": 2, "ref": "master", "sha": "67890", "status": "created", "environment": {"name": "staging"}},
+				{"id": 3, "ref": "master", "sha": "67890", "status": "created", "environment": {"name": "production"}, "deployable": {"id": 42}}
+			]`)
+		case r.Method == "PUT" && r.URL.EscapedPath() == "/api/v4/projects/giantswarm%2Fapi/deployments/1":
+			var update deploymentUpdate
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			updates[r.URL.EscapedPath()] = update
+
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	e := GitlabEventer{
+		client: server.Client(),
+		logger: microloggertest.New(),
+
+		emitted:  map[int]struct{}{},
+		finished: map[int]struct{}{},
+		mutex:    &sync.Mutex{},
+
+		baseURL:     server.URL,
+		environment: "production",
+		group:       "giantswarm",
+		token:       "token",
+	}
+
+	deployments, err := e.fetchCreatedDeployments("api")
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if len(deployments) != 1 {
+		t.Fatalf("expected one deployment, returned: %#v", deployments)
+	}
+
+	event := deployments[0].DeploymentEvent("api")
+	expectedEvent := spec.DeploymentEvent{ID: 1, Name: "api", Sha: "12345", Creator: "octocat", Ref: "master"}
+	if !reflect.DeepEqual(expectedEvent, event) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedEvent, event)
+	}
+
+	// Queued deployments keep the created status, as GitLab does not know
+	// about queued deployments.
+	for _, state := range []spec.DeploymentState{spec.QueuedState, spec.InProgressState, spec.SuccessState} {
+		err := e.SetStatus(event, spec.DeploymentStatus{State: state})
+		if err != nil {
+			t.Fatalf("expected nil error, returned: %#v", err)
+		}
+	}
+
+	expectedUpdates := map[string]deploymentUpdate{
+		"/api/v4/projects/giantswarm%2Fapi/deployments/1": {Status: successStatus},
+	}
+	if !reflect.DeepEqual(expectedUpdates, updates) {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedUpdates, updates)
+	}
+}
+
+// TestMarkEmitted tests that deployments are not emitted again while they are
+// not finished, nor once they are finished, as a poll may have fetched them
+// before they finished. A failed final update keeps them emitted.
+func TestMarkEmitted(t *testing.T) {
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	e := GitlabEventer{
+		client: server.Client(),
+		logger: microloggertest.New(),
+
+		emitted:  map[int]struct{}{},
+		finished: map[int]struct{}{},
+		mutex:    &sync.Mutex{},
+
+		baseURL: server.URL,
+		group:   "giantswarm",
+		token:   "token",
+	}
+	event := spec.DeploymentEvent{ID: 1, Name: "api"}
+
+	if !e.markEmitted(1) {
+		t.Fatalf("expected deployment to be emitted")
+	}
+	if e.markEmitted(1) {
+		t.Fatalf("expected deployment not to be emitted twice")
+	}
+
+	err := e.SetStatus(event, spec.DeploymentStatus{State: spec.SuccessState})
+	if err == nil {
+		t.Fatalf("expected error, returned: nil")
+	}
+	if _, ok := e.emitted[1]; !ok {
+		t.Fatalf("expected deployment to stay emitted after a failed update")
+	}
+
+	fail = false
+	err = e.SetStatus(event, spec.DeploymentStatus{State: spec.SuccessState})
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	if e.markEmitted(1) {
+		t.Fatalf("expected finished deployment not to be emitted again")
+	}
+}
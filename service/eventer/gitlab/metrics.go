@@ -0,0 +1,64 @@
+package gitlab
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// prometheusNamespace is the namespace to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusNamespace = "draughtsman"
+
+	// prometheusSubsystem is the subsystem to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusSubsystem = "gitlab_eventer"
+)
+
+var (
+	requestDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "gitlab_deployment_duration_milliseconds",
+			Help:      "Time taken to request GitLab deployments.",
+		},
+		[]string{"method", "group", "project", "code"},
+	)
+	responseCodeTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "gitlab_deployment_response_code",
+			Help:      "Response codes of GitLab API requests for deployments.",
+		},
+		[]string{"method", "group", "project", "code"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(responseCodeTotal)
+}
+
+// updateRequestMetrics is a utility function for updating metrics related
+// to Deployment API calls.
+func updateRequestMetrics(method, group, project string, statusCode int, startTime time.Time) {
+	requestDuration.WithLabelValues(
+		method,
+		group,
+		project,
+		strconv.Itoa(statusCode),
+	).Set(
+		float64(time.Since(startTime) / time.Millisecond),
+	)
+
+	responseCodeTotal.WithLabelValues(
+		method,
+		group,
+		project,
+		strconv.Itoa(statusCode),
+	).Inc()
+}
@@ -0,0 +1,107 @@
+package gitlab
+
+import (
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// deployment represents a GitLab API Deployment.
+// See: https://docs.gitlab.com/ee/api/deployments.html#list-project-deployments
+type deployment struct {
+	// Deployable is the CI job that created the deployment, if any. GitLab
+	// only accepts status updates for deployments created via the API, which
+	// have no deployable.
+	Deployable *deployable `json:"deployable"`
+
+	// Environment is the environment of the GitLab deployment.
+	Environment environment `json:"environment"`
+
+	// ID is the ID field of the GitLab deployment.
+	ID int `json:"id"`
+
+	// Ref is the branch or tag the deployment was created for.
+	Ref string `json:"ref"`
+
+	// Sha is the SHA hash of the commit the deployment references.
+	Sha string `json:"sha"`
+
+	// Status is the status of the GitLab deployment.
+	Status deploymentStatus `json:"status"`
+
+	// User is the user who created the GitLab deployment.
+	User *user `json:"user"`
+}
+
+// DeploymentEvent returns the deployment as a DeploymentEvent.
+func (d deployment) DeploymentEvent(project string) spec.DeploymentEvent {
+	event := spec.DeploymentEvent{
+		ID:   d.ID,
+		Name: project,
+		Sha:  d.Sha,
+
+		Ref: d.Ref,
+	}
+	if d.User != nil {
+		event.Creator = d.User.Username
+	}
+
+	return event
+}
+
+// deployable represents the CI job of a GitLab API Deployment.
+type deployable struct {
+	// ID is the ID of the CI job.
+	ID int `json:"id"`
+}
+
+// environment represents a GitLab API Environment.
+type environment struct {
+	// Name is the name of the environment, e.g: production.
+	Name string `json:"name"`
+}
+
+// user represents a GitLab API User.
+type user struct {
+	// Username is the username of the user.
+	Username string `json:"username"`
+}
+
+// deploymentUpdate represents the request to update a GitLab API
+// Deployment.
+// See: https://docs.gitlab.com/ee/api/deployments.html#update-a-deployment
+type deploymentUpdate struct {
+	// Status is the new status of the deployment.
+	Status deploymentStatus `json:"status"`
+}
+
+// deploymentStatus represents possible GitLab Deployment statuses.
+type deploymentStatus string
+
+var (
+	// createdStatus is the status of deployments that were created, and
+	// wait to be deployed.
+	createdStatus deploymentStatus = "created"
+	// runningStatus is the status of deployments that are being deployed.
+	runningStatus deploymentStatus = "running"
+	// successStatus is the status of successful deployments.
+	successStatus deploymentStatus = "success"
+	// failedStatus is the status of failed deployments.
+	failedStatus deploymentStatus = "failed"
+	// canceledStatus is the status of deployments that were canceled.
+	canceledStatus deploymentStatus = "canceled"
+)
+
+// deploymentStatusFor returns the GitLab Deployment status matching the
+// given state. GitLab has no notion of queued deployments, so they keep the
+// created status, in which case false is returned.
+func deploymentStatusFor(state spec.DeploymentState) (deploymentStatus, bool) {
+	switch state {
+	case spec.InProgressState:
+		return runningStatus, true
+	case spec.SuccessState:
+		return successStatus, true
+	case spec.FailureState:
+		return failedStatus, true
+	default:
+		return "", false
+	}
+}
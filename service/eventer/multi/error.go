@@ -0,0 +1,23 @@
+package multi
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var unknownEventerError = &microerror.Error{
+	Kind: "unknownEventerError",
+}
+
+// IsUnknownEventer asserts unknownEventerError.
+func IsUnknownEventer(err error) bool {
+	return microerror.Cause(err) == unknownEventerError
+}
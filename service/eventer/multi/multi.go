@@ -0,0 +1,133 @@
+package multi
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// Config represents the configuration used to create a Multi Eventer.
+type Config struct {
+	// Dependencies.
+
+	// Eventers are the Eventers to merge, by type.
+	Eventers map[spec.EventerType]spec.Eventer
+	Logger   micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new Multi
+// Eventer by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Eventers: nil,
+		Logger:   nil,
+	}
+}
+
+// New creates a new configured Multi Eventer. If any of the Eventers can
+// receive webhooks, the returned Eventer forwards webhooks to them.
+func New(config Config) (spec.Eventer, error) {
+	if len(config.Eventers) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "eventers must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	var types []spec.EventerType
+	for t := range config.Eventers {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	eventer := &MultiEventer{
+		// Dependencies.
+		eventers: config.Eventers,
+		logger:   config.Logger,
+
+		// Internals.
+		types: types,
+	}
+
+	for _, e := range config.Eventers {
+		if _, ok := e.(spec.WebhookHandler); ok {
+			return &MultiWebhookEventer{MultiEventer: eventer}, nil
+		}
+	}
+
+	return eventer, nil
+}
+
+// MultiEventer is an implementation of the Eventer interface, that merges
+// the deployment events of multiple Eventers into one channel. Every event
+// remembers the Eventer it came from, so that statuses are set there.
+type MultiEventer struct {
+	// Dependencies.
+	eventers map[spec.EventerType]spec.Eventer
+	logger   micrologger.Logger
+
+	// Internals.
+	types []spec.EventerType
+}
+
+func (e *MultiEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	e.logger.Log("debug", "starting multiple eventers", "types", e.types)
+
+	// The Eventers share a context of their own, so that the ones already
+	// started are stopped again when a later one fails to start.
+	ctx, cancel := context.WithCancel(ctx)
+
+	deploymentEventChannel := make(chan spec.DeploymentEvent)
+
+	var wg sync.WaitGroup
+	for _, t := range e.types {
+		eventerChannel, err := e.eventers[t].NewDeploymentEvents(ctx)
+		if err != nil {
+			cancel()
+			return nil, microerror.Mask(err)
+		}
+
+		wg.Add(1)
+		go func(t spec.EventerType, eventerChannel <-chan spec.DeploymentEvent) {
+			defer wg.Done()
+
+			for event := range eventerChannel {
+				event.Eventer = t
+
+				select {
+				case deploymentEventChannel <- event:
+				case <-ctx.Done():
+				}
+			}
+		}(t, eventerChannel)
+	}
+
+	// The channel is closed once all Eventers closed theirs.
+	go func() {
+		wg.Wait()
+		cancel()
+		close(deploymentEventChannel)
+	}()
+
+	return deploymentEventChannel, nil
+}
+
+func (e *MultiEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	eventer, ok := e.eventers[event.Eventer]
+	if !ok {
+		return microerror.Maskf(unknownEventerError, "eventer %#q of event %d", event.Eventer, event.ID)
+	}
+
+	err := eventer.SetStatus(event, status)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package multi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// testEventer is an Eventer emitting the given events, and recording the
+// statuses set.
+type testEventer struct {
+	events   []spec.DeploymentEvent
+	statuses []spec.DeploymentState
+}
+
+func (e *testEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	c := make(chan spec.DeploymentEvent)
+
+	go func() {
+		defer close(c)
+
+		for _, event := range e.events {
+			c <- event
+		}
+
+		<-ctx.Done()
+	}()
+
+	return c, nil
+}
+
+func (e *testEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	e.statuses = append(e.statuses, status.State)
+	return nil
+}
+
+// testContextEventer is an Eventer remembering the context it was started
+// with.
+type testContextEventer struct {
+	testEventer
+	started *context.Context
+}
+
+func (e *testContextEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	*e.started = ctx
+	return e.testEventer.NewDeploymentEvents(ctx)
+}
+
+// testFailingEventer is an Eventer that fails to start.
+type testFailingEventer struct{}
+
+func (e *testFailingEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	return nil, errors.New("test error")
+}
+
+func (e *testFailingEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	return nil
+}
+
+// testWebhookEventer is a testEventer that counts the webhooks received.
+type testWebhookEventer struct {
+	testEventer
+	webhooks int
+}
+
+func (e *testWebhookEventer) HandleWebhook(ctx context.Context, header http.Header, body []byte) error {
+	e.webhooks++
+	return nil
+}
+
+// TestMultiEventer tests merging events, and routing statuses back to the
+// Eventer the event came from.
+func TestMultiEventer(t *testing.T) {
+	github := &testEventer{events: []spec.DeploymentEvent{{ID: 1, Name: "api"}}}
+	gitlab := &testEventer{events: []spec.DeploymentEvent{{ID: 1, Name: "web"}}}
+
+	c := DefaultConfig()
+	c.Eventers = map[spec.EventerType]spec.Eventer{"GithubEventer": github, "GitlabEventer": gitlab}
+	c.Logger = microloggertest.New()
+
+	e, err := New(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if _, ok := e.(spec.WebhookHandler); ok {
+		t.Fatalf("expected eventer not to handle webhooks")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := e.NewDeploymentEvents(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		event := <-events
+
+		expectedEventer := map[string]spec.EventerType{"api": "GithubEventer", "web": "GitlabEventer"}[event.Name]
+		if event.Eventer != expectedEventer {
+			t.Fatalf("expected event of %#q, returned: %#v", expectedEventer, event)
+		}
+
+		if err := e.SetStatus(event, spec.DeploymentStatus{State: spec.SuccessState}); err != nil {
+			t.Fatalf("expected nil error, returned: %#v", err)
+		}
+	}
+
+	if len(github.statuses) != 1 || len(gitlab.statuses) != 1 {
+		t.Fatalf("expected one status per eventer, returned: %#v, %#v", github.statuses, gitlab.statuses)
+	}
+
+	if err := e.SetStatus(spec.DeploymentEvent{Eventer: "FileEventer"}, spec.DeploymentStatus{}); !IsUnknownEventer(err) {
+		t.Fatalf("expected unknown eventer error, returned: %#v", err)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatalf("expected channel to be closed once the context is cancelled")
+	}
+}
+
+// TestMultiEventerStartError tests that the Eventers already started are
+// stopped when a later Eventer fails to start.
+func TestMultiEventerStartError(t *testing.T) {
+	var started context.Context
+
+	github := &testContextEventer{started: &started}
+
+	c := DefaultConfig()
+	c.Eventers = map[spec.EventerType]spec.Eventer{"GithubEventer": github, "GitlabEventer": &testFailingEventer{}}
+	c.Logger = microloggertest.New()
+
+	e, err := New(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	if _, err := e.NewDeploymentEvents(context.Background()); err == nil {
+		t.Fatalf("expected error, returned nil")
+	}
+
+	if started == nil {
+		t.Fatalf("expected first eventer to be started")
+	}
+	select {
+	case <-started.Done():
+	default:
+		t.Fatalf("expected context of started eventer to be cancelled")
+	}
+}
+
+// TestMultiWebhookEventer tests forwarding webhooks to the Eventers that
+// can receive them.
+func TestMultiWebhookEventer(t *testing.T) {
+	webhook := &testWebhookEventer{}
+
+	c := DefaultConfig()
+	c.Eventers = map[spec.EventerType]spec.Eventer{"GithubWebhookEventer": webhook, "GitlabEventer": &testEventer{}}
+	c.Logger = microloggertest.New()
+
+	e, err := New(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	handler, ok := e.(spec.WebhookHandler)
+	if !ok {
+		t.Fatalf("expected eventer to handle webhooks")
+	}
+	if err := handler.HandleWebhook(context.Background(), http.Header{}, nil); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if webhook.webhooks != 1 {
+		t.Fatalf("expected webhook to be forwarded, returned: %d", webhook.webhooks)
+	}
+}
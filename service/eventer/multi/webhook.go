@@ -0,0 +1,42 @@
+package multi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// MultiWebhookEventer is a MultiEventer that forwards webhooks to all of
+// its Eventers that can receive them.
+type MultiWebhookEventer struct {
+	*MultiEventer
+}
+
+func (e *MultiWebhookEventer) HandleWebhook(ctx context.Context, header http.Header, body []byte) error {
+	var firstErr error
+
+	for _, t := range e.types {
+		handler, ok := e.eventers[t].(spec.WebhookHandler)
+		if !ok {
+			continue
+		}
+
+		err := handler.HandleWebhook(ctx, header, body)
+		if err != nil {
+			e.logger.Log("error", "could not handle webhook", "type", t, "message", err.Error())
+
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return microerror.Mask(firstErr)
+	}
+
+	return nil
+}
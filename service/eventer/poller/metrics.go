@@ -0,0 +1,59 @@
+package poller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// prometheusNamespace is the namespace to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusNamespace = "draughtsman"
+
+	// prometheusSubsystem is the subsystem to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusSubsystem = "poller"
+)
+
+var (
+	pollDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "poll_duration_milliseconds",
+			Help:      "Time taken to poll a project for new deployments.",
+		},
+		[]string{"eventer", "project"},
+	)
+	pollTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "polls_total",
+			Help:      "Number of polls of a project for new deployments.",
+		},
+		[]string{"eventer", "project"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(pollDuration)
+	prometheus.MustRegister(pollTotal)
+}
+
+// updatePollMetrics is a utility function for updating metrics related to
+// polling a project.
+func updatePollMetrics(eventer, project string, startTime time.Time) {
+	pollDuration.WithLabelValues(
+		eventer,
+		project,
+	).Set(
+		float64(time.Since(startTime) / time.Millisecond),
+	)
+
+	pollTotal.WithLabelValues(
+		eventer,
+		project,
+	).Inc()
+}
@@ -0,0 +1,151 @@
+// Package poller schedules the polling of the eventers. Polls are spread
+// across replicas by jittering the poll interval, and the projects of a poll
+// are polled concurrently by a bounded pool of workers.
+package poller
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Config represents the configuration used to create a Poller.
+type Config struct {
+	// Settings.
+
+	// Eventer is the name of the eventer polling, used to label metrics.
+	Eventer  string
+	Interval time.Duration
+	// Jitter is the maximum factor of the interval added to every wait, e.g.
+	// 0.1 waits between 1 and 1.1 intervals. Zero disables jitter.
+	Jitter float64
+	// Workers is the maximum number of projects polled concurrently.
+	Workers int
+}
+
+// DefaultConfig provides a default configuration to create a new Poller by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		Eventer:  "",
+		Interval: 0,
+		Jitter:   0,
+		Workers:  1,
+	}
+}
+
+// New creates a new configured Poller.
+func New(config Config) (*Poller, error) {
+	if config.Eventer == "" {
+		return nil, microerror.Maskf(invalidConfigError, "eventer must not be empty")
+	}
+	if config.Interval.Seconds() == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "interval must be greater than zero")
+	}
+	if config.Jitter < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "jitter must not be negative")
+	}
+	if config.Workers < 1 {
+		return nil, microerror.Maskf(invalidConfigError, "workers must be greater than zero")
+	}
+
+	poller := &Poller{
+		// Internals.
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+		mutex:  &sync.Mutex{},
+
+		// Settings.
+		eventer:  config.Eventer,
+		interval: config.Interval,
+		jitter:   config.Jitter,
+		workers:  config.Workers,
+	}
+
+	return poller, nil
+}
+
+// Poller schedules the polls of an eventer.
+type Poller struct {
+	// Internals.
+
+	// random is seeded per process, so that replicas do not jitter in
+	// lockstep.
+	random *rand.Rand
+	mutex  *sync.Mutex
+
+	// Settings.
+	eventer  string
+	interval time.Duration
+	jitter   float64
+	workers  int
+}
+
+// Each calls poll for every project, polling at most the configured number of
+// projects concurrently, and records the time taken per project. It returns
+// once all started polls are done. Projects not started yet are skipped once
+// the context is cancelled.
+func (p *Poller) Each(ctx context.Context, projects []string, poll func(project string)) {
+	projectChannel := make(chan string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers && i < len(projects); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for project := range projectChannel {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				startTime := time.Now()
+				poll(project)
+				updatePollMetrics(p.eventer, project, startTime)
+			}
+		}()
+	}
+
+feed:
+	for _, project := range projects {
+		select {
+		case projectChannel <- project:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(projectChannel)
+	wg.Wait()
+}
+
+// Wait waits for the jittered poll interval. It returns false if the context
+// was cancelled before, in which case polling should stop.
+func (p *Poller) Wait(ctx context.Context) bool {
+	timer := time.NewTimer(p.nextInterval())
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// nextInterval returns the interval, with a random jitter of up to the
+// configured factor added.
+func (p *Poller) nextInterval() time.Duration {
+	if p.jitter == 0 {
+		return p.interval
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.interval + time.Duration(p.random.Float64()*p.jitter*float64(p.interval))
+}
@@ -0,0 +1,194 @@
+package poller
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestNew tests that the Poller configuration is validated.
+func TestNew(t *testing.T) {
+	tests := []struct {
+		config        func() Config
+		errorMatcher  func(error) bool
+		expectedError bool
+	}{
+		{
+			config: func() Config {
+				c := DefaultConfig()
+				c.Eventer = "GithubEventer"
+				c.Interval = time.Minute
+				return c
+			},
+		},
+		{
+			config: func() Config {
+				c := DefaultConfig()
+				c.Interval = time.Minute
+				return c
+			},
+			errorMatcher:  IsInvalidConfig,
+			expectedError: true,
+		},
+		{
+			config: func() Config {
+				c := DefaultConfig()
+				c.Eventer = "GithubEventer"
+				c.Interval = time.Minute
+				c.Jitter = -0.1
+				return c
+			},
+			errorMatcher:  IsInvalidConfig,
+			expectedError: true,
+		},
+		{
+			config: func() Config {
+				c := DefaultConfig()
+				c.Eventer = "GithubEventer"
+				c.Interval = time.Minute
+				c.Workers = 0
+				return c
+			},
+			errorMatcher:  IsInvalidConfig,
+			expectedError: true,
+		},
+	}
+
+	for index, test := range tests {
+		_, err := New(test.config())
+		if test.expectedError {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v: unexpected error returned: %#v", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+	}
+}
+
+// TestEach tests that every project is polled, and that no more projects
+// than workers are polled concurrently.
+func TestEach(t *testing.T) {
+	c := DefaultConfig()
+	c.Eventer = "TestEventer"
+	c.Interval = time.Minute
+	c.Workers = 2
+
+	p, err := New(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	var mutex sync.Mutex
+	var running, maxRunning int
+	polled := map[string]bool{}
+
+	p.Each(context.Background(), []string{"api", "web", "worker", "cron", "db"}, func(project string) {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		polled[project] = true
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+	})
+
+	if len(polled) != 5 {
+		t.Fatalf("expected all projects to be polled, returned: %#v", polled)
+	}
+	if maxRunning > 2 {
+		t.Fatalf("expected at most 2 concurrent polls, returned: %v", maxRunning)
+	}
+}
+
+// TestEachCancel tests that projects are not polled once the context is
+// cancelled.
+func TestEachCancel(t *testing.T) {
+	c := DefaultConfig()
+	c.Eventer = "TestEventer"
+	c.Interval = time.Minute
+
+	p, err := New(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var polled []string
+	p.Each(ctx, []string{"api", "web", "worker"}, func(project string) {
+		polled = append(polled, project)
+		cancel()
+	})
+
+	if len(polled) != 1 {
+		t.Fatalf("expected polling to stop after cancellation, returned: %#v", polled)
+	}
+}
+
+// TestNextInterval tests that the jitter stays within the configured factor.
+func TestNextInterval(t *testing.T) {
+	tests := []struct {
+		jitter      float64
+		expectedMin time.Duration
+		expectedMax time.Duration
+	}{
+		{
+			jitter:      0,
+			expectedMin: time.Minute,
+			expectedMax: time.Minute,
+		},
+		{
+			jitter:      0.5,
+			expectedMin: time.Minute,
+			expectedMax: 90 * time.Second,
+		},
+	}
+
+	for index, test := range tests {
+		c := DefaultConfig()
+		c.Eventer = "TestEventer"
+		c.Interval = time.Minute
+		c.Jitter = test.jitter
+
+		p, err := New(c)
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+
+		for i := 0; i < 100; i++ {
+			interval := p.nextInterval()
+			if interval < test.expectedMin || interval > test.expectedMax {
+				t.Fatalf("%v: expected interval between %v and %v, returned: %v", index, test.expectedMin, test.expectedMax, interval)
+			}
+		}
+	}
+}
+
+// TestWaitCancel tests that waiting stops once the context is cancelled.
+func TestWaitCancel(t *testing.T) {
+	c := DefaultConfig()
+	c.Eventer = "TestEventer"
+	c.Interval = time.Hour
+
+	p, err := New(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if p.Wait(ctx) {
+		t.Fatalf("expected wait to return false once the context is cancelled")
+	}
+}
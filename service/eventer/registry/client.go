@@ -0,0 +1,252 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/draughtsman/pkg/registryauth"
+)
+
+const (
+	// repositoryFormat is the format of the repository of the chart of a
+	// project. Templated with the organisation and project.
+	repositoryFormat = "%s/%s-chart"
+
+	// ociTagsUrlFormat is the string format for the OCI distribution API
+	// call for Tags. Templated with the base URL and repository.
+	ociTagsUrlFormat = "%s/v2/%s/tags/list"
+
+	// ociManifestUrlFormat is the string format for the OCI distribution
+	// API call for Manifests. Templated with the base URL, repository and
+	// tag.
+	ociManifestUrlFormat = "%s/v2/%s/manifests/%s"
+
+	// ociBlobUrlFormat is the string format for the OCI distribution API
+	// call for Blobs. Templated with the base URL, repository and digest.
+	ociBlobUrlFormat = "%s/v2/%s/blobs/%s"
+
+	// ociManifestMediaTypes are the media types of manifests accepted.
+	ociManifestMediaTypes = "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json"
+
+	// ociCreatedAnnotation is the annotation of manifests holding the
+	// creation time of the artifact.
+	// See: https://github.com/opencontainers/image-spec/blob/main/annotations.md
+	ociCreatedAnnotation = "org.opencontainers.image.created"
+
+	// cnrPackageUrlFormat is the string format for the CNR API call for the
+	// Releases of a package. Templated with the base URL and repository.
+	cnrPackageUrlFormat = "%s/cnr/api/v1/packages/%s"
+
+	// cnrCreatedLayout is the layout of the creation time of CNR releases,
+	// which is given without time zone, in UTC.
+	cnrCreatedLayout = "2006-01-02T15:04:05"
+
+	// jsonMediaType is the media type accepted for other responses.
+	jsonMediaType = "application/json"
+)
+
+// listTags returns the tags of the chart of the project, together with the
+// time they were pushed, if known.
+func (e *RegistryEventer) listTags(project string) ([]chartTag, error) {
+	repository := fmt.Sprintf(repositoryFormat, e.organisation, project)
+
+	if e.api == OCIAPI {
+		body, err := e.request(fmt.Sprintf(ociTagsUrlFormat, e.baseURL, repository), project, repository, jsonMediaType)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var tagList ociTagList
+		if err := json.Unmarshal(body, &tagList); err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var tags []chartTag
+		for _, name := range tagList.Tags {
+			created, err := e.tagCreated(project, repository, name)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			tags = append(tags, chartTag{Name: name, Created: created})
+		}
+
+		return tags, nil
+	}
+
+	body, err := e.request(fmt.Sprintf(cnrPackageUrlFormat, e.baseURL, repository), project, repository, jsonMediaType)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var releases []cnrRelease
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var tags []chartTag
+	for _, release := range releases {
+		created, _ := parseCreated(release.CreatedAt, cnrCreatedLayout)
+		tags = append(tags, chartTag{Name: release.Release, Created: created})
+	}
+
+	return tags, nil
+}
+
+// tagCreated returns the time the tag of an OCI repository was pushed, as
+// given by the creation annotation of its manifest, or the creation time in
+// its config. It returns the zero time if neither is set. Tags are expected
+// not to be moved, so the time is only looked up once per tag.
+func (e *RegistryEventer) tagCreated(project, repository, tag string) (time.Time, error) {
+	key := repository + ":" + tag
+
+	e.createdMutex.Lock()
+	created, ok := e.created[key]
+	e.createdMutex.Unlock()
+	if ok {
+		return created, nil
+	}
+
+	body, err := e.request(fmt.Sprintf(ociManifestUrlFormat, e.baseURL, repository, tag), project, repository, ociManifestMediaTypes)
+	if err != nil {
+		return time.Time{}, microerror.Mask(err)
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return time.Time{}, microerror.Mask(err)
+	}
+
+	created, ok = parseCreated(manifest.Annotations[ociCreatedAnnotation])
+	if !ok && manifest.Config.Digest != "" {
+		body, err := e.request(fmt.Sprintf(ociBlobUrlFormat, e.baseURL, repository, manifest.Config.Digest), project, repository, jsonMediaType)
+		if err != nil {
+			return time.Time{}, microerror.Mask(err)
+		}
+
+		var config ociConfig
+		if err := json.Unmarshal(body, &config); err != nil {
+			return time.Time{}, microerror.Mask(err)
+		}
+
+		created, _ = parseCreated(config.Created)
+	}
+
+	e.createdMutex.Lock()
+	e.created[key] = created
+	e.createdMutex.Unlock()
+
+	return created, nil
+}
+
+// parseCreated parses a creation time in RFC 3339 format, or any of the
+// given layouts. The second return value is false if the time is empty or
+// malformed.
+func parseCreated(value string, layouts ...string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range append([]string{time.RFC3339Nano}, layouts...) {
+		created, err := time.Parse(layout, value)
+		if err == nil {
+			return created, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// request makes a GET request to the registry. Requests that are challenged
+// for a bearer token are retried with a token of the repository. Tokens are
+// reused until they expire.
+func (e *RegistryEventer) request(u, project, repository, accept string) ([]byte, error) {
+	var token string
+	e.tokensMutex.Lock()
+	if cached := e.tokens[repository]; cached.Valid() {
+		token = cached.Value
+	}
+	e.tokensMutex.Unlock()
+
+	resp, err := e.do(u, project, token, accept)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized && e.api == OCIAPI {
+		token, err := e.fetchToken(resp.Header.Get("Www-Authenticate"), project)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		e.tokensMutex.Lock()
+		e.tokens[repository] = token
+		e.tokensMutex.Unlock()
+
+		resp, err = e.do(u, project, token.Value, accept)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		defer resp.Body.Close()
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, microerror.Maskf(unexpectedStatusCode, "received status code: %v, body: %q", resp.StatusCode, string(b))
+	}
+
+	return b, nil
+}
+
+// do makes an authenticated GET request, using the bearer token if there is
+// one, and basic authentication otherwise.
+func (e *RegistryEventer) do(u, project, token, accept string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	req.Header.Set("Accept", accept)
+
+	if token != "" {
+		req.Header.Set("Authorization", registryauth.BearerPrefix+token)
+	} else if e.username != "" {
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	startTime := time.Now()
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	updateRequestMetrics("GET", e.organisation, project, resp.StatusCode, startTime)
+
+	return resp, nil
+}
+
+// fetchToken fetches a bearer token from the token service named by the
+// given authentication challenge.
+func (e *RegistryEventer) fetchToken(challenge, project string) (registryauth.Token, error) {
+	do := func(u string) (*http.Response, error) {
+		return e.do(u, project, "", jsonMediaType)
+	}
+
+	token, err := registryauth.FetchToken(challenge, do)
+	if registryauth.IsInvalidChallenge(err) || registryauth.IsUnexpectedStatusCode(err) {
+		return registryauth.Token{}, microerror.Maskf(unexpectedStatusCode, err.Error())
+	} else if err != nil {
+		return registryauth.Token{}, microerror.Mask(err)
+	}
+
+	return token, nil
+}
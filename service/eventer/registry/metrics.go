@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// prometheusNamespace is the namespace to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusNamespace = "draughtsman"
+
+	// prometheusSubsystem is the subsystem to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusSubsystem = "registry_eventer"
+)
+
+var (
+	requestDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "registry_duration_milliseconds",
+			Help:      "Time taken to request chart tags from the registry.",
+		},
+		[]string{"method", "organisation", "project", "code"},
+	)
+	responseCodeTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "registry_response_code",
+			Help:      "Response codes of registry API requests.",
+		},
+		[]string{"method", "organisation", "project", "code"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(responseCodeTotal)
+}
+
+// updateRequestMetrics is a utility function for updating metrics related
+// to registry API calls.
+func updateRequestMetrics(method, organisation, project string, statusCode int, startTime time.Time) {
+	requestDuration.WithLabelValues(
+		method,
+		organisation,
+		project,
+		strconv.Itoa(statusCode),
+	).Set(
+		float64(time.Since(startTime) / time.Millisecond),
+	)
+
+	responseCodeTotal.WithLabelValues(
+		method,
+		organisation,
+		project,
+		strconv.Itoa(statusCode),
+	).Inc()
+}
@@ -0,0 +1,105 @@
+package registry
+
+import (
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/giantswarm/microerror"
+)
+
+// globCharacters are the characters that make a pattern a glob, rather than
+// a semver range.
+const globCharacters = "*?["
+
+// matcher matches chart tags against a pattern.
+type matcher struct {
+	constraints *semver.Constraints
+	glob        string
+}
+
+// newMatcher creates a matcher for the given pattern. Patterns containing
+// glob characters, e.g: 1.0.0-*, match tags as globs. Other patterns are
+// semver ranges, e.g: >= 1.2.0, < 2.0.0.
+func newMatcher(pattern string) (matcher, error) {
+	if pattern == "" {
+		return matcher{}, microerror.Maskf(invalidPatternError, "pattern must not be empty")
+	}
+
+	if strings.ContainsAny(pattern, globCharacters) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return matcher{}, microerror.Maskf(invalidPatternError, "%#q: %s", pattern, err.Error())
+		}
+
+		return matcher{glob: pattern}, nil
+	}
+
+	constraints, err := semver.NewConstraint(pattern)
+	if err != nil {
+		return matcher{}, microerror.Maskf(invalidPatternError, "%#q: %s", pattern, err.Error())
+	}
+
+	return matcher{constraints: constraints}, nil
+}
+
+// matches returns true if the tag matches the pattern.
+func (m matcher) matches(tag string) bool {
+	if m.constraints != nil {
+		version, err := semver.NewVersion(tag)
+		if err != nil {
+			return false
+		}
+
+		return m.constraints.Check(version)
+	}
+
+	ok, _ := path.Match(m.glob, tag)
+	return ok
+}
+
+// newestTag returns the newest of the given tags.
+func newestTag(tags []chartTag) chartTag {
+	var newest chartTag
+
+	for index, tag := range tags {
+		if index == 0 || tag.newerThan(newest) {
+			newest = tag
+		}
+	}
+
+	return newest
+}
+
+// newerThan returns true if the tag is newer than the other tag. Tags are
+// ordered by the time they were pushed, as the pre-releases of tags like
+// 1.0.0-<sha> carry shas rather than an order. If either push time is
+// unknown, tags are ordered by their semver version, and tags that are no
+// semver version are the oldest.
+func (t chartTag) newerThan(other chartTag) bool {
+	if !t.Created.IsZero() && !other.Created.IsZero() && !t.Created.Equal(other.Created) {
+		return t.Created.After(other.Created)
+	}
+
+	version, err := semver.NewVersion(t.Name)
+	if err != nil {
+		_, otherErr := semver.NewVersion(other.Name)
+		return otherErr != nil && t.Name > other.Name
+	}
+	otherVersion, err := semver.NewVersion(other.Name)
+	if err != nil {
+		return true
+	}
+
+	return version.GreaterThan(otherVersion)
+}
+
+// tagSha returns the sha of the given tag, which is the pre-release part of
+// tags like 1.0.0-<sha>, and the tag itself otherwise.
+func tagSha(tag string) string {
+	version, err := semver.NewVersion(tag)
+	if err != nil || version.Prerelease() == "" {
+		return tag
+	}
+
+	return version.Prerelease()
+}
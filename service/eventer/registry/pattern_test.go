@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMatcher tests matching tags against glob and semver range patterns.
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		pattern       string
+		tag           string
+		expectedMatch bool
+	}{
+		{pattern: "1.0.0-*", tag: "1.0.0-12345", expectedMatch: true},
+		{pattern: "1.0.0-*", tag: "1.2.0", expectedMatch: false},
+		{pattern: ">= 1.2.0, < 2.0.0", tag: "1.2.3", expectedMatch: true},
+		{pattern: ">= 1.2.0, < 2.0.0", tag: "2.0.0", expectedMatch: false},
+		{pattern: ">= 1.2.0, < 2.0.0", tag: "latest", expectedMatch: false},
+		{pattern: "~1.2", tag: "1.2.9", expectedMatch: true},
+	}
+
+	for index, test := range tests {
+		m, err := newMatcher(test.pattern)
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+
+		if returnedMatch := m.matches(test.tag); returnedMatch != test.expectedMatch {
+			t.Fatalf("%v: expected: %v, returned: %v", index, test.expectedMatch, returnedMatch)
+		}
+	}
+}
+
+// TestNewMatcherInvalid tests that invalid patterns are rejected.
+func TestNewMatcherInvalid(t *testing.T) {
+	for index, pattern := range []string{"", "1.0.0-[", "not a version"} {
+		if _, err := newMatcher(pattern); !IsInvalidPattern(err) {
+			t.Fatalf("%v: expected invalid pattern error, returned: %#v", index, err)
+		}
+	}
+}
+
+// TestNewestTag tests the newestTag function.
+func TestNewestTag(t *testing.T) {
+	created := func(minutes int) time.Time {
+		return time.Date(2020, 1, 1, 0, minutes, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		tags        []chartTag
+		expectedTag string
+	}{
+		{
+			tags:        []chartTag{{Name: "1.0.0-fedcba", Created: created(2)}, {Name: "1.0.0-abcdef", Created: created(1)}},
+			expectedTag: "1.0.0-fedcba",
+		},
+		{
+			tags:        []chartTag{{Name: "1.0.0-abcdef", Created: created(1)}, {Name: "1.0.0-fedcba", Created: created(2)}},
+			expectedTag: "1.0.0-fedcba",
+		},
+		{
+			tags:        []chartTag{{Name: "1.2.0"}, {Name: "1.10.0"}, {Name: "1.9.0"}},
+			expectedTag: "1.10.0",
+		},
+		{
+			tags:        []chartTag{{Name: "1.1.0-abcdef"}, {Name: "1.0.0-fedcba", Created: created(1)}},
+			expectedTag: "1.1.0-abcdef",
+		},
+		{
+			tags:        []chartTag{{Name: "latest"}, {Name: "1.0.0"}},
+			expectedTag: "1.0.0",
+		},
+		{
+			tags:        []chartTag{{Name: "latest"}},
+			expectedTag: "latest",
+		},
+	}
+
+	for index, test := range tests {
+		if returnedTag := newestTag(test.tags); returnedTag.Name != test.expectedTag {
+			t.Fatalf("%v: expected: %v, returned: %v", index, test.expectedTag, returnedTag.Name)
+		}
+	}
+}
+
+// TestTagSha tests the tagSha function.
+func TestTagSha(t *testing.T) {
+	tests := []struct {
+		tag         string
+		expectedSha string
+	}{
+		{tag: "1.0.0-12345", expectedSha: "12345"},
+		{tag: "1.2.3", expectedSha: "1.2.3"},
+		{tag: "latest", expectedSha: "latest"},
+	}
+
+	for index, test := range tests {
+		if returnedSha := tagSha(test.tag); returnedSha != test.expectedSha {
+			t.Fatalf("%v: expected: %v, returned: %v", index, test.expectedSha, returnedSha)
+		}
+	}
+}
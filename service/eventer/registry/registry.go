@@ -0,0 +1,437 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/draughtsman/pkg/registryauth"
+	"github.com/giantswarm/draughtsman/service/eventer/poller"
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+	"github.com/giantswarm/draughtsman/service/eventer/state"
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+)
+
+const (
+	// DefaultBaseURL is the URL of the default registry.
+	DefaultBaseURL = "https://quay.io"
+
+	// DefaultPattern is the default pattern of chart tags to deploy, which
+	// matches the charts published for every commit.
+	DefaultPattern = "1.0.0-*"
+)
+
+// RegistryEventerType is an Eventer that watches a chart registry for new
+// tags.
+var RegistryEventerType spec.EventerType = "RegistryEventer"
+
+// Config represents the configuration used to create a Registry Eventer.
+type Config struct {
+	// Dependencies.
+	HTTPClient httpspec.Client
+	Logger     micrologger.Logger
+	// StatusSink is where deployment statuses are reported to, as the
+	// registry can not hold them.
+	StatusSink spec.StatusSink
+	// StateStore persists the newest deployed tag of every project across
+	// restarts. It is optional.
+	StateStore state.Store
+	// ProjectLister lists the projects to poll. It is optional, and
+	// ProjectList is used when empty.
+	ProjectLister spec.ProjectLister
+
+	// Settings.
+	API API
+	// BaseURL is the URL of the registry, e.g: https://quay.io.
+	BaseURL      string
+	Organisation string
+	// Password is used to authenticate against the registry, together with
+	// Username. Both are optional for public repositories.
+	Password     string
+	PollInterval time.Duration
+	// PollJitter is the maximum factor of the poll interval added to every
+	// wait, so that replicas do not poll in lockstep.
+	PollJitter float64
+	// PollWorkers is the maximum number of projects polled concurrently.
+	PollWorkers int
+	ProjectList []string
+	// Pattern is the pattern of chart tags to deploy, given as glob or
+	// semver range.
+	Pattern string
+	// ProjectPatterns overrides Pattern for specific projects.
+	ProjectPatterns map[string]string
+	Username        string
+}
+
+// DefaultConfig provides a default configuration to create a new Registry
+// Eventer by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		HTTPClient:    nil,
+		Logger:        nil,
+		StatusSink:    nil,
+		StateStore:    nil,
+		ProjectLister: nil,
+
+		// Settings.
+		API:         CNRAPI,
+		BaseURL:     DefaultBaseURL,
+		Pattern:     DefaultPattern,
+		PollWorkers: 1,
+	}
+}
+
+// New creates a new configured Registry Eventer.
+func New(config Config) (*RegistryEventer, error) {
+	if config.HTTPClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+	if config.StatusSink == nil {
+		return nil, microerror.Maskf(invalidConfigError, "status sink must not be empty")
+	}
+
+	if config.API != OCIAPI && config.API != CNRAPI {
+		return nil, microerror.Maskf(invalidConfigError, "api must be %#q or %#q", OCIAPI, CNRAPI)
+	}
+	if config.BaseURL == "" {
+		return nil, microerror.Maskf(invalidConfigError, "base url must not be empty")
+	}
+	if config.Organisation == "" {
+		return nil, microerror.Maskf(invalidConfigError, "organisation must not be empty")
+	}
+	if config.PollInterval.Seconds() == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "interval must be greater than zero")
+	}
+	if config.ProjectLister == nil && len(config.ProjectList) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "project list must not be empty")
+	}
+
+	defaultMatcher, err := newMatcher(config.Pattern)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "pattern: %s", err.Error())
+	}
+
+	matchers := map[string]matcher{}
+	for project, pattern := range config.ProjectPatterns {
+		m, err := newMatcher(pattern)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "pattern of project %#q: %s", project, err.Error())
+		}
+		matchers[project] = m
+	}
+
+	var projectPoller *poller.Poller
+	{
+		c := poller.DefaultConfig()
+
+		c.Eventer = string(RegistryEventerType)
+		c.Interval = config.PollInterval
+		c.Jitter = config.PollJitter
+		c.Workers = config.PollWorkers
+
+		var err error
+		projectPoller, err = poller.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	eventer := &RegistryEventer{
+		// Dependencies.
+		client:        config.HTTPClient,
+		logger:        config.Logger,
+		poller:        projectPoller,
+		projectLister: config.ProjectLister,
+		statusSink:    config.StatusSink,
+		stateStore:    config.StateStore,
+
+		// Internals.
+		created:        map[string]time.Time{},
+		createdMutex:   &sync.Mutex{},
+		cursors:        map[string]projectCursor{},
+		cursorsMutex:   &sync.Mutex{},
+		defaultMatcher: defaultMatcher,
+		emitted:        map[string]chartTag{},
+		matchers:       matchers,
+		tokens:         map[string]registryauth.Token{},
+		tokensMutex:    &sync.Mutex{},
+
+		// Settings.
+		api:          config.API,
+		baseURL:      strings.TrimSuffix(config.BaseURL, "/"),
+		organisation: config.Organisation,
+		password:     config.Password,
+		pollInterval: config.PollInterval,
+		projectList:  config.ProjectList,
+		username:     config.Username,
+	}
+
+	return eventer, nil
+}
+
+// RegistryEventer is an implementation of the Eventer interface, that
+// watches a chart registry for new tags of the chart of every project.
+// Deployment statuses are reported to a StatusSink.
+type RegistryEventer struct {
+	// Dependencies.
+	client        httpspec.Client
+	logger        micrologger.Logger
+	poller        *poller.Poller
+	projectLister spec.ProjectLister
+	statusSink    spec.StatusSink
+	stateStore    state.Store
+
+	// Internals.
+
+	// created holds the time every tag of OCI repositories was pushed, as it
+	// is not listed together with the tags.
+	created      map[string]time.Time
+	createdMutex *sync.Mutex
+	// cursors holds the cursor of every project polled. Cursors are only
+	// moved once a tag is deployed, so that tags which were emitted, but not
+	// deployed before a restart, are emitted again.
+	cursors      map[string]projectCursor
+	cursorsMutex *sync.Mutex
+	// defaultMatcher matches the tags of projects without a pattern of their
+	// own in matchers.
+	defaultMatcher matcher
+	// emitted holds the newest tag emitted for every project, so that it is
+	// not emitted again while it is being deployed.
+	emitted  map[string]chartTag
+	matchers map[string]matcher
+	// tokens holds the bearer token of every repository, as issued by the
+	// token service of OCI registries, until it expires.
+	tokens      map[string]registryauth.Token
+	tokensMutex *sync.Mutex
+
+	// Settings.
+	api          API
+	baseURL      string
+	organisation string
+	password     string
+	pollInterval time.Duration
+	projectList  []string
+	username     string
+}
+
+func (e *RegistryEventer) NewDeploymentEvents(ctx context.Context) (<-chan spec.DeploymentEvent, error) {
+	e.logger.Log("debug", "starting polling for registry deployment events", "interval", e.pollInterval, "api", e.api)
+
+	deploymentEventChannel := make(chan spec.DeploymentEvent)
+
+	go func() {
+		defer close(deploymentEventChannel)
+
+		e.loadCursors()
+
+		for {
+			projectList := e.projects()
+
+			e.logger.Log("debug", "Fetching deployment events", "projectlist", projectList)
+			e.poller.Each(ctx, projectList, func(project string) {
+				tags, err := e.listTags(project)
+				if err != nil {
+					e.logger.Log("error", "could not fetch deployment events", "message", err.Error())
+					return
+				}
+
+				event, ok := e.deploymentEvent(project, tags)
+				if !ok {
+					return
+				}
+
+				select {
+				case deploymentEventChannel <- event:
+				case <-ctx.Done():
+				}
+			})
+
+			e.saveCursors()
+
+			if !e.poller.Wait(ctx) {
+				e.logger.Log("debug", "stopped polling for registry deployment events")
+				return
+			}
+		}
+	}()
+
+	return deploymentEventChannel, nil
+}
+
+// projects returns the projects to poll, as listed by the project lister, if
+// there is one.
+func (e *RegistryEventer) projects() []string {
+	if e.projectLister != nil {
+		return e.projectLister.ProjectList()
+	}
+
+	return e.projectList
+}
+
+// matcher returns the matcher of the tags of the project.
+func (e *RegistryEventer) matcher(project string) matcher {
+	if m, ok := e.matchers[project]; ok {
+		return m
+	}
+
+	return e.defaultMatcher
+}
+
+func (e *RegistryEventer) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	err := e.statusSink.SetStatus(event, status)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Failed tags are not retried, so the cursor moves past them as well.
+	if status.State.IsFinal() {
+		e.markDeployed(event.Name, event.Version)
+	}
+
+	return nil
+}
+
+// deploymentEvent returns the deployment event for the newest matching tag,
+// if it is newer than the tag deployed last, and not emitted already. The
+// newest tag found when the project is polled for the first time is assumed
+// to be deployed already.
+func (e *RegistryEventer) deploymentEvent(project string, tags []chartTag) (spec.DeploymentEvent, bool) {
+	var matches []chartTag
+	for _, tag := range tags {
+		if e.matcher(project).matches(tag.Name) {
+			matches = append(matches, tag)
+		}
+	}
+
+	e.cursorsMutex.Lock()
+	defer e.cursorsMutex.Unlock()
+
+	cursor, polled := e.cursors[project]
+	if !polled {
+		cursor = projectCursor{Polled: true}
+		if len(matches) > 0 {
+			cursor.Deployed = newestTag(matches)
+		}
+		e.cursors[project] = cursor
+
+		e.logger.Log("debug", "recorded newest existing tag", "project", project, "tag", cursor.Deployed.Name)
+		return spec.DeploymentEvent{}, false
+	}
+	if len(matches) == 0 {
+		return spec.DeploymentEvent{}, false
+	}
+
+	tag := newestTag(matches)
+
+	last := cursor.Deployed
+	if emitted, ok := e.emitted[project]; ok && emitted.newerThan(last) {
+		last = emitted
+	}
+	if last.Name != "" && !tag.newerThan(last) {
+		return spec.DeploymentEvent{}, false
+	}
+
+	e.emitted[project] = tag
+
+	e.logger.Log("debug", "found new deployment events", "project", project, "tag", tag.Name)
+
+	event := spec.DeploymentEvent{
+		ID:   eventID(project, tag.Name),
+		Name: project,
+		Sha:  tagSha(tag.Name),
+
+		Ref:     tag.Name,
+		Version: tag.Name,
+	}
+
+	return event, true
+}
+
+// markDeployed moves the cursor of the project to the given tag, unless a
+// newer tag was deployed already, and persists the cursors.
+func (e *RegistryEventer) markDeployed(project, tagName string) {
+	tag := chartTag{Name: tagName}
+
+	e.cursorsMutex.Lock()
+	if emitted, ok := e.emitted[project]; ok && emitted.Name == tagName {
+		tag = emitted
+	} else if e.api == OCIAPI {
+		e.createdMutex.Lock()
+		tag.Created = e.created[fmt.Sprintf(repositoryFormat, e.organisation, project)+":"+tagName]
+		e.createdMutex.Unlock()
+	}
+
+	if cursor := e.cursors[project]; cursor.Deployed.Name == "" || tag.newerThan(cursor.Deployed) {
+		e.cursors[project] = projectCursor{Deployed: tag, Polled: true}
+	}
+	e.cursorsMutex.Unlock()
+
+	e.saveCursors()
+}
+
+// loadCursors loads the cursors from the state store, if there is one.
+func (e *RegistryEventer) loadCursors() {
+	if e.stateStore == nil {
+		return
+	}
+
+	cursors := make(map[string]projectCursor)
+
+	ok, err := e.stateStore.Load(&cursors)
+	if err != nil {
+		e.logger.Log("error", "could not load eventer state", "message", err.Error())
+		return
+	}
+	if ok {
+		e.logger.Log("debug", "loaded eventer state", "projects", len(cursors))
+	}
+
+	// Cursors of older versions, which persisted empty cursors for projects
+	// without tags, and without marking them as polled, are dropped, so that
+	// their project is polled as if for the first time, instead of deploying
+	// its newest tag again.
+	for project, cursor := range cursors {
+		if cursor.Deployed.Name == "" && !cursor.Polled {
+			delete(cursors, project)
+		}
+	}
+
+	e.cursorsMutex.Lock()
+	e.cursors = cursors
+	e.cursorsMutex.Unlock()
+}
+
+// saveCursors persists the cursors in the state store, if there is one.
+func (e *RegistryEventer) saveCursors() {
+	if e.stateStore == nil {
+		return
+	}
+
+	e.cursorsMutex.Lock()
+	defer e.cursorsMutex.Unlock()
+
+	err := e.stateStore.Save(e.cursors)
+	if err != nil {
+		e.logger.Log("error", "could not save eventer state", "message", err.Error())
+	}
+}
+
+// eventID returns the ID of the deployment event of the given tag. Tags have
+// no ID, so it is derived from the project and tag, which makes it unique
+// across projects polled concurrently, and stable across restarts.
+func eventID(project, tag string) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s@%s", project, tag)
+
+	return int(h.Sum32() & 0x7fffffff)
+}
@@ -0,0 +1,247 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/draughtsman/pkg/registryauth"
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// TestListTags tests listing tags via the OCI distribution API, including
+// the bearer token challenge, and via the CNR API.
+func TestListTags(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/auth":
+			if r.URL.Query().Get("scope") != "repository:giantswarm/api-chart:pull" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"token": "token"}`)
+		case "/v2/giantswarm/api-chart/tags/list":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s/v2/auth",service="registry",scope="repository:giantswarm/api-chart:pull"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"name": "giantswarm/api-chart", "tags": ["1.0.0-12345", "1.0.0-67890"]}`)
+		case "/v2/giantswarm/api-chart/manifests/1.0.0-12345":
+			fmt.Fprint(w, `{"annotations": {"org.opencontainers.image.created": "2020-01-01T00:02:00Z"}}`)
+		case "/v2/giantswarm/api-chart/manifests/1.0.0-67890":
+			fmt.Fprint(w, `{"config": {"digest": "sha256:67890"}}`)
+		case "/v2/giantswarm/api-chart/blobs/sha256:67890":
+			fmt.Fprint(w, `{"created": "2020-01-01T00:01:00Z"}`)
+		case "/cnr/api/v1/packages/giantswarm/api-chart":
+			fmt.Fprint(w, `[{"release": "1.0.0-12345", "created_at": "2020-01-01T00:02:00"}, {"release": "1.0.0-67890", "created_at": "2020-01-01T00:01:00"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	expectedTags := []chartTag{
+		{Name: "1.0.0-12345", Created: time.Date(2020, 1, 1, 0, 2, 0, 0, time.UTC)},
+		{Name: "1.0.0-67890", Created: time.Date(2020, 1, 1, 0, 1, 0, 0, time.UTC)},
+	}
+
+	for _, api := range []API{OCIAPI, CNRAPI} {
+		e := RegistryEventer{
+			client: server.Client(),
+			logger: microloggertest.New(),
+
+			created:      map[string]time.Time{},
+			createdMutex: &sync.Mutex{},
+			tokens:       map[string]registryauth.Token{},
+			tokensMutex:  &sync.Mutex{},
+
+			api:          api,
+			baseURL:      server.URL,
+			organisation: "giantswarm",
+		}
+
+		tags, err := e.listTags("api")
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", api, err)
+		}
+		if !reflect.DeepEqual(expectedTags, tags) {
+			t.Fatalf("%v: expected: %#v\nreturned: %#v\n", api, expectedTags, tags)
+		}
+	}
+}
+
+// TestDeploymentEvent tests that only matching tags newer than the deployed
+// one trigger deployments, and that the cursor only moves once a tag is
+// deployed.
+func TestDeploymentEvent(t *testing.T) {
+	m, err := newMatcher("1.0.0-*")
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	created := func(minutes int) time.Time {
+		return time.Date(2020, 1, 1, 0, minutes, 0, 0, time.UTC)
+	}
+
+	newEventer := func(cursors map[string]projectCursor) *RegistryEventer {
+		return &RegistryEventer{
+			logger:     microloggertest.New(),
+			statusSink: &testSink{},
+
+			cursors:      cursors,
+			cursorsMutex: &sync.Mutex{},
+			emitted:      map[string]chartTag{},
+			matchers:     map[string]matcher{"api": m},
+		}
+	}
+
+	e := newEventer(map[string]projectCursor{})
+
+	old := chartTag{Name: "1.0.0-fedcba", Created: created(1)}
+	other := chartTag{Name: "1.2.0", Created: created(2)}
+	newer := chartTag{Name: "1.0.0-abcdef", Created: created(3)}
+
+	if _, ok := e.deploymentEvent("api", []chartTag{old}); ok {
+		t.Fatalf("expected existing tags not to trigger a deployment")
+	}
+	if _, ok := e.deploymentEvent("api", []chartTag{old, other}); ok {
+		t.Fatalf("expected tags not matching the pattern not to trigger a deployment")
+	}
+
+	event, ok := e.deploymentEvent("api", []chartTag{newer, old, other})
+	if !ok {
+		t.Fatalf("expected new tag to trigger a deployment")
+	}
+	if event.ID != eventID("api", "1.0.0-abcdef") || event.Name != "api" || event.Sha != "abcdef" || event.Version != "1.0.0-abcdef" || event.Ref != "1.0.0-abcdef" {
+		t.Fatalf("expected deployment of new tag, returned: %#v", event)
+	}
+
+	if _, ok := e.deploymentEvent("api", []chartTag{newer, old, other}); ok {
+		t.Fatalf("expected emitted tag not to trigger a deployment again")
+	}
+	if e.cursors["api"].Deployed != old {
+		t.Fatalf("expected cursor not to move before the tag is deployed, returned: %#v", e.cursors["api"])
+	}
+
+	// The tag is emitted again after a restart, as it was not deployed.
+	restarted := newEventer(map[string]projectCursor{"api": e.cursors["api"]})
+	if _, ok := restarted.deploymentEvent("api", []chartTag{newer, old, other}); !ok {
+		t.Fatalf("expected tag not deployed before a restart to trigger a deployment")
+	}
+
+	if err := e.SetStatus(event, spec.DeploymentStatus{State: spec.SuccessState}); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if e.cursors["api"].Deployed != newer {
+		t.Fatalf("expected cursor to move to the deployed tag, returned: %#v", e.cursors["api"])
+	}
+
+	restarted = newEventer(map[string]projectCursor{"api": e.cursors["api"]})
+	if _, ok := restarted.deploymentEvent("api", []chartTag{newer, old, other}); ok {
+		t.Fatalf("expected deployed tag not to trigger a deployment after a restart")
+	}
+}
+
+// TestDeploymentEventWithoutTags tests that the first tag of a project that
+// had no matching tags when it was first polled triggers a deployment, also
+// after a restart.
+func TestDeploymentEventWithoutTags(t *testing.T) {
+	m, err := newMatcher("1.0.0-*")
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	stateStore := &testStateStore{}
+
+	newEventer := func() *RegistryEventer {
+		e := &RegistryEventer{
+			logger:     microloggertest.New(),
+			stateStore: stateStore,
+
+			cursors:      map[string]projectCursor{},
+			cursorsMutex: &sync.Mutex{},
+			emitted:      map[string]chartTag{},
+			matchers:     map[string]matcher{"api": m},
+		}
+		e.loadCursors()
+
+		return e
+	}
+
+	e := newEventer()
+	if _, ok := e.deploymentEvent("api", nil); ok {
+		t.Fatalf("expected project without tags not to trigger a deployment")
+	}
+	e.saveCursors()
+
+	restarted := newEventer()
+	if !restarted.cursors["api"].Polled {
+		t.Fatalf("expected polled project without tags to be kept, returned: %#v", restarted.cursors)
+	}
+	if _, ok := restarted.deploymentEvent("api", []chartTag{{Name: "1.0.0-abcdef"}}); !ok {
+		t.Fatalf("expected first tag to trigger a deployment after a restart")
+	}
+
+	// Empty cursors of older versions are dropped, so that the newest tag is
+	// assumed to be deployed already.
+	stateStore.data = []byte(`{"api": {"deployed": {"name": ""}}}`)
+
+	restarted = newEventer()
+	if _, ok := restarted.deploymentEvent("api", []chartTag{{Name: "1.0.0-abcdef"}}); ok {
+		t.Fatalf("expected existing tag not to trigger a deployment with an old cursor")
+	}
+}
+
+// TestEventID tests that event IDs differ between projects and tags.
+func TestEventID(t *testing.T) {
+	ids := map[int]bool{}
+
+	for _, project := range []string{"api", "web"} {
+		for _, tag := range []string{"1.0.0-12345", "1.0.0-67890"} {
+			id := eventID(project, tag)
+			if id < 0 || ids[id] {
+				t.Fatalf("expected unique positive id for %s@%s, returned: %d", project, tag, id)
+			}
+			ids[id] = true
+		}
+	}
+}
+
+// testStateStore is a state.Store that keeps the state in memory.
+type testStateStore struct {
+	data []byte
+}
+
+func (s *testStateStore) Load(v interface{}) (bool, error) {
+	if s.data == nil {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(s.data, v)
+}
+
+func (s *testStateStore) Save(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.data = data
+
+	return nil
+}
+
+// testSink is a StatusSink discarding all statuses.
+type testSink struct{}
+
+func (s *testSink) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	return nil
+}
@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"time"
+)
+
+// API represents the API used to list chart tags.
+type API string
+
+var (
+	// OCIAPI lists tags via the OCI distribution API, as served by OCI
+	// registries.
+	OCIAPI API = "oci"
+	// CNRAPI lists releases via the CNR API, as served by Quay application
+	// repositories.
+	CNRAPI API = "cnr"
+)
+
+// ociTagList represents the tag list of an OCI repository.
+// See: https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-tags
+type ociTagList struct {
+	// Name is the name of the repository.
+	Name string `json:"name"`
+
+	// Tags are the tags of the repository.
+	Tags []string `json:"tags"`
+}
+
+// ociManifest represents the manifest of an OCI artifact, e.g. a Helm chart.
+// See: https://github.com/opencontainers/image-spec/blob/main/manifest.md
+type ociManifest struct {
+	// Annotations are the annotations of the manifest, which may hold the
+	// creation time of the artifact.
+	Annotations map[string]string `json:"annotations"`
+
+	// Config is the descriptor of the config blob of the artifact.
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+}
+
+// ociConfig represents the config blob of an OCI artifact. Only container
+// images are required to hold their creation time, Helm charts may omit it.
+// See: https://github.com/opencontainers/image-spec/blob/main/config.md
+type ociConfig struct {
+	// Created is the creation time of the artifact.
+	Created string `json:"created"`
+}
+
+// cnrRelease represents a release of a CNR package.
+type cnrRelease struct {
+	// CreatedAt is the time the release was pushed.
+	CreatedAt string `json:"created_at"`
+
+	// Release is the version of the release.
+	Release string `json:"release"`
+}
+
+// chartTag represents a tag of the chart of a project.
+type chartTag struct {
+	// Name is the name of the tag, e.g: 1.0.0-<sha>.
+	Name string `json:"name"`
+
+	// Created is the time the tag was pushed. It is zero if the registry does
+	// not tell.
+	Created time.Time `json:"created"`
+}
+
+// projectCursor represents the state of polling a project.
+type projectCursor struct {
+	// Deployed is the newest tag of the project that was deployed. Matching
+	// tags newer than it trigger a deployment.
+	Deployed chartTag `json:"deployed"`
+
+	// Polled is true once the project was polled for the first time, even if
+	// it had no matching tags, so that the first tag pushed afterwards
+	// triggers a deployment after a restart, too.
+	Polled bool `json:"polled"`
+}
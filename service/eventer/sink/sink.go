@@ -0,0 +1,97 @@
+package sink
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+)
+
+// Type represents the type of StatusSink to configure.
+type Type string
+
+var (
+	// LogSinkType is a StatusSink that logs statuses.
+	LogSinkType Type = "log"
+	// WebhookSinkType is a StatusSink that posts statuses to a webhook.
+	WebhookSinkType Type = "webhook"
+)
+
+// Config represents the configuration used to create a StatusSink.
+type Config struct {
+	// Dependencies.
+	HTTPClient httpspec.Client
+	Logger     micrologger.Logger
+
+	// Settings.
+	Type Type
+	// WebhookURL is the URL statuses are posted to by the webhook sink.
+	WebhookURL string
+}
+
+// DefaultConfig provides a default configuration to create a new StatusSink
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		HTTPClient: nil,
+		Logger:     nil,
+
+		// Settings.
+		Type: LogSinkType,
+	}
+}
+
+// New creates a new configured StatusSink.
+func New(config Config) (spec.StatusSink, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	switch config.Type {
+	case LogSinkType:
+		return &LogSink{
+			logger: config.Logger,
+		}, nil
+
+	case WebhookSinkType:
+		if config.HTTPClient == nil {
+			return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+		}
+		if config.WebhookURL == "" {
+			return nil, microerror.Maskf(invalidConfigError, "webhook url must not be empty")
+		}
+
+		return &WebhookSink{
+			client: config.HTTPClient,
+			logger: config.Logger,
+
+			webhookURL: config.WebhookURL,
+		}, nil
+
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "status sink type not implemented")
+	}
+}
+
+// LogSink is an implementation of the StatusSink interface, that only logs
+// statuses.
+type LogSink struct {
+	// Dependencies.
+	logger micrologger.Logger
+}
+
+func (s *LogSink) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	s.logger.Log(
+		"debug", "deployment status",
+		"project", event.Name,
+		"id", event.ID,
+		"version", event.Version,
+		"state", status.State,
+		"description", status.Description,
+		"logURL", status.LogURL,
+	)
+
+	return nil
+}
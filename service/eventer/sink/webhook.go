@@ -0,0 +1,90 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+)
+
+// webhookPayload is the body posted to the webhook for every status.
+type webhookPayload struct {
+	// Project is the name of the deployed project.
+	Project string `json:"project"`
+
+	// ID is the ID of the deployment event.
+	ID int `json:"id"`
+
+	// Sha is the sha of the deployed chart.
+	Sha string `json:"sha"`
+
+	// Version is the version of the deployed chart.
+	Version string `json:"version,omitempty"`
+
+	// State is the state of the deployment.
+	State spec.DeploymentState `json:"state"`
+
+	// Description is a description of the state.
+	Description string `json:"description,omitempty"`
+
+	// EnvironmentURL is the URL of the deployed environment.
+	EnvironmentURL string `json:"environmentURL,omitempty"`
+
+	// LogURL is the URL of the output of the deployment.
+	LogURL string `json:"logURL,omitempty"`
+}
+
+// WebhookSink is an implementation of the StatusSink interface, that posts
+// statuses as JSON to a webhook.
+type WebhookSink struct {
+	// Dependencies.
+	client httpspec.Client
+	logger micrologger.Logger
+
+	// Settings.
+	webhookURL string
+}
+
+func (s *WebhookSink) SetStatus(event spec.DeploymentEvent, status spec.DeploymentStatus) error {
+	s.logger.Log("debug", "posting deployment status to webhook", "project", event.Name, "id", event.ID, "state", status.State)
+
+	payload := webhookPayload{
+		Project: event.Name,
+		ID:      event.ID,
+		Sha:     event.Sha,
+		Version: event.Version,
+
+		State:          status.State,
+		Description:    status.Description,
+		EnvironmentURL: status.EnvironmentURL,
+		LogURL:         status.LogURL,
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	req, err := http.NewRequest("POST", s.webhookURL, bytes.NewReader(b))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return microerror.Maskf(unexpectedStatusCode, "received status code: %v", resp.StatusCode)
+	}
+
+	return nil
+}
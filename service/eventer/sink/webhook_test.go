@@ -0,0 +1,57 @@
+package sink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+
+	"github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// TestWebhookSink tests posting statuses to a webhook.
+func TestWebhookSink(t *testing.T) {
+	var payloads []webhookPayload
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payloads = append(payloads, payload)
+
+		if payload.State == spec.FailureState {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}))
+	defer server.Close()
+
+	c := DefaultConfig()
+	c.HTTPClient = server.Client()
+	c.Logger = microloggertest.New()
+	c.Type = WebhookSinkType
+	c.WebhookURL = server.URL
+
+	s, err := New(c)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+
+	event := spec.DeploymentEvent{ID: 1, Name: "api", Sha: "12345", Version: "1.0.0-12345"}
+
+	if err := s.SetStatus(event, spec.DeploymentStatus{State: spec.SuccessState, Description: "Deployed"}); err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if err := s.SetStatus(event, spec.DeploymentStatus{State: spec.FailureState}); !IsUnexpectedStatusCode(err) {
+		t.Fatalf("expected unexpected status code error, returned: %#v", err)
+	}
+
+	expectedPayload := webhookPayload{Project: "api", ID: 1, Sha: "12345", Version: "1.0.0-12345", State: spec.SuccessState, Description: "Deployed"}
+	if len(payloads) != 2 || payloads[0] != expectedPayload {
+		t.Fatalf("expected: %#v\nreturned: %#v\n", expectedPayload, payloads)
+	}
+}
@@ -1,5 +1,16 @@
 package spec
 
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// shaVersionFormat is the format of chart versions built for a sha.
+	shaVersionFormat = "1.0.0-%v"
+)
+
 // EventerType represents the type of Eventer to configure.
 type EventerType string
 
@@ -7,16 +18,37 @@ type EventerType string
 type Eventer interface {
 	// NewDeploymentEvents returns a channel of DeploymentEvents.
 	// This channel can be ranged over to receive DeploymentEvents as they come
-	// in.
+	// in. It is closed once the context is cancelled.
 	// In case of error during setup, the error will be non-nil.
-	NewDeploymentEvents() (<-chan DeploymentEvent, error)
+	NewDeploymentEvents(ctx context.Context) (<-chan DeploymentEvent, error)
 
-	// SetPending updates the DeploymentEvent remote state to a pending state.
-	SetPending(DeploymentEvent) error
-	// SetSuccess updates the DeploymentEvent remote state to a success state.
-	SetSuccess(DeploymentEvent) error
-	// SetFailed updates the DeploymentEvent remote state to a failed state.
-	SetFailed(DeploymentEvent) error
+	// SetStatus updates the DeploymentEvent remote state to the given
+	// status. Eventers that do not support a state, or any of the optional
+	// fields of the status, fall back to the closest thing they support.
+	SetStatus(DeploymentEvent, DeploymentStatus) error
+}
+
+// WebhookHandler represents an Eventer that can additionally receive
+// deployment events pushed to it via HTTP webhooks.
+type WebhookHandler interface {
+	// HandleWebhook processes a single webhook delivery, given the request
+	// headers and the raw request body. Deliveries that are not relevant to
+	// the Eventer are ignored, and do not result in an error.
+	HandleWebhook(ctx context.Context, header http.Header, body []byte) error
+}
+
+// StatusSink represents a Service that DeploymentStatuses are reported to,
+// for Eventers whose backend can not hold them.
+type StatusSink interface {
+	// SetStatus reports the status of the DeploymentEvent.
+	SetStatus(DeploymentEvent, DeploymentStatus) error
+}
+
+// ProjectLister represents a Service that lists the projects to deploy. The
+// list may change over time, so it should be listed again when used.
+type ProjectLister interface {
+	// ProjectList returns the names of the projects to deploy.
+	ProjectList() []string
 }
 
 // DeploymentEvent represents a request for a chart to be deployed.
@@ -29,4 +61,102 @@ type DeploymentEvent struct {
 
 	// Sha is the version of the chart to deploy.
 	Sha string
+
+	// Creator is the name of the user who requested the deployment. It is
+	// optional.
+	Creator string
+
+	// Description is a description of the deployment given by its creator.
+	// It is optional.
+	Description string
+
+	// Payload holds additional options for the deployment.
+	Payload DeploymentPayload
+
+	// Ref is the name of the branch or tag the deployment was requested
+	// for. It is optional, and Sha is authoritative.
+	Ref string
+
+	// Task is the task to perform. It defaults to DeployTask when empty.
+	Task DeploymentTask
+
+	// Version is the version of the chart to deploy. It is optional, and
+	// defaults to the version the chart of Sha is published with.
+	Version string
+
+	// Eventer is the type of the Eventer that produced the event, when
+	// multiple Eventers are used, so that statuses are set there.
+	Eventer EventerType
+}
+
+// ChartVersion returns the chart version to install for the event. Charts
+// are versioned by sha, unless the eventer found a specific chart version.
+func (e DeploymentEvent) ChartVersion() string {
+	if e.Version != "" {
+		return e.Version
+	}
+
+	return fmt.Sprintf(shaVersionFormat, e.Sha)
+}
+
+// DeploymentPayload represents the options a deployment can request.
+type DeploymentPayload struct {
+	// DryRun requests the deployment to be simulated without being applied.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Namespace is the namespace to install the chart to. It overrides the
+	// default namespace when set.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Values are additional chart values, given as Helm --set arguments,
+	// e.g: {"image.tag": "1.2.3"}.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// DeploymentTask represents the task a deployment requests.
+type DeploymentTask string
+
+var (
+	// DeployTask installs or upgrades the chart.
+	DeployTask DeploymentTask = "deploy"
+	// RollbackTask rolls the release back to its previous revision.
+	RollbackTask DeploymentTask = "rollback"
+)
+
+// DeploymentState represents the state of a deployment.
+type DeploymentState string
+
+var (
+	// QueuedState is the state of deployments waiting to be installed.
+	QueuedState DeploymentState = "queued"
+	// InProgressState is the state of deployments being installed.
+	InProgressState DeploymentState = "in_progress"
+	// PendingState is the state of deployments that were accepted.
+	PendingState DeploymentState = "pending"
+	// SuccessState is the state of deployments that were installed.
+	SuccessState DeploymentState = "success"
+	// FailureState is the state of deployments that could not be installed.
+	FailureState DeploymentState = "failure"
+)
+
+// IsFinal returns true if no further status follows the state.
+func (s DeploymentState) IsFinal() bool {
+	return s == SuccessState || s == FailureState
+}
+
+// DeploymentStatus represents the status of a deployment.
+type DeploymentStatus struct {
+	// State is the state of the deployment.
+	State DeploymentState
+
+	// Description is a human readable description of the status, e.g. the
+	// error that made the deployment fail. Eventers may truncate it.
+	Description string
+
+	// EnvironmentURL is the URL of the environment that was deployed to. It
+	// is optional.
+	EnvironmentURL string
+
+	// LogURL is the URL of the details of the deployment. It is optional.
+	LogURL string
 }
@@ -0,0 +1,33 @@
+package spec
+
+import (
+	"testing"
+)
+
+// TestChartVersion tests the ChartVersion method.
+func TestChartVersion(t *testing.T) {
+	tests := []struct {
+		event           DeploymentEvent
+		expectedVersion string
+	}{
+		{
+			event:           DeploymentEvent{Sha: "12345"},
+			expectedVersion: "1.0.0-12345",
+		},
+		{
+			event:           DeploymentEvent{Sha: "12345", Version: "1.2.3"},
+			expectedVersion: "1.2.3",
+		},
+	}
+
+	for index, test := range tests {
+		returnedVersion := test.event.ChartVersion()
+
+		if returnedVersion != test.expectedVersion {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedVersion, returnedVersion,
+			)
+		}
+	}
+}
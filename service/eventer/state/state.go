@@ -0,0 +1,192 @@
+// Package state persists eventer state, like the cursors of pollers, across
+// restarts of draughtsman.
+package state
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Store persists eventer state.
+type Store interface {
+	// Load decodes the stored state into v. It returns false if no state has
+	// been stored yet.
+	Load(v interface{}) (bool, error)
+	// Save stores v as the new state.
+	Save(v interface{}) error
+}
+
+// Config represents the configuration used to create a ConfigMap Store.
+type Config struct {
+	// Dependencies.
+	KubernetesClient kubernetes.Interface
+	Logger           micrologger.Logger
+
+	// Settings.
+
+	// Key is the key to reference the state in the configmap, so that
+	// several eventers can share a configmap.
+	Key       string
+	Name      string
+	Namespace string
+}
+
+// DefaultConfig provides a default configuration to create a new ConfigMap
+// Store by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		KubernetesClient: nil,
+		Logger:           nil,
+
+		// Settings.
+		Key:       "",
+		Name:      "",
+		Namespace: "",
+	}
+}
+
+// New creates a new configured ConfigMap Store.
+func New(config Config) (*ConfigMapStore, error) {
+	// Dependencies.
+	if config.KubernetesClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "kubernetes client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	// Settings.
+	if config.Key == "" {
+		return nil, microerror.Maskf(invalidConfigError, "key must not be empty")
+	}
+	if config.Name == "" {
+		return nil, microerror.Maskf(invalidConfigError, "name must not be empty")
+	}
+	if config.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "namespace must not be empty")
+	}
+
+	store := &ConfigMapStore{
+		// Dependencies.
+		kubernetesClient: config.KubernetesClient,
+		logger:           config.Logger,
+
+		// Internals.
+		mutex: &sync.Mutex{},
+
+		// Settings.
+		key:       config.Key,
+		name:      config.Name,
+		namespace: config.Namespace,
+	}
+
+	return store, nil
+}
+
+// ConfigMapStore is an implementation of the Store interface, that keeps
+// state as JSON in a Kubernetes ConfigMap.
+type ConfigMapStore struct {
+	// Dependencies.
+	kubernetesClient kubernetes.Interface
+	logger           micrologger.Logger
+
+	// Internals.
+
+	// saved is the last data that was read or written, used to skip writes
+	// when the state did not change.
+	saved string
+	mutex *sync.Mutex
+
+	// Settings.
+	key       string
+	name      string
+	namespace string
+}
+
+func (s *ConfigMapStore) Load(v interface{}) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.logger.Log("debug", "loading eventer state from configmap", "name", s.name, "namespace", s.namespace)
+
+	cm, err := s.kubernetesClient.CoreV1().ConfigMaps(s.namespace).Get(s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	data, ok := cm.Data[s.key]
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	s.saved = data
+
+	return true, nil
+}
+
+func (s *ConfigMapStore) Save(v interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	data := string(b)
+
+	if data == s.saved {
+		return nil
+	}
+
+	s.logger.Log("debug", "saving eventer state to configmap", "name", s.name, "namespace", s.namespace)
+
+	configMaps := s.kubernetesClient.CoreV1().ConfigMaps(s.namespace)
+
+	cm, err := configMaps.Get(s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+			},
+			Data: map[string]string{
+				s.key: data,
+			},
+		}
+
+		_, err = configMaps.Create(cm)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[s.key] = data
+
+		_, err = configMaps.Update(cm)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	s.saved = data
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestConfigMapStore tests that state saved by one store is loaded by
+// another, as after a restart.
+func TestConfigMapStore(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset()
+
+	newStore := func() *ConfigMapStore {
+		c := DefaultConfig()
+
+		c.KubernetesClient = k8sClient
+		c.Logger = microloggertest.New()
+
+		c.Key = "github"
+		c.Name = "draughtsman-eventer-state"
+		c.Namespace = "draughtsman"
+
+		store, err := New(c)
+		if err != nil {
+			t.Fatalf("expected nil error, returned: %#v", err)
+		}
+
+		return store
+	}
+
+	var loaded map[string]int
+
+	ok, err := newStore().Load(&loaded)
+	if err != nil {
+		t.Fatalf("expected nil error, returned: %#v", err)
+	}
+	if ok {
+		t.Fatalf("expected no state to be loaded")
+	}
+
+	store := newStore()
+	for _, saved := range []map[string]int{{"api": 1}, {"api": 2, "kvm-operator": 3}} {
+		if err := store.Save(saved); err != nil {
+			t.Fatalf("expected nil error, returned: %#v", err)
+		}
+
+		ok, err := newStore().Load(&loaded)
+		if err != nil {
+			t.Fatalf("expected nil error, returned: %#v", err)
+		}
+		if !ok || !reflect.DeepEqual(saved, loaded) {
+			t.Fatalf("expected: %#v\nreturned: %#v\n", saved, loaded)
+		}
+	}
+}
@@ -14,6 +14,8 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
 )
 
 const (
@@ -26,7 +28,7 @@ type Config struct {
 	Logger           micrologger.Logger
 
 	HelmBinaryPath string
-	ProjectList    []string
+	ProjectLister  eventerspec.ProjectLister
 	Repository     string
 }
 
@@ -34,9 +36,9 @@ type HelmMigration struct {
 	kubernetesClient kubernetes.Interface
 	logger           micrologger.Logger
 
-	helmBinary  string
-	repository  string
-	projectList []string
+	helmBinary    string
+	repository    string
+	projectLister eventerspec.ProjectLister
 }
 
 func New(c Config) (*HelmMigration, error) {
@@ -53,17 +55,17 @@ func New(c Config) (*HelmMigration, error) {
 	if c.Repository == "" {
 		return nil, microerror.Maskf(invalidConfigError, "repository must not be empty")
 	}
-	if c.ProjectList == nil {
-		return nil, microerror.Maskf(invalidConfigError, "projectList must not be empty")
+	if c.ProjectLister == nil {
+		return nil, microerror.Maskf(invalidConfigError, "projectLister must not be empty")
 	}
 
 	h := HelmMigration{
 		kubernetesClient: c.KubernetesClient,
 		logger:           c.Logger,
 
-		repository:  c.Repository,
-		helmBinary:  c.HelmBinaryPath,
-		projectList: c.ProjectList,
+		repository:    c.Repository,
+		helmBinary:    c.HelmBinaryPath,
+		projectLister: c.ProjectLister,
 	}
 
 	return &h, nil
@@ -248,7 +250,7 @@ func (h *HelmMigration) installHelm2to3Migration(projectList []string) error {
 
 func (h *HelmMigration) listRemainingHelmRelease() ([]string, error) {
 	var remaining []string
-	for _, project := range h.projectList {
+	for _, project := range h.projectLister.ProjectList() {
 		lo := metav1.ListOptions{
 			LabelSelector: fmt.Sprintf("OWNER=TILLER,NAME=%s", project),
 		}
@@ -0,0 +1,68 @@
+package chartcache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/afero"
+)
+
+// archive archives the chart directory as gzipped tarball, as installed by
+// Helm. Paths in the archive start with the name of the directory. Files are
+// archived in lexical order without modification times, so that equal
+// charts have equal digests.
+func archive(fileSystem afero.Fs, dir string) ([]byte, error) {
+	var paths []string
+	err := afero.Walk(fileSystem, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	for _, path := range paths {
+		b, err := afero.ReadFile(fileSystem, path)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		name, err := filepath.Rel(filepath.Dir(dir), path)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		err = tw.WriteHeader(&tar.Header{Name: filepath.ToSlash(name), Mode: 0644, Size: int64(len(b))})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if _, err := tw.Write(b); err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return buf.Bytes(), nil
+}
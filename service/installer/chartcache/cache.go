@@ -0,0 +1,376 @@
+// Package chartcache caches pulled charts on disk, so that re-deploys and
+// retries of a chart version do not pull it again.
+package chartcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
+
+	"github.com/giantswarm/draughtsman/service/installer/chartsource"
+)
+
+const (
+	// blobsDirectory is the directory of the cache holding the chart
+	// archives, named by their SHA-256 digest.
+	blobsDirectory = "blobs"
+
+	// indexFileName is the name of the file holding the index of the cache.
+	indexFileName = "index.json"
+)
+
+// Config represents the configuration used to create a Cache.
+type Config struct {
+	// Dependencies.
+	FileSystem afero.Fs
+	Logger     micrologger.Logger
+
+	// Settings.
+
+	// Directory is the directory the cache is kept in, e.g:
+	// /var/cache/draughtsman/charts. The cache survives restarts if the
+	// directory does.
+	Directory string
+	// MaxSize is the maximum size of the cached chart archives, in bytes.
+	// Least recently used charts are evicted beyond it.
+	MaxSize int64
+}
+
+// DefaultConfig provides a default configuration to create a new Cache by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		FileSystem: afero.NewMemMapFs(),
+		Logger:     nil,
+
+		// Settings.
+		Directory: "",
+		MaxSize:   512 * 1024 * 1024,
+	}
+}
+
+// New creates a new configured Cache, loading the index of the cache kept in
+// the directory, if there is one.
+func New(config Config) (*Cache, error) {
+	// Dependencies.
+	if config.FileSystem == nil {
+		return nil, microerror.Maskf(invalidConfigError, "file system must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	// Settings.
+	if config.Directory == "" {
+		return nil, microerror.Maskf(invalidConfigError, "directory must not be empty")
+	}
+	if config.MaxSize <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "max size must be greater than zero")
+	}
+
+	err := config.FileSystem.MkdirAll(filepath.Join(config.Directory, blobsDirectory), os.FileMode(0755))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	cache := &Cache{
+		// Dependencies.
+		fileSystem: config.FileSystem,
+		logger:     config.Logger,
+
+		// Internals.
+		entries: map[string]entry{},
+		mutex:   &sync.Mutex{},
+
+		// Settings.
+		directory: config.Directory,
+		maxSize:   config.MaxSize,
+	}
+
+	cache.loadIndex()
+
+	return cache, nil
+}
+
+// Cache is a content-addressed cache of chart archives on disk, keyed by
+// project and version. Archives are verified against their digest when they
+// are read, and the least recently used charts are evicted when the cache
+// grows beyond its maximum size.
+type Cache struct {
+	// Dependencies.
+	fileSystem afero.Fs
+	logger     micrologger.Logger
+
+	// Internals.
+
+	// entries holds the cached charts, by key.
+	entries map[string]entry
+	// lastUsed is the sequence number of the last use of a cached chart.
+	lastUsed uint64
+	mutex    *sync.Mutex
+
+	// Settings.
+	directory string
+	maxSize   int64
+}
+
+// entry represents a cached chart.
+type entry struct {
+	// Digest is the hex encoded SHA-256 digest of the chart archive.
+	Digest string `json:"digest"`
+	// Reference is the chart reference the chart was pulled from. Charts are
+	// pulled again if the reference of the project changed.
+	Reference string `json:"reference"`
+	Size      int64  `json:"size"`
+	// Used is the sequence number of the last use of the chart.
+	Used uint64 `json:"used"`
+}
+
+// Get copies the cached archive of the chart into the given dir, and returns
+// its path. It returns false if the chart is not cached, or if the cached
+// archive does not match its digest, in which case it is evicted.
+func (c *Cache) Get(chart chartsource.Chart, dir string) (string, bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := cacheKey(chart)
+
+	e, ok := c.entries[key]
+	if !ok || e.Reference != chart.Reference {
+		cacheMisses.Inc()
+		return "", false, nil
+	}
+
+	b, err := afero.ReadFile(c.fileSystem, c.blobPath(e.Digest))
+	if err != nil && !os.IsNotExist(err) {
+		return "", false, microerror.Mask(err)
+	}
+	if err != nil || digest(b) != e.Digest {
+		c.logger.Log("error", "evicting corrupted chart from cache", "project", chart.Project, "version", chart.Version, "digest", e.Digest)
+
+		delete(c.entries, key)
+		c.removeBlob(e.Digest)
+		c.saveIndex()
+
+		cacheMisses.Inc()
+		return "", false, nil
+	}
+
+	chartPath := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", chart.Project, chart.Version))
+	err = afero.WriteFile(c.fileSystem, chartPath, b, os.FileMode(0644))
+	if err != nil {
+		return "", false, microerror.Mask(err)
+	}
+
+	c.lastUsed++
+	e.Used = c.lastUsed
+	c.entries[key] = e
+	c.saveIndex()
+
+	cacheHits.Inc()
+	return chartPath, true, nil
+}
+
+// Put caches the chart pulled to the given path. Chart directories are
+// archived. Least recently used charts are evicted afterwards, if the cache
+// grew beyond its maximum size.
+func (c *Cache) Put(chart chartsource.Chart, chartPath string) error {
+	b, err := c.read(chartPath)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	d := digest(b)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Archives are written to a temporary file first, and renamed, so that
+	// blobs are never read partially written. Existing blobs are replaced, in
+	// case they got corrupted.
+	{
+		blobPath := c.blobPath(d)
+		tmpPath := blobPath + ".tmp"
+
+		err := afero.WriteFile(c.fileSystem, tmpPath, b, os.FileMode(0644))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		err = c.fileSystem.Rename(tmpPath, blobPath)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	key := cacheKey(chart)
+	if old, ok := c.entries[key]; ok && old.Digest != d {
+		delete(c.entries, key)
+		c.removeBlob(old.Digest)
+	}
+
+	c.lastUsed++
+	c.entries[key] = entry{
+		Digest:    d,
+		Reference: chart.Reference,
+		Size:      int64(len(b)),
+		Used:      c.lastUsed,
+	}
+
+	c.evict()
+	c.saveIndex()
+
+	return nil
+}
+
+// read reads the chart archive at the given path, or archives the chart
+// directory at the given path.
+func (c *Cache) read(chartPath string) ([]byte, error) {
+	fi, err := c.fileSystem.Stat(chartPath)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if fi.IsDir() {
+		b, err := archive(c.fileSystem, chartPath)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return b, nil
+	}
+
+	b, err := afero.ReadFile(c.fileSystem, chartPath)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
+
+// evict evicts the least recently used charts, until the size of the cached
+// archives is within the maximum size. Archives are shared by the charts
+// of equal content, so they are only counted, and removed, once.
+func (c *Cache) evict() {
+	var keys []string
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].Used < c.entries[keys[j]].Used
+	})
+
+	for _, key := range keys {
+		size := c.size()
+		if size <= c.maxSize {
+			break
+		}
+
+		e := c.entries[key]
+		delete(c.entries, key)
+		c.removeBlob(e.Digest)
+
+		c.logger.Log("debug", "evicted chart from cache", "key", key, "size", size)
+	}
+
+	cacheSize.Set(float64(c.size()))
+}
+
+// size returns the size of the cached archives.
+func (c *Cache) size() int64 {
+	var size int64
+
+	counted := map[string]bool{}
+	for _, e := range c.entries {
+		if counted[e.Digest] {
+			continue
+		}
+
+		size += e.Size
+		counted[e.Digest] = true
+	}
+
+	return size
+}
+
+// removeBlob removes the archive of the given digest, unless it is still
+// used by cached charts.
+func (c *Cache) removeBlob(d string) {
+	for _, e := range c.entries {
+		if e.Digest == d {
+			return
+		}
+	}
+
+	err := c.fileSystem.Remove(c.blobPath(d))
+	if err != nil && !os.IsNotExist(err) {
+		c.logger.Log("error", "could not remove chart from cache", "digest", d, "message", err.Error())
+	}
+}
+
+// loadIndex loads the index of the cache, if there is one. Caches with an
+// unreadable index are started empty.
+func (c *Cache) loadIndex() {
+	b, err := afero.ReadFile(c.fileSystem, filepath.Join(c.directory, indexFileName))
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		c.logger.Log("error", "could not read chart cache index", "message", err.Error())
+		return
+	}
+
+	entries := map[string]entry{}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		c.logger.Log("error", "could not parse chart cache index", "message", err.Error())
+		return
+	}
+
+	for _, e := range entries {
+		if e.Used > c.lastUsed {
+			c.lastUsed = e.Used
+		}
+	}
+	c.entries = entries
+
+	c.logger.Log("debug", "loaded chart cache index", "charts", len(entries))
+
+	c.evict()
+}
+
+// saveIndex saves the index of the cache.
+func (c *Cache) saveIndex() {
+	b, err := json.Marshal(c.entries)
+	if err != nil {
+		c.logger.Log("error", "could not encode chart cache index", "message", err.Error())
+		return
+	}
+
+	err = afero.WriteFile(c.fileSystem, filepath.Join(c.directory, indexFileName), b, os.FileMode(0644))
+	if err != nil {
+		c.logger.Log("error", "could not write chart cache index", "message", err.Error())
+	}
+}
+
+func (c *Cache) blobPath(d string) string {
+	return filepath.Join(c.directory, blobsDirectory, d)
+}
+
+// cacheKey returns the key of the chart in the cache.
+func cacheKey(chart chartsource.Chart) string {
+	return chart.Project + "@" + chart.Version
+}
+
+// digest returns the hex encoded SHA-256 digest of the content.
+func digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,199 @@
+package chartcache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+
+	"github.com/giantswarm/draughtsman/service/installer/chartsource"
+)
+
+func newTestCache(t *testing.T, fileSystem afero.Fs, maxSize int64) *Cache {
+	c := DefaultConfig()
+
+	c.FileSystem = fileSystem
+	c.Logger = microloggertest.New()
+
+	c.Directory = "/cache"
+	c.MaxSize = maxSize
+
+	cache, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cache
+}
+
+// TestCache tests that charts are cached, and the least recently used charts
+// are evicted beyond the maximum size.
+func TestCache(t *testing.T) {
+	fileSystem := afero.NewMemMapFs()
+	cache := newTestCache(t, fileSystem, 20)
+
+	charts := map[string]chartsource.Chart{
+		"a": {Project: "api", Reference: "oci://quay.io/giantswarm/api-chart:1.0.0-a", Version: "1.0.0-a"},
+		"b": {Project: "api", Reference: "oci://quay.io/giantswarm/api-chart:1.0.0-b", Version: "1.0.0-b"},
+		"c": {Project: "web", Reference: "oci://quay.io/giantswarm/web-chart:1.0.0-c", Version: "1.0.0-c"},
+	}
+	for name := range charts {
+		err := afero.WriteFile(fileSystem, "/pull/"+name, []byte("chart "+name), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		put            string
+		get            string
+		expectedCached bool
+	}{
+		{put: "a", get: "a", expectedCached: true},
+		{put: "b", get: "b", expectedCached: true},
+		// Using a makes b the least recently used chart.
+		{get: "a", expectedCached: true},
+		// Caching c exceeds the maximum size, so b is evicted.
+		{put: "c", get: "b", expectedCached: false},
+		{get: "a", expectedCached: true},
+		{get: "c", expectedCached: true},
+	}
+
+	for index, test := range tests {
+		if test.put != "" {
+			err := cache.Put(charts[test.put], "/pull/"+test.put)
+			if err != nil {
+				t.Fatalf("%v: unexpected error: %#v", index, err)
+			}
+		}
+
+		chartPath, cached, err := cache.Get(charts[test.get], "/install")
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %#v", index, err)
+		}
+		if cached != test.expectedCached {
+			t.Fatalf("%v\nexpected cached: %#v\nreturned cached: %#v\n", index, test.expectedCached, cached)
+		}
+		if !cached {
+			continue
+		}
+
+		b, err := afero.ReadFile(fileSystem, chartPath)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %#v", index, err)
+		}
+		if string(b) != "chart "+test.get {
+			t.Fatalf("%v\nexpected: %q\nreturned: %q\n", index, "chart "+test.get, b)
+		}
+	}
+
+	// Test that the cache is loaded from the directory again.
+	cache = newTestCache(t, fileSystem, 20)
+
+	if _, cached, _ := cache.Get(charts["c"], "/install"); !cached {
+		t.Fatalf("expected chart to be cached after reload")
+	}
+
+	// Test that charts of another reference are pulled again.
+	moved := charts["c"]
+	moved.Reference = "https://giantswarm.github.io/charts/web-chart"
+	if _, cached, _ := cache.Get(moved, "/install"); cached {
+		t.Fatalf("expected chart of another reference not to be cached")
+	}
+}
+
+// TestCacheCorrupted tests that corrupted charts are evicted.
+func TestCacheCorrupted(t *testing.T) {
+	fileSystem := afero.NewMemMapFs()
+	cache := newTestCache(t, fileSystem, 1024)
+
+	chart := chartsource.Chart{Project: "api", Version: "1.0.0-a"}
+
+	if err := afero.WriteFile(fileSystem, "/pull/chart.tgz", []byte("chart"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Put(chart, "/pull/chart.tgz"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := afero.WriteFile(fileSystem, cache.blobPath(digest([]byte("chart"))), []byte("corrupted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cached, err := cache.Get(chart, "/install")
+	if err != nil {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if cached {
+		t.Fatalf("expected corrupted chart not to be cached")
+	}
+
+	if _, err := fileSystem.Stat(cache.blobPath(digest([]byte("chart")))); err == nil {
+		t.Fatalf("expected corrupted chart to be removed")
+	}
+}
+
+// TestCacheDirectory tests that chart directories are archived.
+func TestCacheDirectory(t *testing.T) {
+	fileSystem := afero.NewMemMapFs()
+	cache := newTestCache(t, fileSystem, 1024)
+
+	chart := chartsource.Chart{Project: "api", Version: "1.0.0-a"}
+
+	files := map[string]string{
+		"/pull/giantswarm_api-chart_1.0.0-a/api-chart/Chart.yaml":            "name: api-chart\n",
+		"/pull/giantswarm_api-chart_1.0.0-a/api-chart/templates/config.yaml": "kind: ConfigMap\n",
+	}
+	for path, content := range files {
+		if err := afero.WriteFile(fileSystem, path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cache.Put(chart, "/pull/giantswarm_api-chart_1.0.0-a/api-chart"); err != nil {
+		t.Fatal(err)
+	}
+
+	chartPath, cached, err := cache.Get(chart, "/install")
+	if err != nil || !cached {
+		t.Fatalf("expected chart to be cached, returned error: %#v", err)
+	}
+
+	b, err := afero.ReadFile(fileSystem, chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+
+	archived := map[string]string{}
+	for {
+		h, err := tr.Next()
+		if err != nil {
+			break
+		}
+		content, _ := ioutil.ReadAll(tr)
+		archived[h.Name] = string(content)
+	}
+
+	expected := map[string]string{
+		"api-chart/Chart.yaml":            "name: api-chart\n",
+		"api-chart/templates/config.yaml": "kind: ConfigMap\n",
+	}
+	for name, content := range expected {
+		if archived[name] != content {
+			t.Fatalf("expected %#q to be archived as %q, returned %q", name, content, archived[name])
+		}
+	}
+	if len(archived) != len(expected) {
+		t.Fatalf("expected %d archived files, returned %d", len(expected), len(archived))
+	}
+}
@@ -0,0 +1,50 @@
+package chartsource
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var digestMismatchError = &microerror.Error{
+	Kind: "digestMismatchError",
+}
+
+// IsDigestMismatch asserts digestMismatchError.
+func IsDigestMismatch(err error) bool {
+	return microerror.Cause(err) == digestMismatchError
+}
+
+var invalidReferenceError = &microerror.Error{
+	Kind: "invalidReferenceError",
+}
+
+// IsInvalidReference asserts invalidReferenceError.
+func IsInvalidReference(err error) bool {
+	return microerror.Cause(err) == invalidReferenceError
+}
+
+var notFoundError = &microerror.Error{
+	Kind: "notFoundError",
+}
+
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
+}
+
+var pullFailedError = &microerror.Error{
+	Kind: "pullFailedError",
+}
+
+// IsPullFailed asserts pullFailedError.
+func IsPullFailed(err error) bool {
+	return microerror.Cause(err) == pullFailedError
+}
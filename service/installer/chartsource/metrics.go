@@ -0,0 +1,50 @@
+package chartsource
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// prometheusNamespace is the namespace to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusNamespace = "draughtsman"
+
+	// prometheusSubsystem is the subsystem to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusSubsystem = "chart_source"
+)
+
+var (
+	pullDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "pull_duration_milliseconds",
+			Help:      "Time taken to pull charts.",
+		},
+		[]string{"source"},
+	)
+	pullTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "pull_total",
+			Help:      "Number of total charts pulled.",
+		},
+		[]string{"source"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(pullDuration)
+	prometheus.MustRegister(pullTotal)
+}
+
+// updatePullMetrics is a utility function for updating metrics related to
+// chart pulls.
+func updatePullMetrics(source string, startTime time.Time) {
+	pullDuration.WithLabelValues(source).Set(float64(time.Since(startTime) / time.Millisecond))
+	pullTotal.WithLabelValues(source).Inc()
+}
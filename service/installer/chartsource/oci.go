@@ -0,0 +1,81 @@
+package chartsource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/afero"
+
+	"github.com/giantswarm/draughtsman/service/installer/oci"
+)
+
+const (
+	// ociChartFileName is the name of the archive of charts pulled from OCI
+	// registries.
+	ociChartFileName = "chart.tgz"
+)
+
+// OCIConfig represents the configuration used to create an OCI Source.
+type OCIConfig struct {
+	// Dependencies.
+	Client     *oci.Client
+	FileSystem afero.Fs
+}
+
+// DefaultOCIConfig provides a default configuration to create a new OCI
+// Source by best effort.
+func DefaultOCIConfig() OCIConfig {
+	return OCIConfig{
+		// Dependencies.
+		Client:     nil,
+		FileSystem: afero.NewMemMapFs(),
+	}
+}
+
+// NewOCI creates a new configured OCI Source.
+func NewOCI(config OCIConfig) (*OCISource, error) {
+	if config.Client == nil {
+		return nil, microerror.Maskf(invalidConfigError, "client must not be empty")
+	}
+	if config.FileSystem == nil {
+		return nil, microerror.Maskf(invalidConfigError, "file system must not be empty")
+	}
+
+	source := &OCISource{
+		client:     config.Client,
+		fileSystem: config.FileSystem,
+	}
+
+	return source, nil
+}
+
+// OCISource is an implementation of the Source interface, that pulls charts
+// from OCI registries, e.g: oci://quay.io/giantswarm/api-chart:1.0.0-a1b2c3.
+type OCISource struct {
+	client     *oci.Client
+	fileSystem afero.Fs
+}
+
+func (s *OCISource) Supports(reference string) bool {
+	return oci.IsReference(reference)
+}
+
+func (s *OCISource) Pull(ctx context.Context, chart Chart, dir string) (string, error) {
+	defer updatePullMetrics("oci", time.Now())
+
+	b, err := s.client.Pull(ctx, chart.Reference)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	chartPath := filepath.Join(dir, ociChartFileName)
+	err = afero.WriteFile(s.fileSystem, chartPath, b, os.FileMode(0644))
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return chartPath, nil
+}
@@ -0,0 +1,107 @@
+package chartsource
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+
+	"github.com/giantswarm/draughtsman/service/installer/oci"
+)
+
+// testClient serves the given responses, by URL.
+type testClient map[string][]byte
+
+func (c testClient) Do(req *http.Request) (*http.Response, error) {
+	b, ok := c[req.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(&bytes.Buffer{})}, nil
+	}
+
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(b))}, nil
+}
+
+// TestOCIPull tests pulling charts from OCI registries into the given dir.
+func TestOCIPull(t *testing.T) {
+	chart := []byte("chart archive")
+	sum := sha256.Sum256(chart)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	client := testClient{
+		"https://localhost:5000/v2/charts/api/manifests/1.0.0-a": []byte(fmt.Sprintf(`{"layers": [{"mediaType": "application/vnd.cncf.helm.chart.content.v1.tar+gzip", "digest": %q}]}`, digest)),
+		"https://localhost:5000/v2/charts/api/blobs/" + digest:   chart,
+	}
+
+	var ociClient *oci.Client
+	{
+		c := oci.DefaultConfig()
+
+		c.HTTPClient = client
+		c.Logger = microloggertest.New()
+
+		var err error
+		ociClient, err = oci.New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fileSystem := afero.NewMemMapFs()
+
+	c := DefaultOCIConfig()
+
+	c.Client = ociClient
+	c.FileSystem = fileSystem
+
+	source, err := NewOCI(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		reference         string
+		expectedChartPath string
+		errorMatcher      func(error) bool
+	}{
+		{
+			reference:         "oci://localhost:5000/charts/api:1.0.0-a",
+			expectedChartPath: "/tmp/chart.tgz",
+		},
+		{
+			reference:    "oci://localhost:5000/charts/api:1.0.0-b",
+			errorMatcher: oci.IsNotFound,
+		},
+	}
+
+	for index, test := range tests {
+		chartPath, err := source.Pull(context.Background(), Chart{Project: "api", Reference: test.reference}, "/tmp")
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v: unexpected error returned: %#v", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+
+		if chartPath != test.expectedChartPath {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedChartPath, chartPath)
+		}
+
+		b, err := afero.ReadFile(fileSystem, chartPath)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %#v", index, err)
+		}
+		if string(b) != string(chart) {
+			t.Fatalf("%v\nexpected: %q\nreturned: %q\n", index, chart, b)
+		}
+	}
+}
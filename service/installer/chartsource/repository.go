@@ -0,0 +1,334 @@
+package chartsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
+
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+)
+
+const (
+	// indexFileName is the name of the index file of chart repositories.
+	indexFileName = "index.yaml"
+
+	// minShortShaLength is the minimum length of abbreviated shas in chart
+	// versions, as abbreviated by git.
+	minShortShaLength = 7
+)
+
+// RepositoryConfig represents the configuration used to create a Repository
+// Source.
+type RepositoryConfig struct {
+	// Dependencies.
+	FileSystem afero.Fs
+	// HTTPClient is used to pull charts, unless CAFile is set.
+	HTTPClient httpspec.Client
+	Logger     micrologger.Logger
+
+	// Settings.
+
+	// CAFile is the path of a PEM file holding the certificate authorities
+	// trusted by chart repositories, in addition to the system ones.
+	CAFile string
+	// Password is used to authenticate against chart repositories, together
+	// with Username. Both are optional for public repositories.
+	Password string
+	// Timeout is the timeout of requests, when CAFile is set.
+	Timeout  time.Duration
+	Username string
+}
+
+// DefaultRepositoryConfig provides a default configuration to create a new
+// Repository Source by best effort.
+func DefaultRepositoryConfig() RepositoryConfig {
+	return RepositoryConfig{
+		// Dependencies.
+		FileSystem: afero.NewMemMapFs(),
+		HTTPClient: nil,
+		Logger:     nil,
+
+		// Settings.
+		CAFile:   "",
+		Password: "",
+		Timeout:  time.Minute,
+		Username: "",
+	}
+}
+
+// NewRepository creates a new configured Repository Source.
+func NewRepository(config RepositoryConfig) (*RepositorySource, error) {
+	// Dependencies.
+	if config.FileSystem == nil {
+		return nil, microerror.Maskf(invalidConfigError, "file system must not be empty")
+	}
+	if config.HTTPClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	// Settings.
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "timeout must be greater than zero")
+	}
+
+	client := config.HTTPClient
+	if config.CAFile != "" {
+		var err error
+		client, err = newCAClient(config.FileSystem, config.CAFile, config.Timeout)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	source := &RepositorySource{
+		// Dependencies.
+		client:     client,
+		fileSystem: config.FileSystem,
+		logger:     config.Logger,
+
+		// Settings.
+		password: config.Password,
+		username: config.Username,
+	}
+
+	return source, nil
+}
+
+// RepositorySource is an implementation of the Source interface, that pulls
+// charts from index.yaml based HTTP chart repositories, e.g. ChartMuseum or
+// GitHub Pages. Chart references name the repository, followed by the chart,
+// e.g: https://giantswarm.github.io/charts/api-chart. The chart version is
+// resolved from the deployed sha.
+type RepositorySource struct {
+	// Dependencies.
+	client     httpspec.Client
+	fileSystem afero.Fs
+	logger     micrologger.Logger
+
+	// Settings.
+	password string
+	username string
+}
+
+// index represents the index file of a chart repository.
+type index struct {
+	Entries map[string][]indexEntry `json:"entries"`
+}
+
+// indexEntry represents a chart version in the index file of a chart
+// repository.
+type indexEntry struct {
+	Digest  string   `json:"digest"`
+	URLs    []string `json:"urls"`
+	Version string   `json:"version"`
+}
+
+func (s *RepositorySource) Supports(reference string) bool {
+	return strings.HasPrefix(reference, "https://") || strings.HasPrefix(reference, "http://")
+}
+
+func (s *RepositorySource) Pull(ctx context.Context, chart Chart, dir string) (string, error) {
+	defer updatePullMetrics("repository", time.Now())
+
+	repositoryURL, chartName, err := splitRepositoryReference(chart.Reference)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var idx index
+	{
+		b, err := s.get(ctx, repositoryURL, repositoryURL.ResolveReference(&url.URL{Path: indexFileName}))
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		if err := yaml.Unmarshal(b, &idx); err != nil {
+			return "", microerror.Maskf(pullFailedError, "could not parse index of %#q: %s", repositoryURL.String(), err.Error())
+		}
+	}
+
+	entry, ok := resolveVersion(idx.Entries[chartName], chart.Version, chart.Sha)
+	if !ok {
+		return "", microerror.Maskf(notFoundError, "chart %#q in version %#q, or of sha %#q, not found in %#q", chartName, chart.Version, chart.Sha, repositoryURL.String())
+	}
+	if len(entry.URLs) == 0 {
+		return "", microerror.Maskf(pullFailedError, "chart %#q in version %#q has no url", chartName, entry.Version)
+	}
+
+	s.logger.Log("debug", "resolved chart version", "chart", chartName, "version", entry.Version, "sha", chart.Sha)
+
+	chartURL, err := repositoryURL.Parse(entry.URLs[0])
+	if err != nil {
+		return "", microerror.Maskf(pullFailedError, "url of chart %#q: %s", chartName, err.Error())
+	}
+
+	b, err := s.get(ctx, repositoryURL, chartURL)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	if entry.Digest != "" {
+		sum := sha256.Sum256(b)
+		if hex.EncodeToString(sum[:]) != strings.TrimPrefix(entry.Digest, "sha256:") {
+			return "", microerror.Maskf(digestMismatchError, "chart %#q in version %#q does not match digest %#q", chartName, entry.Version, entry.Digest)
+		}
+	}
+
+	chartPath := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", chartName, entry.Version))
+	err = afero.WriteFile(s.fileSystem, chartPath, b, os.FileMode(0644))
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return chartPath, nil
+}
+
+// get makes a GET request. Credentials are only sent to the host of the
+// repository, and not to other hosts charts may be served from.
+func (s *RepositorySource) get(ctx context.Context, repositoryURL, u *url.URL) ([]byte, error) {
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+
+	if s.username != "" && u.Host == repositoryURL.Host {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, microerror.Maskf(pullFailedError, err.Error())
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, microerror.Maskf(pullFailedError, err.Error())
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, microerror.Maskf(notFoundError, "%#q", u.String())
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, microerror.Maskf(pullFailedError, "received status code %v for %#q, body: %q", resp.StatusCode, u.String(), string(b))
+	}
+
+	return b, nil
+}
+
+// splitRepositoryReference splits the reference of a chart into the URL of
+// the repository, with a trailing slash, and the name of the chart.
+func splitRepositoryReference(reference string) (*url.URL, string, error) {
+	slash := strings.LastIndex(reference, "/")
+	if slash < 0 {
+		return nil, "", microerror.Maskf(invalidReferenceError, "reference %#q must name a repository and chart", reference)
+	}
+
+	chartName := reference[slash+1:]
+	if chartName == "" {
+		return nil, "", microerror.Maskf(invalidReferenceError, "reference %#q must name a chart", reference)
+	}
+
+	repositoryURL, err := url.Parse(reference[:slash+1])
+	if err != nil {
+		return nil, "", microerror.Maskf(invalidReferenceError, err.Error())
+	}
+	if repositoryURL.Host == "" {
+		return nil, "", microerror.Maskf(invalidReferenceError, "reference %#q must name a repository host", reference)
+	}
+
+	return repositoryURL, chartName, nil
+}
+
+// resolveVersion returns the entry of the given version. If there is none,
+// it returns the first entry whose version identifies the sha in its
+// pre-release or build metadata, e.g: 1.2.0-a1b2c3d or 1.2.0+a1b2c3d. Shas
+// may be abbreviated in versions. Repositories list the newest version
+// first.
+func resolveVersion(entries []indexEntry, version, sha string) (indexEntry, bool) {
+	for _, entry := range entries {
+		if entry.Version == version {
+			return entry, true
+		}
+	}
+
+	if sha == "" {
+		return indexEntry{}, false
+	}
+
+	for _, entry := range entries {
+		if versionIdentifiesSha(entry.Version, sha) {
+			return entry, true
+		}
+	}
+
+	return indexEntry{}, false
+}
+
+// versionIdentifiesSha returns true if an identifier of the pre-release or
+// build metadata of the version is the sha, or an abbreviation of it.
+func versionIdentifiesSha(version, sha string) bool {
+	i := strings.IndexAny(version, "-+")
+	if i < 0 {
+		return false
+	}
+
+	identifiers := strings.FieldsFunc(version[i+1:], func(r rune) bool {
+		return r == '.' || r == '-' || r == '+'
+	})
+	for _, identifier := range identifiers {
+		if identifier == sha {
+			return true
+		}
+		if len(identifier) >= minShortShaLength && strings.HasPrefix(sha, identifier) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newCAClient returns an HTTP client trusting the certificate authorities of
+// the given PEM file, in addition to the system ones.
+func newCAClient(fileSystem afero.Fs, caFile string, timeout time.Duration) (*http.Client, error) {
+	b, err := afero.ReadFile(fileSystem, caFile)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "ca file: %s", err.Error())
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, microerror.Maskf(invalidConfigError, "ca file %#q holds no certificates", caFile)
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}
+
+	return client, nil
+}
@@ -0,0 +1,194 @@
+package chartsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+)
+
+// testRepository returns a TLS server behaving like a chart repository, that
+// requires basic authentication, and serves the given charts by version.
+func testRepository(t *testing.T, charts map[string][]byte, digests map[string]string) *httptest.Server {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "robot" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path == "/charts/index.yaml" {
+			fmt.Fprint(w, "apiVersion: v1\nentries:\n  api-chart:\n")
+			for _, version := range []string{"1.2.0-b2c3d4e", "1.1.0-a1b2c3d", "1.0.0-a1b2c3d4e5f6"} {
+				fmt.Fprintf(w, "  - version: %s\n    digest: %s\n    urls:\n    - api-chart-%s.tgz\n", version, digests[version], version)
+			}
+			return
+		}
+
+		for version, chart := range charts {
+			if r.URL.Path == "/charts/api-chart-"+version+".tgz" {
+				w.Write(chart)
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	return server
+}
+
+// TestRepositoryPull tests pulling charts from a chart repository, trusting
+// its certificate authority.
+func TestRepositoryPull(t *testing.T) {
+	charts := map[string][]byte{
+		"1.2.0-b2c3d4e":      []byte("chart 1.2.0"),
+		"1.1.0-a1b2c3d":      []byte("chart 1.1.0"),
+		"1.0.0-a1b2c3d4e5f6": []byte("chart 1.0.0"),
+	}
+	digests := map[string]string{}
+	for version, chart := range charts {
+		sum := sha256.Sum256(chart)
+		digests[version] = hex.EncodeToString(sum[:])
+	}
+	digests["1.2.0-b2c3d4e"] = "0000"
+
+	server := testRepository(t, charts, digests)
+	defer server.Close()
+
+	fileSystem := afero.NewMemMapFs()
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := afero.WriteFile(fileSystem, "/ca.pem", caPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := DefaultRepositoryConfig()
+
+	c.FileSystem = fileSystem
+	c.HTTPClient = http.DefaultClient
+	c.Logger = microloggertest.New()
+
+	c.CAFile = "/ca.pem"
+	c.Password = "pass"
+	c.Username = "robot"
+
+	source, err := NewRepository(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		chart             Chart
+		expectedChartPath string
+		expectedChart     string
+		errorMatcher      func(error) bool
+	}{
+		// Test that the chart version is resolved from the abbreviated sha.
+		{
+			chart:             Chart{Sha: "a1b2c3d4e5f6a7b8", Version: "1.0.0-a1b2c3d4e5f6a7b8"},
+			expectedChartPath: "/tmp/api-chart-1.1.0-a1b2c3d.tgz",
+			expectedChart:     "chart 1.1.0",
+		},
+		// Test that exact chart versions are preferred.
+		{
+			chart:             Chart{Sha: "a1b2c3d4e5f6a7b8", Version: "1.0.0-a1b2c3d4e5f6"},
+			expectedChartPath: "/tmp/api-chart-1.0.0-a1b2c3d4e5f6.tgz",
+			expectedChart:     "chart 1.0.0",
+		},
+		// Test that charts not matching their digest are rejected.
+		{
+			chart:        Chart{Sha: "b2c3d4e5f6a7b8c9", Version: "1.0.0-b2c3d4e5f6a7b8c9"},
+			errorMatcher: IsDigestMismatch,
+		},
+		// Test that unknown shas are not found.
+		{
+			chart:        Chart{Sha: "c3d4e5f6a7b8c9d0", Version: "1.0.0-c3d4e5f6a7b8c9d0"},
+			errorMatcher: IsNotFound,
+		},
+	}
+
+	for index, test := range tests {
+		test.chart.Project = "api"
+		test.chart.Reference = server.URL + "/charts/api-chart"
+
+		chartPath, err := source.Pull(context.Background(), test.chart, "/tmp")
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v: unexpected error returned: %#v", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+
+		if chartPath != test.expectedChartPath {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedChartPath, chartPath)
+		}
+
+		b, err := afero.ReadFile(fileSystem, chartPath)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %#v", index, err)
+		}
+		if string(b) != test.expectedChart {
+			t.Fatalf("%v\nexpected: %q\nreturned: %q\n", index, test.expectedChart, b)
+		}
+	}
+}
+
+// TestSplitRepositoryReference tests splitting chart references into the
+// repository and chart.
+func TestSplitRepositoryReference(t *testing.T) {
+	tests := []struct {
+		reference          string
+		expectedRepository string
+		expectedChart      string
+		errorMatcher       func(error) bool
+	}{
+		{
+			reference:          "https://giantswarm.github.io/charts/api-chart",
+			expectedRepository: "https://giantswarm.github.io/charts/",
+			expectedChart:      "api-chart",
+		},
+		{
+			reference:          "http://chartmuseum:8080/api-chart",
+			expectedRepository: "http://chartmuseum:8080/",
+			expectedChart:      "api-chart",
+		},
+		{
+			reference:    "https://giantswarm.github.io/charts/",
+			errorMatcher: IsInvalidReference,
+		},
+		{
+			reference:    "https://api-chart",
+			errorMatcher: IsInvalidReference,
+		},
+	}
+
+	for index, test := range tests {
+		repositoryURL, chartName, err := splitRepositoryReference(test.reference)
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v: unexpected error returned: %#v", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+
+		if repositoryURL.String() != test.expectedRepository {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedRepository, repositoryURL.String())
+		}
+		if chartName != test.expectedChart {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedChart, chartName)
+		}
+	}
+}
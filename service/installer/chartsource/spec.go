@@ -0,0 +1,31 @@
+package chartsource
+
+import (
+	"context"
+)
+
+// Chart represents the chart of a deployment.
+type Chart struct {
+	// Project is the name of the deployed project, e.g: api.
+	Project string
+	// Reference is the reference of the chart, as rendered from the chart
+	// template of the project, e.g:
+	// oci://quay.io/giantswarm/api-chart:1.0.0-a1b2c3. It is empty for charts
+	// in the CNR registry.
+	Reference string
+	// Sha is the deployed sha, e.g: a1b2c3.
+	Sha string
+	// Version is the chart version to install, e.g: 1.0.0-a1b2c3.
+	Version string
+}
+
+// Source represents a Service that pulls charts, e.g. from a registry or a
+// chart repository.
+type Source interface {
+	// Supports returns true if the source pulls charts of the given
+	// reference.
+	Supports(reference string) bool
+	// Pull pulls the chart into the given dir, and returns the path of the
+	// chart archive, or directory, to install.
+	Pull(ctx context.Context, chart Chart, dir string) (string, error)
+}
@@ -0,0 +1,109 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/giantswarm/microerror"
+)
+
+// historyEntry represents a revision of a release, as listed by helm history.
+type historyEntry struct {
+	Revision int    `json:"revision"`
+	Status   string `json:"status"`
+}
+
+// atomicArgs returns the Helm arguments to wait for the resources of the
+// release to become ready.
+func (i *HelmInstaller) atomicArgs() []string {
+	return []string{"--wait", "--timeout", i.timeout.String()}
+}
+
+// rollbackFailedInstall rolls the release back to its last successful
+// revision, after the given install error, or uninstalls the release, if the
+// failed install created it. Releases that never succeeded, but existed
+// before, are left alone, as uninstalling them could delete resources that
+// are still in use. The returned error describes the install error, and the
+// outcome of the rollback.
+func (i *HelmInstaller) rollbackFailedInstall(ctx context.Context, release, namespace string, installErr error) error {
+	i.logger.Log("debug", "rolling back failed install", "release", release, "namespace", namespace)
+
+	installMessage := errorMessage(installErr)
+
+	history, err := i.releaseHistory(ctx, release, namespace)
+	if err != nil {
+		return microerror.Maskf(rollbackFailedError, "could not find last successful revision of release %#q: %s, after failed install: %s", release, errorMessage(err), installMessage)
+	}
+
+	revision, ok := lastSuccessfulRevision(history)
+	if !ok && len(history) != 1 {
+		return microerror.Maskf(rollbackFailedError, "release %#q has no successful revision to roll back to, leaving it as is, after failed install: %s", release, installMessage)
+	}
+	if !ok {
+		err := i.runHelmCommand(ctx, "uninstall", "uninstall", release, "--namespace", namespace)
+		if err != nil {
+			return microerror.Maskf(rollbackFailedError, "uninstall of release %#q failed: %s, after failed install: %s", release, errorMessage(err), installMessage)
+		}
+
+		return microerror.Maskf(rolledBackError, "uninstalled release %#q after failed install: %s", release, installMessage)
+	}
+
+	rollbackCommand := []string{"rollback", release, fmt.Sprintf("%d", revision), "--namespace", namespace}
+	rollbackCommand = append(rollbackCommand, i.atomicArgs()...)
+
+	err = i.runHelmCommand(ctx, "rollback", rollbackCommand...)
+	if err != nil {
+		return microerror.Maskf(rollbackFailedError, "rollback of release %#q to revision %d failed: %s, after failed install: %s", release, revision, errorMessage(err), installMessage)
+	}
+
+	return microerror.Maskf(rolledBackError, "rolled back release %#q to revision %d after failed install: %s", release, revision, installMessage)
+}
+
+// errorMessage returns the message of the error on a single line, as Helm
+// errors end with the output of Helm.
+func errorMessage(err error) string {
+	return strings.Join(strings.Fields(err.Error()), " ")
+}
+
+// releaseHistory returns the revisions of the release.
+func (i *HelmInstaller) releaseHistory(ctx context.Context, release, namespace string) ([]historyEntry, error) {
+	cmd := exec.CommandContext(ctx, i.helmBinaryPath, "history", release, "--output", "yaml", "--namespace", namespace)
+
+	var stdOutBuf, stdErrBuf bytes.Buffer
+	cmd.Stdout = &stdOutBuf
+	cmd.Stderr = &stdErrBuf
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, microerror.Maskf(helmError, "error output: %s", stdErrBuf.String())
+	}
+
+	var history []historyEntry
+	err = yaml.Unmarshal(stdOutBuf.Bytes(), &history)
+	if err != nil {
+		return nil, microerror.Maskf(helmError, "could not parse helm history output: %s", err.Error())
+	}
+
+	return history, nil
+}
+
+// lastSuccessfulRevision returns the last revision of the release that was
+// deployed successfully. Helm marks deployed revisions superseded once they
+// are upgraded.
+func lastSuccessfulRevision(history []historyEntry) (int, bool) {
+	var revision int
+	for _, entry := range history {
+		if entry.Status != "deployed" && entry.Status != "superseded" {
+			continue
+		}
+		if entry.Revision > revision {
+			revision = entry.Revision
+		}
+	}
+
+	return revision, revision > 0
+}
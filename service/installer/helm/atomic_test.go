@@ -0,0 +1,225 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	configurerspec "github.com/giantswarm/draughtsman/service/configurer/spec"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+	"github.com/giantswarm/draughtsman/service/installer/chartsource"
+)
+
+// testHelmBinary writes a script acting as Helm binary, which logs its
+// arguments to the returned log file, fails the given commands, and prints
+// the given history.
+func testHelmBinary(t *testing.T, dir string, failing []string, history string) (string, string) {
+	logPath := filepath.Join(dir, "helm.log")
+	historyPath := filepath.Join(dir, "history.yaml")
+
+	if err := ioutil.WriteFile(historyPath, []byte(history), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	script := "#!/bin/sh\n"
+	script += fmt.Sprintf("echo \"$@\" >> %s\n", logPath)
+	for _, command := range failing {
+		script += fmt.Sprintf("if [ \"$1\" = %s ]; then echo \"Error: %s failed\" >&2; exit 1; fi\n", command, command)
+	}
+	script += fmt.Sprintf("if [ \"$1\" = history ]; then cat %s; fi\n", historyPath)
+
+	binaryPath := filepath.Join(dir, "helm")
+	if err := ioutil.WriteFile(binaryPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return binaryPath, logPath
+}
+
+// TestAtomicInstall tests that failed atomic installs are rolled back to the
+// last successful revision.
+func TestAtomicInstall(t *testing.T) {
+	disabled := false
+
+	history := "- revision: 1\n  status: superseded\n- revision: 2\n  status: deployed\n- revision: 3\n  status: failed\n"
+
+	tests := []struct {
+		project          configuration.Project
+		failing          []string
+		history          string
+		expectedCommands []string
+		errorMatcher     func(error) bool
+	}{
+		// Test that successful installs wait for the release.
+		{
+			project: configuration.Project{Name: "api", Namespace: "giantswarm"},
+			expectedCommands: []string{
+				"upgrade --install --namespace giantswarm --wait --timeout 1m0s api /install/chart.tgz",
+			},
+		},
+		// Test that failed installs are rolled back.
+		{
+			project: configuration.Project{Name: "api", Namespace: "giantswarm"},
+			failing: []string{"upgrade"},
+			history: history,
+			expectedCommands: []string{
+				"upgrade --install --namespace giantswarm --wait --timeout 1m0s api /install/chart.tgz",
+				"history api --output yaml --namespace giantswarm",
+				"rollback api 2 --namespace giantswarm --wait --timeout 1m0s",
+			},
+			errorMatcher: IsRolledBack,
+		},
+		// Test that failed rollbacks are reported.
+		{
+			project: configuration.Project{Name: "api", Namespace: "giantswarm"},
+			failing: []string{"upgrade", "rollback"},
+			history: history,
+			expectedCommands: []string{
+				"upgrade --install --namespace giantswarm --wait --timeout 1m0s api /install/chart.tgz",
+				"history api --output yaml --namespace giantswarm",
+				"rollback api 2 --namespace giantswarm --wait --timeout 1m0s",
+			},
+			errorMatcher: IsRollbackFailed,
+		},
+		// Test that releases created by the failed install are uninstalled.
+		{
+			project: configuration.Project{Name: "api", Namespace: "giantswarm"},
+			failing: []string{"upgrade"},
+			history: "- revision: 1\n  status: failed\n",
+			expectedCommands: []string{
+				"upgrade --install --namespace giantswarm --wait --timeout 1m0s api /install/chart.tgz",
+				"history api --output yaml --namespace giantswarm",
+				"uninstall api --namespace giantswarm",
+			},
+			errorMatcher: IsRolledBack,
+		},
+		// Test that releases which existed before, but never succeeded, are
+		// left alone.
+		{
+			project: configuration.Project{Name: "api", Namespace: "giantswarm"},
+			failing: []string{"upgrade"},
+			history: "- revision: 1\n  status: failed\n- revision: 2\n  status: failed\n",
+			expectedCommands: []string{
+				"upgrade --install --namespace giantswarm --wait --timeout 1m0s api /install/chart.tgz",
+				"history api --output yaml --namespace giantswarm",
+			},
+			errorMatcher: IsRollbackFailed,
+		},
+		// Test that projects can disable atomic installs.
+		{
+			project: configuration.Project{Name: "api", Namespace: "giantswarm", Atomic: &disabled},
+			failing: []string{"upgrade"},
+			history: history,
+			expectedCommands: []string{
+				"upgrade --install --namespace giantswarm api /install/chart.tgz",
+			},
+			errorMatcher: IsHelm,
+		},
+	}
+
+	for index, test := range tests {
+		dir, err := ioutil.TempDir("", "draughtsman-helm-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		binaryPath, logPath := testHelmBinary(t, dir, test.failing, test.history)
+
+		fileSystem := afero.NewMemMapFs()
+		// The chart is pulled into a fixed dir, so that the install command
+		// is known.
+		source := &testFixedDirSource{testSource: testSource{fileSystem: fileSystem, prefix: "oci://"}, dir: "/install"}
+
+		i := HelmInstaller{
+			chartSources: []chartsource.Source{source},
+			configurers:  []configurerspec.Configurer{},
+			fileSystem:   fileSystem,
+			logger:       microloggertest.New(),
+			projects:     configuration.Manifest{Projects: []configuration.Project{test.project}},
+
+			atomic:         true,
+			chartTemplate:  "oci://localhost:5000/charts/{{ .Project }}",
+			helmBinaryPath: binaryPath,
+			timeout:        time.Minute,
+		}
+
+		err = i.Install(context.Background(), eventerspec.DeploymentEvent{Name: "api", Sha: "a"})
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected matching error\nreturned: %#v\n", index, err)
+			}
+		} else if err != nil {
+			t.Fatalf("%v\nunexpected error: %#v\n", index, err)
+		}
+
+		b, err := ioutil.ReadFile(logPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commands := strings.Split(strings.TrimSpace(string(b)), "\n")
+
+		if !reflect.DeepEqual(commands, test.expectedCommands) {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedCommands, commands)
+		}
+	}
+}
+
+// testFixedDirSource pulls charts into a fixed dir.
+type testFixedDirSource struct {
+	testSource
+	dir string
+}
+
+func (s *testFixedDirSource) Pull(ctx context.Context, chart chartsource.Chart, dir string) (string, error) {
+	return s.testSource.Pull(ctx, chart, s.dir)
+}
+
+// TestLastSuccessfulRevision tests finding the revision to roll back to.
+func TestLastSuccessfulRevision(t *testing.T) {
+	tests := []struct {
+		history          []historyEntry
+		expectedRevision int
+		expectedOK       bool
+	}{
+		{
+			history:          []historyEntry{{Revision: 1, Status: "superseded"}, {Revision: 2, Status: "deployed"}, {Revision: 3, Status: "failed"}},
+			expectedRevision: 2,
+			expectedOK:       true,
+		},
+		{
+			history:          []historyEntry{{Revision: 4, Status: "superseded"}, {Revision: 5, Status: "failed"}, {Revision: 6, Status: "pending-upgrade"}},
+			expectedRevision: 4,
+			expectedOK:       true,
+		},
+		{
+			history:    []historyEntry{{Revision: 1, Status: "failed"}},
+			expectedOK: false,
+		},
+		{
+			history:    nil,
+			expectedOK: false,
+		},
+	}
+
+	for index, test := range tests {
+		revision, ok := lastSuccessfulRevision(test.history)
+
+		if ok != test.expectedOK {
+			t.Fatalf("%v\nexpected ok: %#v\nreturned ok: %#v\n", index, test.expectedOK, ok)
+		}
+		if revision != test.expectedRevision {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedRevision, revision)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package helm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/draughtsman/service/installer/chartsource"
+)
+
+// cnrSource is an implementation of the Source interface, that pulls charts
+// from the CNR registry, using the Helm quay plugin. It pulls the charts of
+// projects without chart reference.
+type cnrSource struct {
+	installer *HelmInstaller
+}
+
+func (s *cnrSource) Supports(reference string) bool {
+	return reference == ""
+}
+
+// Pull pulls the chart into the given dir, by running the Helm quay plugin in
+// it, so that concurrent installs do not share a directory.
+func (s *cnrSource) Pull(ctx context.Context, chart chartsource.Chart, dir string) (string, error) {
+	if err := s.installer.runHelmCommandInDir(
+		ctx,
+		dir,
+		"pull",
+		"quay",
+		"pull",
+		s.installer.versionedChartName(chart.Project, chart.Version),
+	); err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	chartPath := filepath.Join(dir, s.installer.chartName(chart.Project, chart.Version))
+	if _, err := s.installer.fileSystem.Stat(chartPath); os.IsNotExist(err) {
+		return "", microerror.Maskf(helmError, "could not find downloaded chart")
+	}
+
+	return chartPath, nil
+}
@@ -21,3 +21,32 @@ var helmError = &microerror.Error{
 func IsHelm(err error) bool {
 	return microerror.Cause(err) == helmError
 }
+
+var unsupportedTaskError = &microerror.Error{
+	Kind: "unsupportedTaskError",
+}
+
+// IsUnsupportedTask asserts unsupportedTaskError.
+func IsUnsupportedTask(err error) bool {
+	return microerror.Cause(err) == unsupportedTaskError
+}
+
+var rolledBackError = &microerror.Error{
+	Kind: "rolledBackError",
+}
+
+// IsRolledBack asserts rolledBackError, returned when an atomic install
+// failed, and its release was rolled back.
+func IsRolledBack(err error) bool {
+	return microerror.Cause(err) == rolledBackError
+}
+
+var rollbackFailedError = &microerror.Error{
+	Kind: "rollbackFailedError",
+}
+
+// IsRollbackFailed asserts rollbackFailedError, returned when an atomic
+// install failed, and its release could not be rolled back.
+func IsRollbackFailed(err error) bool {
+	return microerror.Cause(err) == rollbackFailedError
+}
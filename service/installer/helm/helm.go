@@ -2,10 +2,10 @@ package helm
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -16,18 +16,22 @@ import (
 	"github.com/spf13/afero"
 
 	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	"github.com/giantswarm/draughtsman/service/configurer"
 	configurerspec "github.com/giantswarm/draughtsman/service/configurer/spec"
 	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+	"github.com/giantswarm/draughtsman/service/installer/chartcache"
+	"github.com/giantswarm/draughtsman/service/installer/chartsource"
+	"github.com/giantswarm/draughtsman/service/installer/payload"
 	"github.com/giantswarm/draughtsman/service/installer/spec"
 )
 
 const (
 	// versionedChartFormat is the format the CNR registry uses to address
 	// charts. For example, we use this to address that chart to pull.
-	versionedChartFormat = "%v/%v/%v-chart@1.0.0-%v"
+	versionedChartFormat = "%v/%v/%v-chart@%v"
 
 	// chartNameFormat is the format for the name of the chart folder.
-	chartNameFormat = "%v_%v-chart_1.0.0-%v/%v-chart"
+	chartNameFormat = "%v_%v-chart_%v/%v-chart"
 
 	// pollInterval is the time interval between checking the status of helm releases
 	pollInterval = 1 * time.Minute
@@ -39,19 +43,47 @@ var HelmInstallerType spec.InstallerType = "HelmInstaller"
 // Config represents the configuration used to create a Helm Installer.
 type Config struct {
 	// Dependencies.
-	Configurers []configurerspec.Configurer
-	FileSystem  afero.Fs
-	Logger      micrologger.Logger
+
+	// ChartCache caches pulled charts, so that re-deploys and retries do not
+	// pull them again. It is optional.
+	ChartCache *chartcache.Cache
+	// ChartSources pull the charts of chart references, e.g. from OCI
+	// registries. The first source supporting a reference is used. Charts of
+	// projects without chart reference are pulled from the CNR registry.
+	ChartSources []chartsource.Source
+	Configurers  []configurerspec.Configurer
+	FileSystem   afero.Fs
+	Logger       micrologger.Logger
+	// ProjectConfiguration looks up the namespace, release name, and other
+	// settings of the installed projects.
+	ProjectConfiguration configuration.Getter
+	// ProjectLister lists the projects whose releases are checked.
+	ProjectLister eventerspec.ProjectLister
 
 	// Settings.
+
+	// Atomic installs releases atomically, unless projects configure
+	// otherwise. Installs wait for the resources of the release to become
+	// ready within the timeout, and roll the release back to its last
+	// successful revision if they do not.
+	Atomic bool
+	// ChartTemplate is the template of the reference of the chart of
+	// projects without chart template of their own, e.g:
+	// oci://quay.io/giantswarm/{{ .Project }}-chart:{{ .Version }}. It must
+	// be supported by one of the chart sources. Charts are pulled from the
+	// CNR registry, using the Helm quay plugin, when empty. Password and
+	// Username are only required then.
+	ChartTemplate  string
 	Environment    string
 	HelmBinaryPath string
-	Namespace      string
 	Organisation   string
 	Password       string
 	Provider       string
 	Registry       string
-	Username       string
+	// Timeout is the time atomic installs and their rollbacks wait for the
+	// resources of the release to become ready.
+	Timeout  time.Duration
+	Username string
 }
 
 // DefaultConfig provides a default configuration to create a new Helm
@@ -59,15 +91,22 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		// Dependencies.
-		Configurers: nil,
-		FileSystem:  afero.NewMemMapFs(),
-		Logger:      nil,
+		ChartCache:           nil,
+		ChartSources:         nil,
+		Configurers:          nil,
+		FileSystem:           afero.NewMemMapFs(),
+		Logger:               nil,
+		ProjectConfiguration: nil,
+		ProjectLister:        nil,
 
 		// Settings.
+		Atomic:         false,
+		ChartTemplate:  "",
 		HelmBinaryPath: "",
 		Organisation:   "",
 		Password:       "",
 		Registry:       "",
+		Timeout:        5 * time.Minute,
 		Username:       "",
 	}
 }
@@ -84,21 +123,30 @@ func New(config Config) (*HelmInstaller, error) {
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
 	}
+	if config.ProjectConfiguration == nil {
+		return nil, microerror.Maskf(invalidConfigError, "project configuration must not be empty")
+	}
+	if config.ProjectLister == nil {
+		return nil, microerror.Maskf(invalidConfigError, "project lister must not be empty")
+	}
 
 	// Settings.
+	if config.ChartTemplate != "" && !supported(config.ChartSources, config.ChartTemplate) {
+		return nil, microerror.Maskf(invalidConfigError, "chart template must be supported by a chart source")
+	}
+	if _, err := (configuration.Project{}).ChartReference(config.ChartTemplate, configuration.ChartReferenceData{}); err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "chart template: %s", err.Error())
+	}
 	if config.Environment == "" {
 		return nil, microerror.Maskf(invalidConfigError, "environment must not be empty")
 	}
 	if config.HelmBinaryPath == "" {
 		return nil, microerror.Maskf(invalidConfigError, "helm binary path must not be empty")
 	}
-	if config.Namespace == "" {
-		return nil, microerror.Maskf(invalidConfigError, "release namespace must not be empty")
-	}
 	if config.Organisation == "" {
 		return nil, microerror.Maskf(invalidConfigError, "organisation must not be empty")
 	}
-	if config.Password == "" {
+	if config.ChartTemplate == "" && config.Password == "" {
 		return nil, microerror.Maskf(invalidConfigError, "password must not be empty")
 	}
 	if config.Provider == "" {
@@ -108,7 +156,10 @@ func New(config Config) (*HelmInstaller, error) {
 	if config.Registry == "" {
 		return nil, microerror.Maskf(invalidConfigError, "registry must not be empty")
 	}
-	if config.Username == "" {
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "timeout must be greater than zero")
+	}
+	if config.ChartTemplate == "" && config.Username == "" {
 		return nil, microerror.Maskf(invalidConfigError, "username must not be empty")
 	}
 
@@ -118,23 +169,34 @@ func New(config Config) (*HelmInstaller, error) {
 
 	installer := &HelmInstaller{
 		// Dependencies.
-		configurers: config.Configurers,
-		fileSystem:  config.FileSystem,
-		logger:      config.Logger,
+		chartCache:    config.ChartCache,
+		configurers:   config.Configurers,
+		fileSystem:    config.FileSystem,
+		logger:        config.Logger,
+		projectLister: config.ProjectLister,
+		projects:      config.ProjectConfiguration,
 
 		// Settings.
+		atomic:         config.Atomic,
+		chartTemplate:  config.ChartTemplate,
 		environment:    config.Environment,
 		helmBinaryPath: config.HelmBinaryPath,
-		namespace:      config.Namespace,
 		organisation:   config.Organisation,
 		password:       config.Password,
 		provider:       config.Provider,
 		registry:       config.Registry,
+		timeout:        config.Timeout,
 		username:       config.Username,
 	}
 
-	if err := installer.login(); err != nil {
-		return nil, microerror.Mask(err)
+	installer.chartSources = append(append([]chartsource.Source{}, config.ChartSources...), &cnrSource{installer: installer})
+
+	// Logging into the CNR registry is only needed if charts are pulled from
+	// there.
+	if installer.chartTemplate == "" {
+		if err := installer.login(); err != nil {
+			return nil, microerror.Mask(err)
+		}
 	}
 
 	if err := installer.fetchMetrics(); err != nil {
@@ -148,51 +210,64 @@ func New(config Config) (*HelmInstaller, error) {
 // that uses Helm to install charts.
 type HelmInstaller struct {
 	// Dependencies.
-	configurers []configurerspec.Configurer
-	fileSystem  afero.Fs
-	logger      micrologger.Logger
+	chartCache    *chartcache.Cache
+	chartSources  []chartsource.Source
+	configurers   []configurerspec.Configurer
+	fileSystem    afero.Fs
+	logger        micrologger.Logger
+	projectLister eventerspec.ProjectLister
+	projects      configuration.Getter
 
 	// Settings.
+	atomic         bool
+	chartTemplate  string
 	environment    string
 	helmBinaryPath string
-	namespace      string
 	organisation   string
 	password       string
 	provider       string
 	registry       string
+	timeout        time.Duration
 	username       string
 }
 
 // versionedChartName builds a chart name, including a version,
-// given a project name and a sha.
-func (i *HelmInstaller) versionedChartName(project, sha string) string {
+// given a project name and a chart version.
+func (i *HelmInstaller) versionedChartName(project, version string) string {
 	return fmt.Sprintf(
 		versionedChartFormat,
 		i.registry,
 		i.organisation,
 		project,
-		sha,
+		version,
 	)
 }
 
-// chartName builds a chart name, given a project name and sha.
-func (i *HelmInstaller) chartName(project, sha string) string {
+// chartName builds a chart name, given a project name and chart version.
+func (i *HelmInstaller) chartName(project, version string) string {
 	return fmt.Sprintf(
 		chartNameFormat,
 		i.organisation,
 		project,
-		sha,
+		version,
 		project,
 	)
 }
 
 // runHelmCommand runs the given Helm command.
-func (i *HelmInstaller) runHelmCommand(name string, args ...string) error {
+func (i *HelmInstaller) runHelmCommand(ctx context.Context, name string, args ...string) error {
+	return i.runHelmCommandInDir(ctx, "", name, args...)
+}
+
+// runHelmCommandInDir runs the given Helm command in the given working
+// directory, or in the working directory of the process, if it is empty.
+func (i *HelmInstaller) runHelmCommandInDir(ctx context.Context, dir, name string, args ...string) error {
 	i.logger.Log("debug", "running helm command", "name", name)
 
 	defer updateHelmMetrics(name, time.Now())
 
-	cmd := exec.Command(i.helmBinaryPath, args...)
+	cmd := exec.CommandContext(ctx, i.helmBinaryPath, args...)
+	cmd.Dir = dir
 
 	var stdOutBuf, stdErrBuf bytes.Buffer
 	cmd.Stdout = &stdOutBuf
@@ -226,12 +301,11 @@ func (i *HelmInstaller) runHelmCommand(name string, args ...string) error {
 
 func (i *HelmInstaller) fetchMetrics() error {
 	ticker := time.NewTicker(pollInterval)
-	projectList := configuration.GetProjectList(i.provider, i.environment)
 
 	go func() {
 		for c := ticker.C; ; <-c {
 			i.logger.Log("debug", "fetching metrics")
-			i.checkHelmRelease(projectList)
+			i.checkHelmRelease(i.projectLister.ProjectList())
 			i.logger.Log("debug", "fetched metrics")
 		}
 	}()
@@ -243,13 +317,9 @@ func (i *HelmInstaller) checkHelmRelease(projectList []string) {
 	// Reset the last metrics in this vector so only new metrics could be reported.
 	helmReleaseFailure.Reset()
 	for _, prj := range projectList {
+		project := i.projects.Project(prj)
 
-		args := []string{"history", prj, "--output", "yaml", "--max", "1", "--namespace"}
-		if prj == "draughtsman" {
-			args = append(args, i.namespace)
-		} else {
-			args = append(args, "draughtsman")
-		}
+		args := []string{"history", project.Release, "--output", "yaml", "--max", "1", "--namespace", project.Namespace}
 
 		cmd := exec.Command(i.helmBinaryPath, args...)
 
@@ -282,6 +352,7 @@ func (i *HelmInstaller) login() error {
 	i.logger.Log("debug", "logging into registry", "username", i.username, "registry", i.registry)
 
 	return i.runHelmCommand(
+		context.Background(),
 		"login",
 		"quay",
 		"login",
@@ -291,39 +362,90 @@ func (i *HelmInstaller) login() error {
 	)
 }
 
-func (i *HelmInstaller) Install(event eventerspec.DeploymentEvent) error {
-	project := event.Name
-	sha := event.Sha
+func (i *HelmInstaller) Install(ctx context.Context, event eventerspec.DeploymentEvent) error {
+	switch event.Task {
+	case "", eventerspec.DeployTask:
+		return i.upgrade(ctx, event)
+	case eventerspec.RollbackTask:
+		return i.rollback(ctx, event)
+	default:
+		return microerror.Maskf(unsupportedTaskError, "task %#q is not supported", event.Task)
+	}
+}
 
-	i.logger.Log("debug", "installing chart", "name", project, "sha", sha)
+// releaseNamespace returns the namespace the release of the deployed project lives
+// in. Deployments may request a namespace in their payload, if the project
+// allows it.
+func (i *HelmInstaller) releaseNamespace(event eventerspec.DeploymentEvent) (string, error) {
+	namespace, err := payload.Namespace(i.projects.Project(event.Name), event.Payload)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
 
-	if err := i.runHelmCommand(
-		"pull",
-		"quay",
-		"pull",
-		i.versionedChartName(project, sha),
-	); err != nil {
-		return microerror.Mask(err)
+	return namespace, nil
+}
+
+// payloadArgs returns the Helm arguments requested by the payload of the
+// deployment, in a stable order. Values are set as strings, and only if the
+// project allows them.
+func payloadArgs(project configuration.Project, p eventerspec.DeploymentPayload) ([]string, error) {
+	var args []string
+
+	if p.DryRun {
+		args = append(args, "--dry-run")
 	}
 
-	dir, err := os.Getwd()
+	values, err := payload.Values(project, p)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for _, value := range values {
+		args = append(args, "--set-string", value)
+	}
+
+	return args, nil
+}
+
+// rollback rolls the release of the deployed project back to its previous
+// revision.
+func (i *HelmInstaller) rollback(ctx context.Context, event eventerspec.DeploymentEvent) error {
+	i.logger.Log("debug", "rolling back release", "name", event.Name, "dryRun", event.Payload.DryRun)
+
+	release := i.projects.Project(event.Name).Release
+
+	namespace, err := i.releaseNamespace(event)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	chartPath := path.Join(dir, i.chartName(project, sha))
-	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
-		return microerror.Maskf(helmError, "could not find downloaded chart")
+	rollbackCommand := []string{"rollback", release, "--namespace", namespace}
+	if event.Payload.DryRun {
+		rollbackCommand = append(rollbackCommand, "--dry-run")
+	}
+
+	err = i.runHelmCommand(ctx, "rollback", rollbackCommand...)
+	if err != nil {
+		return microerror.Mask(err)
 	}
 
-	defer os.Remove(chartPath)
+	return nil
+}
+
+// upgrade installs or upgrades the release of the deployed project to the
+// chart of the deployed sha.
+func (i *HelmInstaller) upgrade(ctx context.Context, event eventerspec.DeploymentEvent) error {
+	project := event.Name
+	version := event.ChartVersion()
 
-	i.logger.Log("debug", "downloaded chart", "chart", chartPath)
+	i.logger.Log("debug", "installing chart", "name", project, "sha", event.Sha, "version", version, "dryRun", event.Payload.DryRun)
 
-	// We create a tmp dir in which all Helm values files are written to. After we
-	// are done we can just remove the whole tmp dir to clean up.
+	// We create a tmp dir in which all Helm values files, and charts pulled
+	// from OCI registries, are written to. After we are done we can just
+	// remove the whole tmp dir to clean up.
 	var tmpDir string
 	{
+		var err error
 		tmpDir, err = afero.TempDir(i.fileSystem, "", "draughtsman-installer")
 		if err != nil {
 			return microerror.Mask(err)
@@ -331,50 +453,52 @@ func (i *HelmInstaller) Install(event eventerspec.DeploymentEvent) error {
 		defer func() {
 			err := i.fileSystem.RemoveAll(tmpDir)
 			if err != nil {
-				i.logger.Log("error", fmt.Sprintf("could not remove tmp dir: %#v", err), "name", project, "sha", sha)
+				i.logger.Log("error", fmt.Sprintf("could not remove tmp dir: %#v", err), "name", project, "sha", event.Sha)
 			}
 		}()
 	}
 
+	projectConfiguration := i.projects.Project(project)
+
+	chartPath, err := i.pullChart(ctx, tmpDir, projectConfiguration, event, version)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
 	var forceArg string
 	{
-		if strings.HasSuffix(project, "app-collection") {
+		if projectConfiguration.Force {
 			forceArg = "--force"
 		}
 	}
 
-	namespaceArgs := []string{"--namespace"}
-	{
-		if project == "draughtsman" {
-			namespaceArgs = append(namespaceArgs, i.namespace)
-		} else {
-			namespaceArgs = append(namespaceArgs, "draughtsman")
-		}
+	namespace, err := i.releaseNamespace(event)
+	if err != nil {
+		return microerror.Mask(err)
 	}
+	namespaceArgs := []string{"--namespace", namespace}
 
-	// The intaller accepts multiple configurers during initialization. Here we
-	// iterate over all of them to get all the values they provide. For each
-	// values file we have to create a file in the tmp dir we created above.
-	var valuesFilesArgs []string
-	for _, c := range i.configurers {
-		fileName := filepath.Join(tmpDir, fmt.Sprintf("%s-values.yaml", strings.ToLower(string(c.Type()))))
-		values, err := c.Values()
-		if err != nil {
-			return microerror.Mask(err)
-		}
+	requestedArgs, err := payloadArgs(projectConfiguration, event.Payload)
+	if err != nil {
+		return microerror.Mask(err)
+	}
 
-		err = afero.WriteFile(i.fileSystem, fileName, []byte(values), os.FileMode(0644))
-		if err != nil {
-			return microerror.Mask(err)
-		}
+	// Dry runs change nothing that could be rolled back.
+	atomic := projectConfiguration.IsAtomic(i.atomic) && !event.Payload.DryRun
 
-		valuesFilesArgs = append(valuesFilesArgs, "--values", fileName)
+	valuesFilesArgs, err := i.writeValuesFiles(tmpDir, projectConfiguration)
+	if err != nil {
+		return microerror.Mask(err)
 	}
 
 	// The arguments used to execute Helm for app installation can take multiple
-	// values files. At the end the command looks something like this.
+	// values files, followed by any arguments requested by the deployment
+	// payload. At the end the command looks something like this.
 	//
-	//     helm upgrade --install --values ${file1} --values $(file2) ${project} ${chart_path}
+	//     helm upgrade --install --values ${file1} --values $(file2) --set-string ${key}=${value} ${project} ${chart_path}
+	//
+	// Atomic installs wait for the resources of the release, and roll the
+	// release back if the install fails.
 	//
 	var installCommand []string
 	{
@@ -383,14 +507,115 @@ func (i *HelmInstaller) Install(event eventerspec.DeploymentEvent) error {
 			installCommand = append(installCommand, forceArg)
 		}
 		installCommand = append(installCommand, valuesFilesArgs...)
+		installCommand = append(installCommand, requestedArgs...)
 		installCommand = append(installCommand, namespaceArgs...)
-		installCommand = append(installCommand, project, chartPath)
+		if atomic {
+			installCommand = append(installCommand, i.atomicArgs()...)
+		}
+		installCommand = append(installCommand, projectConfiguration.Release, chartPath)
 
-		err := i.runHelmCommand("install", installCommand...)
-		if err != nil {
+		err := i.runHelmCommand(ctx, "install", installCommand...)
+		if err != nil && atomic {
+			return microerror.Mask(i.rollbackFailedInstall(ctx, projectConfiguration.Release, namespace, err))
+		} else if err != nil {
 			return microerror.Mask(err)
 		}
 	}
 
 	return nil
 }
+
+// pullChart pulls the chart of the project in the given version into the
+// given dir, using the first chart source supporting the chart reference of
+// the project, and returns its path. Cached charts are not pulled again.
+func (i *HelmInstaller) pullChart(ctx context.Context, dir string, project configuration.Project, event eventerspec.DeploymentEvent, version string) (string, error) {
+	data := configuration.ChartReferenceData{
+		Organisation: i.organisation,
+		Project:      project.Name,
+		Registry:     i.registry,
+		Sha:          event.Sha,
+		Version:      version,
+	}
+
+	reference, err := project.ChartReference(i.chartTemplate, data)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	chart := chartsource.Chart{
+		Project:   project.Name,
+		Reference: reference,
+		Sha:       event.Sha,
+		Version:   version,
+	}
+
+	if i.chartCache != nil {
+		chartPath, cached, err := i.chartCache.Get(chart, dir)
+		if err != nil {
+			i.logger.Log("error", "could not read chart cache", "name", project.Name, "version", version, "message", err.Error())
+		} else if cached {
+			i.logger.Log("debug", "found chart in cache", "chart", chartPath)
+			return chartPath, nil
+		}
+	}
+
+	for _, source := range i.chartSources {
+		if !source.Supports(reference) {
+			continue
+		}
+
+		chartPath, err := source.Pull(ctx, chart, dir)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		i.logger.Log("debug", "downloaded chart", "chart", chartPath)
+
+		if i.chartCache != nil {
+			err := i.chartCache.Put(chart, chartPath)
+			if err != nil {
+				i.logger.Log("error", "could not cache chart", "name", project.Name, "version", version, "message", err.Error())
+			}
+		}
+
+		return chartPath, nil
+	}
+
+	return "", microerror.Maskf(helmError, "chart reference %#q is not supported", reference)
+}
+
+// supported returns true if one of the chart sources supports the reference.
+func supported(sources []chartsource.Source, reference string) bool {
+	for _, source := range sources {
+		if source.Supports(reference) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeValuesFiles writes the values of all configurers to files in the given
+// dir, and returns the Helm arguments to use them.
+func (i *HelmInstaller) writeValuesFiles(dir string, project configuration.Project) ([]string, error) {
+	values, err := configurer.ProjectValues(i.configurers, project.ValuesKeys)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The intaller accepts multiple configurers during initialization. For
+	// each values file they provide we have to create a file in the tmp dir
+	// we created above.
+	var valuesFilesArgs []string
+	for _, v := range values {
+		fileName := filepath.Join(dir, fmt.Sprintf("%s-values.yaml", v.Name))
+		err = afero.WriteFile(i.fileSystem, fileName, []byte(v.Data), os.FileMode(0644))
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		valuesFilesArgs = append(valuesFilesArgs, "--values", fileName)
+	}
+
+	return valuesFilesArgs, nil
+}
@@ -1,7 +1,23 @@
 package helm
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	"github.com/giantswarm/draughtsman/service/configurer"
+	configurerspec "github.com/giantswarm/draughtsman/service/configurer/spec"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+	"github.com/giantswarm/draughtsman/service/installer/chartcache"
+	"github.com/giantswarm/draughtsman/service/installer/chartsource"
+	"github.com/giantswarm/draughtsman/service/installer/payload"
 )
 
 // TestVersionedChartName tests the versionedChartName method.
@@ -10,16 +26,23 @@ func TestVersionedChartName(t *testing.T) {
 		registry          string
 		organisation      string
 		project           string
-		sha               string
+		version           string
 		expectedChartName string
 	}{
 		{
 			registry:          "quay.io",
 			organisation:      "giantswarm",
 			project:           "api",
-			sha:               "12345",
+			version:           "1.0.0-12345",
 			expectedChartName: "quay.io/giantswarm/api-chart@1.0.0-12345",
 		},
+		{
+			registry:          "quay.io",
+			organisation:      "giantswarm",
+			project:           "api",
+			version:           "1.2.3",
+			expectedChartName: "quay.io/giantswarm/api-chart@1.2.3",
+		},
 	}
 
 	for index, test := range tests {
@@ -28,7 +51,7 @@ func TestVersionedChartName(t *testing.T) {
 			organisation: test.organisation,
 		}
 
-		returnedChartName := i.versionedChartName(test.project, test.sha)
+		returnedChartName := i.versionedChartName(test.project, test.version)
 
 		if returnedChartName != test.expectedChartName {
 			t.Fatalf(
@@ -45,14 +68,14 @@ func TestChartName(t *testing.T) {
 		registry          string
 		organisation      string
 		project           string
-		sha               string
+		version           string
 		expectedChartName string
 	}{
 		{
 			registry:          "quay.io",
 			organisation:      "giantswarm",
 			project:           "api",
-			sha:               "12345",
+			version:           "1.0.0-12345",
 			expectedChartName: "giantswarm_api-chart_1.0.0-12345/api-chart",
 		},
 	}
@@ -63,7 +86,7 @@ func TestChartName(t *testing.T) {
 			organisation: test.organisation,
 		}
 
-		returnedChartName := i.chartName(test.project, test.sha)
+		returnedChartName := i.chartName(test.project, test.version)
 
 		if returnedChartName != test.expectedChartName {
 			t.Fatalf(
@@ -73,3 +96,329 @@ func TestChartName(t *testing.T) {
 		}
 	}
 }
+
+// TestPayloadArgs tests the payloadArgs function.
+func TestPayloadArgs(t *testing.T) {
+	tests := []struct {
+		payload      eventerspec.DeploymentPayload
+		expectedArgs []string
+		errorMatcher func(error) bool
+	}{
+		// Test that an empty payload adds no arguments.
+		{
+			payload:      eventerspec.DeploymentPayload{},
+			expectedArgs: nil,
+		},
+
+		// Test that values are set as strings in a stable order, after the
+		// dry run flag.
+		{
+			payload: eventerspec.DeploymentPayload{
+				DryRun: true,
+				Values: map[string]string{
+					"replicas":  "2",
+					"image.tag": "1.2.3",
+				},
+			},
+			expectedArgs: []string{"--dry-run", "--set-string", "image.tag=1.2.3", "--set-string", "replicas=2"},
+		},
+
+		// Test that values the project does not allow are rejected.
+		{
+			payload: eventerspec.DeploymentPayload{
+				Values: map[string]string{
+					"securityContext.privileged": "true",
+				},
+			},
+			errorMatcher: payload.IsForbiddenPayload,
+		},
+	}
+
+	project := configuration.Project{
+		Name:          "api",
+		PayloadValues: []string{"image.tag", "replicas"},
+	}
+
+	for index, test := range tests {
+		returnedArgs, err := payloadArgs(project, test.payload)
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected error, returned: %#v\n", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+		}
+
+		if !reflect.DeepEqual(test.expectedArgs, returnedArgs) {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedArgs, returnedArgs,
+			)
+		}
+	}
+}
+
+// TestReleaseNamespace tests the releaseNamespace method.
+func TestReleaseNamespace(t *testing.T) {
+	tests := []struct {
+		event             eventerspec.DeploymentEvent
+		expectedNamespace string
+		errorMatcher      func(error) bool
+	}{
+		{
+			event:             eventerspec.DeploymentEvent{Name: "api"},
+			expectedNamespace: "draughtsman",
+		},
+		{
+			event:             eventerspec.DeploymentEvent{Name: "draughtsman"},
+			expectedNamespace: "giantswarm",
+		},
+		{
+			event: eventerspec.DeploymentEvent{
+				Name:    "draughtsman",
+				Payload: eventerspec.DeploymentPayload{Namespace: "monitoring"},
+			},
+			expectedNamespace: "monitoring",
+		},
+		{
+			event: eventerspec.DeploymentEvent{
+				Name:    "api",
+				Payload: eventerspec.DeploymentPayload{Namespace: "monitoring"},
+			},
+			errorMatcher: payload.IsForbiddenPayload,
+		},
+	}
+
+	for index, test := range tests {
+		i := HelmInstaller{
+			projects: configuration.Manifest{
+				Projects: []configuration.Project{
+					{Name: "draughtsman", Namespace: "giantswarm", PayloadNamespaces: []string{"monitoring"}},
+				},
+			},
+		}
+
+		returnedNamespace, err := i.releaseNamespace(test.event)
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected error, returned: %#v\n", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+		}
+
+		if returnedNamespace != test.expectedNamespace {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedNamespace, returnedNamespace,
+			)
+		}
+	}
+}
+
+// testConfigurer is a Configurer holding values under multiple keys.
+type testConfigurer map[string]string
+
+func (c testConfigurer) Type() configurerspec.ConfigurerType {
+	return "TestConfigurer"
+}
+
+func (c testConfigurer) Values() (string, error) {
+	return c["values"], nil
+}
+
+func (c testConfigurer) KeyValues(key string) (string, bool, error) {
+	values, ok := c[key]
+	return values, ok, nil
+}
+
+// TestWriteValuesFiles tests the writeValuesFiles method.
+func TestWriteValuesFiles(t *testing.T) {
+	tests := []struct {
+		project        configuration.Project
+		expectedValues []string
+		errorMatcher   func(error) bool
+	}{
+		{
+			project:        configuration.Project{Name: "api"},
+			expectedValues: []string{"default"},
+		},
+		{
+			project:        configuration.Project{Name: "api", ValuesKeys: []string{"api", "monitoring"}},
+			expectedValues: []string{"api", "monitoring"},
+		},
+		{
+			project:      configuration.Project{Name: "api", ValuesKeys: []string{"missing"}},
+			errorMatcher: configurer.IsValuesKeyMissing,
+		},
+	}
+
+	for index, test := range tests {
+		fileSystem := afero.NewMemMapFs()
+
+		i := HelmInstaller{
+			configurers: []configurerspec.Configurer{
+				testConfigurer{"values": "default", "api": "api", "monitoring": "monitoring"},
+			},
+			fileSystem: fileSystem,
+		}
+
+		args, err := i.writeValuesFiles("/tmp", test.project)
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected matching error\nreturned: %#v\n", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v\nunexpected error: %#v\n", index, err)
+		}
+
+		var returnedValues []string
+		for j := 1; j < len(args); j += 2 {
+			b, err := afero.ReadFile(fileSystem, args[j])
+			if err != nil {
+				t.Fatalf("%v\nunexpected error: %#v\n", index, err)
+			}
+			returnedValues = append(returnedValues, string(b))
+		}
+
+		if !reflect.DeepEqual(returnedValues, test.expectedValues) {
+			t.Fatalf(
+				"%v\nexpected: %#v\nreturned: %#v\n",
+				index, test.expectedValues, returnedValues,
+			)
+		}
+	}
+}
+
+// testSource pulls the charts of references with the given prefix, writing
+// the reference and version as chart, and counts the pulls.
+type testSource struct {
+	fileSystem afero.Fs
+	prefix     string
+	pulls      int
+}
+
+func (s *testSource) Supports(reference string) bool {
+	return strings.HasPrefix(reference, s.prefix)
+}
+
+func (s *testSource) Pull(ctx context.Context, chart chartsource.Chart, dir string) (string, error) {
+	s.pulls++
+
+	chartPath := filepath.Join(dir, "chart.tgz")
+	err := afero.WriteFile(s.fileSystem, chartPath, []byte(chart.Reference+"@"+chart.Version), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return chartPath, nil
+}
+
+// TestPullChart tests that charts are pulled from the first chart source
+// supporting their reference, unless they are cached.
+func TestPullChart(t *testing.T) {
+	fileSystem := afero.NewMemMapFs()
+
+	var cache *chartcache.Cache
+	{
+		c := chartcache.DefaultConfig()
+
+		c.FileSystem = fileSystem
+		c.Logger = microloggertest.New()
+
+		c.Directory = "/cache"
+
+		var err error
+		cache, err = chartcache.New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ociSource := &testSource{fileSystem: fileSystem, prefix: "oci://"}
+	repositorySource := &testSource{fileSystem: fileSystem, prefix: "https://"}
+
+	i := HelmInstaller{
+		chartCache:   cache,
+		chartSources: []chartsource.Source{ociSource, repositorySource},
+		fileSystem:   fileSystem,
+		logger:       microloggertest.New(),
+
+		chartTemplate: "oci://localhost:5000/charts/{{ .Project }}",
+	}
+
+	tests := []struct {
+		project       configuration.Project
+		event         eventerspec.DeploymentEvent
+		expectedChart string
+		expectedPulls int
+		errorMatcher  func(error) bool
+	}{
+		{
+			project:       configuration.Project{Name: "api"},
+			event:         eventerspec.DeploymentEvent{Name: "api", Sha: "a"},
+			expectedChart: "oci://localhost:5000/charts/api@1.0.0-a",
+			expectedPulls: 1,
+		},
+		// Test that re-deploys use the cached chart.
+		{
+			project:       configuration.Project{Name: "api"},
+			event:         eventerspec.DeploymentEvent{Name: "api", Sha: "a"},
+			expectedChart: "oci://localhost:5000/charts/api@1.0.0-a",
+			expectedPulls: 1,
+		},
+		{
+			project:       configuration.Project{Name: "api"},
+			event:         eventerspec.DeploymentEvent{Name: "api", Sha: "b"},
+			expectedChart: "oci://localhost:5000/charts/api@1.0.0-b",
+			expectedPulls: 2,
+		},
+		{
+			project:       configuration.Project{Name: "web", Chart: "https://charts.giantswarm.io/{{ .Project }}-chart"},
+			event:         eventerspec.DeploymentEvent{Name: "web", Sha: "a", Version: "1.2.0"},
+			expectedChart: "https://charts.giantswarm.io/web-chart@1.2.0",
+			expectedPulls: 2,
+		},
+		{
+			project:      configuration.Project{Name: "api", Chart: "s3://charts/{{ .Project }}"},
+			event:        eventerspec.DeploymentEvent{Name: "api", Sha: "a"},
+			errorMatcher: IsHelm,
+		},
+	}
+
+	for index, test := range tests {
+		dir := fmt.Sprintf("/install/%d", index)
+
+		chartPath, err := i.pullChart(context.Background(), dir, test.project, test.event, test.event.ChartVersion())
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected matching error\nreturned: %#v\n", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v\nunexpected error: %#v\n", index, err)
+		}
+
+		if !strings.HasPrefix(chartPath, dir) {
+			t.Fatalf("%v\nexpected chart in: %#v\nreturned: %#v\n", index, dir, chartPath)
+		}
+
+		b, err := afero.ReadFile(fileSystem, chartPath)
+		if err != nil {
+			t.Fatalf("%v\nunexpected error: %#v\n", index, err)
+		}
+		if string(b) != test.expectedChart {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedChart, string(b))
+		}
+		if ociSource.pulls != test.expectedPulls {
+			t.Fatalf("%v\nexpected pulls: %#v\nreturned pulls: %#v\n", index, test.expectedPulls, ociSource.pulls)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package helmsdk
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"helm.sh/helm/v3/pkg/action"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// storageDriver is the Helm storage driver releases are stored with,
+	// which is the default of Helm 3.
+	storageDriver = "secret"
+)
+
+// ActionConfigFunc returns the Helm action configuration of the releases in
+// the given namespace.
+type ActionConfigFunc func(namespace string) (*action.Configuration, error)
+
+// NewActionConfigFunc returns an ActionConfigFunc for the cluster of the
+// given REST config, storing releases as secrets. The action configuration of
+// every namespace is created once.
+func NewActionConfigFunc(restConfig *rest.Config, logger micrologger.Logger) (ActionConfigFunc, error) {
+	if restConfig == nil {
+		return nil, microerror.Maskf(invalidConfigError, "rest config must not be empty")
+	}
+	if logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	cachedDiscoveryClient := memory.NewMemCacheClient(discoveryClient)
+
+	log := func(format string, v ...interface{}) {
+		logger.Log("debug", fmt.Sprintf(format, v...))
+	}
+
+	configs := map[string]*action.Configuration{}
+	mutex := &sync.Mutex{}
+
+	return func(namespace string) (*action.Configuration, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if cfg, ok := configs[namespace]; ok {
+			return cfg, nil
+		}
+
+		getter := &restClientGetter{
+			discoveryClient: cachedDiscoveryClient,
+			namespace:       namespace,
+			restConfig:      restConfig,
+		}
+
+		cfg := &action.Configuration{}
+		err := cfg.Init(getter, namespace, storageDriver, log)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		configs[namespace] = cfg
+
+		return cfg, nil
+	}, nil
+}
+
+// restClientGetter provides the clients of the Helm SDK from a REST config,
+// instead of a kubeconfig file. Resources without a namespace are created in
+// the namespace of the getter.
+type restClientGetter struct {
+	discoveryClient discovery.CachedDiscoveryInterface
+	namespace       string
+	restConfig      *rest.Config
+}
+
+func (g *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.restConfig), nil
+}
+
+func (g *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	return g.discoveryClient, nil
+}
+
+func (g *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	return restmapper.NewDeferredDiscoveryRESTMapper(g.discoveryClient), nil
+}
+
+func (g *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	overrides := &clientcmd.ConfigOverrides{
+		Context: clientcmdapi.Context{
+			Namespace: g.namespace,
+		},
+	}
+
+	return clientcmd.NewDefaultClientConfig(*clientcmdapi.NewConfig(), overrides)
+}
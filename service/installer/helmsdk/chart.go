@@ -0,0 +1,101 @@
+package helmsdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+	"github.com/giantswarm/draughtsman/service/installer/oci"
+)
+
+const (
+	// cnrPullUrlFormat is the string format for the CNR API call to pull a
+	// chart. Templated with the registry, organisation, project and version.
+	cnrPullUrlFormat = "https://%s/cnr/api/v1/packages/%s/%s-chart/%s/helm/pull"
+)
+
+// pullChart pulls the chart of the project in the given version, and loads
+// it. Charts are pulled from the OCI registry of the chart reference of the
+// project, or from the CNR registry, if there is no chart reference.
+func (i *HelmSDKInstaller) pullChart(ctx context.Context, project configuration.Project, event eventerspec.DeploymentEvent, version string) (*chart.Chart, error) {
+	defer updateActionMetrics("pull", time.Now())
+
+	data := configuration.ChartReferenceData{
+		Organisation: i.organisation,
+		Project:      project.Name,
+		Registry:     i.registry,
+		Sha:          event.Sha,
+		Version:      version,
+	}
+
+	reference, err := project.ChartReference(i.chartTemplate, data)
+	if err != nil {
+		return nil, microerror.Maskf(pullFailedError, err.Error())
+	}
+
+	if reference == "" {
+		return i.pullCNRChart(ctx, project.Name, version)
+	}
+	if !oci.IsReference(reference) {
+		return nil, microerror.Maskf(pullFailedError, "chart reference %#q is not supported", reference)
+	}
+
+	b, err := i.ociClient.Pull(ctx, reference)
+	if oci.IsCancelled(err) {
+		return nil, microerror.Maskf(cancelledError, err.Error())
+	} else if err != nil {
+		return nil, microerror.Maskf(pullFailedError, err.Error())
+	}
+
+	c, err := loader.LoadArchive(bytes.NewReader(b))
+	if err != nil {
+		return nil, microerror.Maskf(pullFailedError, "could not load chart %#q: %s", reference, err.Error())
+	}
+
+	return c, nil
+}
+
+// pullCNRChart pulls the chart of the project in the given version from the
+// CNR registry, and loads it.
+func (i *HelmSDKInstaller) pullCNRChart(ctx context.Context, project, version string) (*chart.Chart, error) {
+	u := fmt.Sprintf(cnrPullUrlFormat, i.registry, i.organisation, project, version)
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+
+	if i.username != "" {
+		req.SetBasicAuth(i.username, i.password)
+	}
+
+	resp, err := i.client.Do(req)
+	if ctx.Err() != nil {
+		return nil, microerror.Maskf(cancelledError, ctx.Err().Error())
+	} else if err != nil {
+		return nil, microerror.Maskf(pullFailedError, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, microerror.Maskf(pullFailedError, "received status code %v for chart %#q, body: %q", resp.StatusCode, u, string(b))
+	}
+
+	c, err := loader.LoadArchive(resp.Body)
+	if err != nil {
+		return nil, microerror.Maskf(pullFailedError, "could not load chart %#q: %s", u, err.Error())
+	}
+
+	return c, nil
+}
@@ -0,0 +1,59 @@
+package helmsdk
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var cancelledError = &microerror.Error{
+	Kind: "cancelledError",
+}
+
+// IsCancelled asserts cancelledError.
+func IsCancelled(err error) bool {
+	return microerror.Cause(err) == cancelledError
+}
+
+var invalidValuesError = &microerror.Error{
+	Kind: "invalidValuesError",
+}
+
+// IsInvalidValues asserts invalidValuesError.
+func IsInvalidValues(err error) bool {
+	return microerror.Cause(err) == invalidValuesError
+}
+
+var pullFailedError = &microerror.Error{
+	Kind: "pullFailedError",
+}
+
+// IsPullFailed asserts pullFailedError.
+func IsPullFailed(err error) bool {
+	return microerror.Cause(err) == pullFailedError
+}
+
+var releaseFailedError = &microerror.Error{
+	Kind: "releaseFailedError",
+}
+
+// IsReleaseFailed asserts releaseFailedError.
+func IsReleaseFailed(err error) bool {
+	return microerror.Cause(err) == releaseFailedError
+}
+
+var unsupportedTaskError = &microerror.Error{
+	Kind: "unsupportedTaskError",
+}
+
+// IsUnsupportedTask asserts unsupportedTaskError.
+func IsUnsupportedTask(err error) bool {
+	return microerror.Cause(err) == unsupportedTaskError
+}
@@ -0,0 +1,390 @@
+package helmsdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	configurerspec "github.com/giantswarm/draughtsman/service/configurer/spec"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+	"github.com/giantswarm/draughtsman/service/installer/oci"
+	"github.com/giantswarm/draughtsman/service/installer/payload"
+	"github.com/giantswarm/draughtsman/service/installer/spec"
+)
+
+const (
+	// pollInterval is the time interval between checking the status of helm
+	// releases.
+	pollInterval = 1 * time.Minute
+)
+
+// HelmSDKInstallerType is an Installer that uses the Helm SDK.
+var HelmSDKInstallerType spec.InstallerType = "HelmSDKInstaller"
+
+// Config represents the configuration used to create a Helm SDK Installer.
+type Config struct {
+	// Dependencies.
+
+	// ActionConfig returns the Helm action configuration of the releases in
+	// a namespace, e.g. as created by NewActionConfigFunc.
+	ActionConfig ActionConfigFunc
+	Configurers  []configurerspec.Configurer
+	// HTTPClient is used to pull charts from the CNR registry.
+	HTTPClient httpspec.Client
+	Logger     micrologger.Logger
+	// OCIClient is used to pull charts from OCI registries.
+	OCIClient *oci.Client
+	// ProjectConfiguration looks up the namespace, release name, and other
+	// settings of the installed projects.
+	ProjectConfiguration configuration.Getter
+	// ProjectLister lists the projects whose releases are checked.
+	ProjectLister eventerspec.ProjectLister
+
+	// Settings.
+
+	// ChartTemplate is the template of the reference of the chart of
+	// projects without chart template of their own, e.g:
+	// oci://quay.io/giantswarm/{{ .Project }}-chart:{{ .Version }}. Charts
+	// are pulled from the CNR registry when empty.
+	ChartTemplate string
+	Organisation  string
+	Password      string
+	// Registry is the host of the CNR registry, e.g: quay.io.
+	Registry string
+	// Timeout is the time Helm waits for hooks to complete.
+	Timeout  time.Duration
+	Username string
+}
+
+// DefaultConfig provides a default configuration to create a new Helm SDK
+// Installer by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		ActionConfig:         nil,
+		Configurers:          nil,
+		HTTPClient:           nil,
+		Logger:               nil,
+		OCIClient:            nil,
+		ProjectConfiguration: nil,
+		ProjectLister:        nil,
+
+		// Settings.
+		ChartTemplate: "",
+		Organisation:  "",
+		Password:      "",
+		Registry:      "",
+		Timeout:       5 * time.Minute,
+		Username:      "",
+	}
+}
+
+// New creates a new configured Helm SDK Installer.
+func New(config Config) (*HelmSDKInstaller, error) {
+	// Dependencies.
+	if config.ActionConfig == nil {
+		return nil, microerror.Maskf(invalidConfigError, "action config must not be empty")
+	}
+	if config.Configurers == nil {
+		return nil, microerror.Maskf(invalidConfigError, "configurers must not be empty")
+	}
+	if config.HTTPClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+	if config.OCIClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "oci client must not be empty")
+	}
+	if config.ProjectConfiguration == nil {
+		return nil, microerror.Maskf(invalidConfigError, "project configuration must not be empty")
+	}
+	if config.ProjectLister == nil {
+		return nil, microerror.Maskf(invalidConfigError, "project lister must not be empty")
+	}
+
+	// Settings.
+	if config.ChartTemplate != "" && !oci.IsReference(config.ChartTemplate) {
+		return nil, microerror.Maskf(invalidConfigError, "chart template must refer to an oci registry")
+	}
+	if _, err := (configuration.Project{}).ChartReference(config.ChartTemplate, configuration.ChartReferenceData{}); err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "chart template: %s", err.Error())
+	}
+	if config.Organisation == "" {
+		return nil, microerror.Maskf(invalidConfigError, "organisation must not be empty")
+	}
+	if config.Registry == "" {
+		return nil, microerror.Maskf(invalidConfigError, "registry must not be empty")
+	}
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "timeout must be greater than zero")
+	}
+
+	installer := &HelmSDKInstaller{
+		// Dependencies.
+		actionConfig:  config.ActionConfig,
+		client:        config.HTTPClient,
+		configurers:   config.Configurers,
+		logger:        config.Logger,
+		ociClient:     config.OCIClient,
+		projectLister: config.ProjectLister,
+		projects:      config.ProjectConfiguration,
+
+		// Settings.
+		chartTemplate: config.ChartTemplate,
+		organisation:  config.Organisation,
+		password:      config.Password,
+		registry:      config.Registry,
+		timeout:       config.Timeout,
+		username:      config.Username,
+	}
+
+	go installer.fetchMetrics()
+
+	return installer, nil
+}
+
+// HelmSDKInstaller is an implementation of the Installer interface, that
+// uses the Helm SDK to install charts, without depending on a Helm binary.
+type HelmSDKInstaller struct {
+	// Dependencies.
+	actionConfig  ActionConfigFunc
+	client        httpspec.Client
+	configurers   []configurerspec.Configurer
+	logger        micrologger.Logger
+	ociClient     *oci.Client
+	projectLister eventerspec.ProjectLister
+	projects      configuration.Getter
+
+	// Settings.
+	chartTemplate string
+	organisation  string
+	password      string
+	registry      string
+	timeout       time.Duration
+	username      string
+}
+
+// Install installs the chart of the deployment. Cancelling the context
+// aborts pulling the chart, and the Helm action, if it did not start yet.
+// Helm actions that started are bounded by the timeout, or the deadline of
+// the context, if it is earlier.
+func (i *HelmSDKInstaller) Install(ctx context.Context, event eventerspec.DeploymentEvent) error {
+	switch event.Task {
+	case "", eventerspec.DeployTask:
+		return i.upgrade(ctx, event)
+	case eventerspec.RollbackTask:
+		return i.rollback(ctx, event)
+	default:
+		return microerror.Maskf(unsupportedTaskError, "task %#q is not supported", event.Task)
+	}
+}
+
+// releaseNamespace returns the namespace the release of the deployed project
+// lives in. Deployments may request a namespace in their payload, if the
+// project allows it.
+func (i *HelmSDKInstaller) releaseNamespace(event eventerspec.DeploymentEvent) (string, error) {
+	namespace, err := payload.Namespace(i.projects.Project(event.Name), event.Payload)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return namespace, nil
+}
+
+// actionTimeout returns the timeout of Helm actions, shortened to the
+// deadline of the context, if there is one.
+func (i *HelmSDKInstaller) actionTimeout(ctx context.Context) time.Duration {
+	timeout := i.timeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	return timeout
+}
+
+// rollback rolls the release of the deployed project back to its previous
+// revision.
+func (i *HelmSDKInstaller) rollback(ctx context.Context, event eventerspec.DeploymentEvent) error {
+	releaseName := i.projects.Project(event.Name).Release
+	namespace, err := i.releaseNamespace(event)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	i.logger.Log("debug", "rolling back release", "name", event.Name, "release", releaseName, "namespace", namespace, "dryRun", event.Payload.DryRun)
+
+	cfg, err := i.actionConfig(namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return microerror.Maskf(cancelledError, err.Error())
+	}
+
+	rollback := action.NewRollback(cfg)
+	rollback.DryRun = event.Payload.DryRun
+	rollback.Timeout = i.actionTimeout(ctx)
+
+	defer updateActionMetrics("rollback", time.Now())
+
+	err = rollback.Run(releaseName)
+	if err != nil {
+		return microerror.Maskf(releaseFailedError, "rollback of release %#q: %s", releaseName, err.Error())
+	}
+
+	return nil
+}
+
+// upgrade installs or upgrades the release of the deployed project to the
+// chart of the deployed sha.
+func (i *HelmSDKInstaller) upgrade(ctx context.Context, event eventerspec.DeploymentEvent) error {
+	project := i.projects.Project(event.Name)
+	namespace, err := i.releaseNamespace(event)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	version := event.ChartVersion()
+
+	i.logger.Log("debug", "installing chart", "name", project.Name, "release", project.Release, "namespace", namespace, "sha", event.Sha, "version", version, "dryRun", event.Payload.DryRun)
+
+	c, err := i.pullChart(ctx, project, event, version)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	vals, err := i.values(project, event.Payload)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	cfg, err := i.actionConfig(namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return microerror.Maskf(cancelledError, err.Error())
+	}
+
+	last, err := lastRelease(cfg, project.Release)
+	if err != nil {
+		return microerror.Maskf(releaseFailedError, "history of release %#q: %s", project.Release, err.Error())
+	}
+
+	if last == nil {
+		err = i.install(ctx, cfg, project, namespace, c, vals, event.Payload.DryRun)
+	} else {
+		err = i.runUpgrade(ctx, cfg, project, namespace, c, vals, event.Payload.DryRun)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// install installs the first release of the project.
+func (i *HelmSDKInstaller) install(ctx context.Context, cfg *action.Configuration, project configuration.Project, namespace string, c *chart.Chart, vals map[string]interface{}, dryRun bool) error {
+	install := action.NewInstall(cfg)
+	install.DryRun = dryRun
+	install.Namespace = namespace
+	install.ReleaseName = project.Release
+	install.Timeout = i.actionTimeout(ctx)
+
+	defer updateActionMetrics("install", time.Now())
+
+	_, err := install.Run(c, vals)
+	if err != nil {
+		return microerror.Maskf(releaseFailedError, "install of release %#q: %s", project.Release, err.Error())
+	}
+
+	return nil
+}
+
+// runUpgrade upgrades the existing release of the project.
+func (i *HelmSDKInstaller) runUpgrade(ctx context.Context, cfg *action.Configuration, project configuration.Project, namespace string, c *chart.Chart, vals map[string]interface{}, dryRun bool) error {
+	upgrade := action.NewUpgrade(cfg)
+	upgrade.DryRun = dryRun
+	upgrade.Force = project.Force
+	upgrade.Namespace = namespace
+	upgrade.Timeout = i.actionTimeout(ctx)
+
+	defer updateActionMetrics("upgrade", time.Now())
+
+	_, err := upgrade.Run(project.Release, c, vals)
+	if err != nil {
+		return microerror.Maskf(releaseFailedError, "upgrade of release %#q: %s", project.Release, err.Error())
+	}
+
+	return nil
+}
+
+// lastRelease returns the last revision of the release, or nil if there is
+// none.
+func lastRelease(cfg *action.Configuration, name string) (*release.Release, error) {
+	releases, err := cfg.Releases.History(name)
+	if err == driver.ErrReleaseNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var last *release.Release
+	for _, r := range releases {
+		if last == nil || r.Version > last.Version {
+			last = r
+		}
+	}
+
+	return last, nil
+}
+
+// fetchMetrics reports the status of the releases of the listed projects,
+// every poll interval.
+func (i *HelmSDKInstaller) fetchMetrics() {
+	ticker := time.NewTicker(pollInterval)
+
+	for c := ticker.C; ; <-c {
+		i.logger.Log("debug", "fetching metrics")
+		i.checkReleases(i.projectLister.ProjectList())
+		i.logger.Log("debug", "fetched metrics")
+	}
+}
+
+func (i *HelmSDKInstaller) checkReleases(projectList []string) {
+	// Reset the last metrics in this vector so only new metrics could be reported.
+	releaseStatus.Reset()
+	for _, prj := range projectList {
+		project := i.projects.Project(prj)
+
+		cfg, err := i.actionConfig(project.Namespace)
+		if err != nil {
+			i.logger.Log("error", "could not create helm action config", "message", err.Error())
+			continue
+		}
+
+		last, err := lastRelease(cfg, project.Release)
+		if err != nil {
+			i.logger.Log("error", fmt.Sprintf("could not find helm release %#q history", project.Release), "message", err.Error())
+			continue
+		}
+
+		if last != nil {
+			reportRelease(prj, last.Info.Status.String())
+		} else {
+			reportRelease(prj, "not-found")
+		}
+	}
+}
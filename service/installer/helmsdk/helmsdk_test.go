@@ -0,0 +1,333 @@
+package helmsdk
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	configurerspec "github.com/giantswarm/draughtsman/service/configurer/spec"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+	"github.com/giantswarm/draughtsman/service/installer/oci"
+	"github.com/giantswarm/draughtsman/service/installer/payload"
+)
+
+// testConfigurer is a Configurer providing fixed values.
+type testConfigurer string
+
+func (c testConfigurer) Type() configurerspec.ConfigurerType {
+	return "TestConfigurer"
+}
+
+func (c testConfigurer) Values() (string, error) {
+	return string(c), nil
+}
+
+// testProjectLister lists no projects.
+type testProjectLister struct{}
+
+func (l testProjectLister) ProjectList() []string {
+	return nil
+}
+
+// testClient serves the given charts, by URL.
+type testClient map[string][]byte
+
+func (c testClient) Do(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	b, ok := c[req.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(&bytes.Buffer{})}, nil
+	}
+
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(b))}, nil
+}
+
+// testChart returns the archive of a chart of the given name and version,
+// rendering its replicas value into a configmap.
+func testChart(t *testing.T, name, version string) []byte {
+	files := map[string]string{
+		name + "/Chart.yaml":               "apiVersion: v2\nname: " + name + "\nversion: " + version + "\n",
+		name + "/templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\ndata:\n  replicas: \"{{ .Values.replicas }}\"\n",
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for fileName, content := range files {
+		err := tw.WriteHeader(&tar.Header{Name: fileName, Mode: 0644, Size: int64(len(content))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// testActionConfig returns an ActionConfigFunc storing releases in memory,
+// and returning the action configuration of every namespace.
+func testActionConfig() (ActionConfigFunc, map[string]*action.Configuration) {
+	configs := map[string]*action.Configuration{}
+
+	return func(namespace string) (*action.Configuration, error) {
+		if _, ok := configs[namespace]; !ok {
+			configs[namespace] = &action.Configuration{
+				Capabilities: chartutil.DefaultCapabilities,
+				KubeClient:   &kubefake.PrintingKubeClient{Out: ioutil.Discard},
+				Log:          func(string, ...interface{}) {},
+				Releases:     storage.Init(driver.NewMemory()),
+			}
+		}
+
+		return configs[namespace], nil
+	}, configs
+}
+
+// testOCIChart adds the manifest and blob of the chart to the client, as
+// served by an OCI registry.
+func testOCIChart(client testClient, repository, tag string, chart []byte) {
+	sum := sha256.Sum256(chart)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	client["https://localhost:5000/v2/"+repository+"/manifests/"+tag] = []byte(fmt.Sprintf(`{"layers": [{"mediaType": "application/vnd.cncf.helm.chart.content.v1.tar+gzip", "digest": %q}]}`, digest))
+	client["https://localhost:5000/v2/"+repository+"/blobs/"+digest] = chart
+}
+
+func newTestInstaller(t *testing.T, actionConfig ActionConfigFunc) *HelmSDKInstaller {
+	client := testClient{
+		"https://quay.io/cnr/api/v1/packages/giantswarm/api-chart/1.0.0-a/helm/pull": testChart(t, "api-chart", "1.0.0-a"),
+		"https://quay.io/cnr/api/v1/packages/giantswarm/api-chart/1.0.0-b/helm/pull": testChart(t, "api-chart", "1.0.0-b"),
+	}
+	testOCIChart(client, "charts/web", "1.0.0-a", testChart(t, "web", "1.0.0-a"))
+
+	var ociClient *oci.Client
+	{
+		c := oci.DefaultConfig()
+
+		c.HTTPClient = client
+		c.Logger = microloggertest.New()
+
+		var err error
+		ociClient, err = oci.New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := DefaultConfig()
+
+	c.ActionConfig = actionConfig
+	c.Configurers = []configurerspec.Configurer{
+		testConfigurer("replicas: 1\nimage:\n  tag: latest\n"),
+		testConfigurer("replicas: 2\n"),
+	}
+	c.HTTPClient = client
+	c.Logger = microloggertest.New()
+	c.OCIClient = ociClient
+	c.ProjectConfiguration = configuration.Manifest{
+		Projects: []configuration.Project{
+			{Name: "api", Namespace: "giantswarm", Release: "api-release", PayloadValues: []string{"replicas"}},
+			{Name: "web", Namespace: "giantswarm", Chart: "oci://localhost:5000/charts/{{ .Project }}:{{ .Version }}"},
+		},
+	}
+	c.ProjectLister = testProjectLister{}
+
+	c.Organisation = "giantswarm"
+	c.Registry = "quay.io"
+	c.Timeout = time.Minute
+
+	i, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return i
+}
+
+// TestInstall tests installing, upgrading, and rolling back a release.
+func TestInstall(t *testing.T) {
+	actionConfig, configs := testActionConfig()
+	i := newTestInstaller(t, actionConfig)
+
+	tests := []struct {
+		event            eventerspec.DeploymentEvent
+		expectedVersion  int
+		expectedChart    string
+		expectedReplicas interface{}
+	}{
+		{
+			event:            eventerspec.DeploymentEvent{Name: "api", Sha: "a"},
+			expectedVersion:  1,
+			expectedChart:    "1.0.0-a",
+			expectedReplicas: float64(2),
+		},
+		{
+			event: eventerspec.DeploymentEvent{
+				Name:    "api",
+				Sha:     "b",
+				Payload: eventerspec.DeploymentPayload{Values: map[string]string{"replicas": "3"}},
+			},
+			expectedVersion:  2,
+			expectedChart:    "1.0.0-b",
+			expectedReplicas: "3",
+		},
+		{
+			event:            eventerspec.DeploymentEvent{Name: "api", Task: eventerspec.RollbackTask},
+			expectedVersion:  3,
+			expectedChart:    "1.0.0-a",
+			expectedReplicas: float64(2),
+		},
+	}
+
+	for index, test := range tests {
+		err := i.Install(context.Background(), test.event)
+		if err != nil {
+			t.Fatalf("%v\nunexpected error: %#v\n", index, err)
+		}
+
+		last, err := lastRelease(configs["giantswarm"], "api-release")
+		if err != nil {
+			t.Fatalf("%v\nunexpected error: %#v\n", index, err)
+		}
+
+		if last.Version != test.expectedVersion {
+			t.Fatalf("%v\nexpected version: %#v\nreturned version: %#v\n", index, test.expectedVersion, last.Version)
+		}
+		if last.Info.Status != release.StatusDeployed {
+			t.Fatalf("%v\nexpected status: %#v\nreturned status: %#v\n", index, release.StatusDeployed, last.Info.Status)
+		}
+		if last.Chart.Metadata.Version != test.expectedChart {
+			t.Fatalf("%v\nexpected chart: %#v\nreturned chart: %#v\n", index, test.expectedChart, last.Chart.Metadata.Version)
+		}
+		if last.Config["replicas"] != test.expectedReplicas {
+			t.Fatalf("%v\nexpected replicas: %#v\nreturned replicas: %#v\n", index, test.expectedReplicas, last.Config["replicas"])
+		}
+		if tag := last.Config["image"].(map[string]interface{})["tag"]; tag != "latest" {
+			t.Fatalf("%v\nexpected tag: %#v\nreturned tag: %#v\n", index, "latest", tag)
+		}
+	}
+}
+
+// TestInstallOCIChart tests installing a chart pulled from an OCI registry.
+func TestInstallOCIChart(t *testing.T) {
+	actionConfig, configs := testActionConfig()
+	i := newTestInstaller(t, actionConfig)
+
+	err := i.Install(context.Background(), eventerspec.DeploymentEvent{Name: "web", Sha: "a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+
+	last, err := lastRelease(configs["giantswarm"], "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+
+	if last.Chart.Metadata.Name != "web" {
+		t.Fatalf("expected chart: %#v\nreturned chart: %#v\n", "web", last.Chart.Metadata.Name)
+	}
+
+	err = i.Install(context.Background(), eventerspec.DeploymentEvent{Name: "web", Sha: "b"})
+	if !IsPullFailed(err) {
+		t.Fatalf("expected matching error\nreturned: %#v\n", err)
+	}
+}
+
+// TestInstallErrors tests the errors returned when installing fails.
+func TestInstallErrors(t *testing.T) {
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		ctx          context.Context
+		event        eventerspec.DeploymentEvent
+		errorMatcher func(error) bool
+	}{
+		{
+			ctx:          cancelledCtx,
+			event:        eventerspec.DeploymentEvent{Name: "api", Sha: "a"},
+			errorMatcher: IsCancelled,
+		},
+		{
+			ctx:          context.Background(),
+			event:        eventerspec.DeploymentEvent{Name: "api", Sha: "c"},
+			errorMatcher: IsPullFailed,
+		},
+		{
+			ctx:          context.Background(),
+			event:        eventerspec.DeploymentEvent{Name: "api", Task: eventerspec.RollbackTask},
+			errorMatcher: IsReleaseFailed,
+		},
+		{
+			ctx:          context.Background(),
+			event:        eventerspec.DeploymentEvent{Name: "api", Task: "migrate"},
+			errorMatcher: IsUnsupportedTask,
+		},
+		{
+			ctx: context.Background(),
+			event: eventerspec.DeploymentEvent{
+				Name:    "api",
+				Sha:     "a",
+				Payload: eventerspec.DeploymentPayload{Values: map[string]string{"image.tag": "1.2.3"}},
+			},
+			errorMatcher: payload.IsForbiddenPayload,
+		},
+		{
+			ctx: context.Background(),
+			event: eventerspec.DeploymentEvent{
+				Name:    "api",
+				Sha:     "a",
+				Payload: eventerspec.DeploymentPayload{Namespace: "kube-system"},
+			},
+			errorMatcher: payload.IsForbiddenPayload,
+		},
+	}
+
+	for index, test := range tests {
+		actionConfig, configs := testActionConfig()
+		i := newTestInstaller(t, actionConfig)
+
+		err := i.Install(test.ctx, test.event)
+		if !test.errorMatcher(err) {
+			t.Fatalf("%v\nexpected matching error\nreturned: %#v\n", index, err)
+		}
+
+		if cfg, ok := configs["giantswarm"]; ok {
+			last, err := lastRelease(cfg, "api-release")
+			if err != nil {
+				t.Fatalf("%v\nunexpected error: %#v\n", index, err)
+			}
+			if last != nil {
+				t.Fatalf("%v\nexpected no release\nreturned: %#v\n", index, last.Version)
+			}
+		}
+	}
+}
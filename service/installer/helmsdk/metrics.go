@@ -0,0 +1,75 @@
+package helmsdk
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	gaugeValue float64 = 1
+
+	// prometheusNamespace is the namespace to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusNamespace = "draughtsman"
+
+	// prometheusSubsystem is the subsystem to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusSubsystem = "helm_sdk_installer"
+)
+
+var (
+	actionDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "action_duration_milliseconds",
+			Help:      "Time taken to run Helm actions.",
+		},
+		[]string{"name"},
+	)
+	actionTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "action_total",
+			Help:      "Number of total Helm actions run.",
+		},
+		[]string{"name"},
+	)
+	releaseStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "release_status",
+			Help:      "The status of the last revision of the releases of draughtsman deployments.",
+		},
+		[]string{"name", "status"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(actionDuration)
+	prometheus.MustRegister(actionTotal)
+	prometheus.MustRegister(releaseStatus)
+}
+
+// updateActionMetrics is a utility function for updating metrics related to
+// Helm actions.
+func updateActionMetrics(name string, startTime time.Time) {
+	actionDuration.WithLabelValues(
+		name,
+	).Set(
+		float64(time.Since(startTime) / time.Millisecond),
+	)
+
+	actionTotal.WithLabelValues(
+		name,
+	).Inc()
+}
+
+func reportRelease(name, status string) {
+	releaseStatus.WithLabelValues(
+		name, status,
+	).Set(gaugeValue)
+}
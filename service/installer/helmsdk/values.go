@@ -0,0 +1,69 @@
+package helmsdk
+
+import (
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/strvals"
+
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	"github.com/giantswarm/draughtsman/service/configurer"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+	"github.com/giantswarm/draughtsman/service/installer/payload"
+)
+
+// values returns the values to install the project with. The values of all
+// configurers are merged in order, like multiple Helm values files, followed
+// by the values requested by the deployment payload, like --set-string
+// arguments. Only values the project allows may be requested.
+func (i *HelmSDKInstaller) values(project configuration.Project, p eventerspec.DeploymentPayload) (map[string]interface{}, error) {
+	configurerValues, err := configurer.ProjectValues(i.configurers, project.ValuesKeys)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	vals := map[string]interface{}{}
+	for _, v := range configurerValues {
+		parsed, err := chartutil.ReadValues([]byte(v.Data))
+		if err != nil {
+			return nil, microerror.Maskf(invalidValuesError, "values of %#q: %s", v.Name, err.Error())
+		}
+
+		vals = mergeValues(vals, parsed)
+	}
+
+	requested, err := payload.Values(project, p)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for _, value := range requested {
+		err := strvals.ParseIntoString(value, vals)
+		if err != nil {
+			return nil, microerror.Maskf(invalidValuesError, "payload value %#q: %s", value, err.Error())
+		}
+	}
+
+	return vals, nil
+}
+
+// mergeValues merges the values of b into a, like Helm merges values files.
+// Nested maps are merged, and other values of b replace those of a.
+func mergeValues(a, b map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+
+	for k, v := range b {
+		if v, ok := v.(map[string]interface{}); ok {
+			if outV, ok := out[k].(map[string]interface{}); ok {
+				out[k] = mergeValues(outV, v)
+				continue
+			}
+		}
+
+		out[k] = v
+	}
+
+	return out
+}
@@ -6,14 +6,22 @@ import (
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 
 	"github.com/giantswarm/draughtsman/flag"
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
 	"github.com/giantswarm/draughtsman/service/configurer"
 	configurerspec "github.com/giantswarm/draughtsman/service/configurer/spec"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+	"github.com/giantswarm/draughtsman/service/installer/chartcache"
+	"github.com/giantswarm/draughtsman/service/installer/chartsource"
 	"github.com/giantswarm/draughtsman/service/installer/helm"
+	"github.com/giantswarm/draughtsman/service/installer/helmsdk"
+	"github.com/giantswarm/draughtsman/service/installer/oci"
 	"github.com/giantswarm/draughtsman/service/installer/spec"
 )
 
@@ -21,8 +29,15 @@ import (
 type Config struct {
 	// Dependencies.
 	FileSystem       afero.Fs
+	HTTPClient       httpspec.Client
 	KubernetesClient kubernetes.Interface
 	Logger           micrologger.Logger
+	// ProjectConfiguration looks up the settings of the deployed projects.
+	ProjectConfiguration configuration.Getter
+	// ProjectLister lists the projects whose releases are checked.
+	ProjectLister eventerspec.ProjectLister
+	// RESTConfig is the config of the cluster to install to.
+	RESTConfig *rest.Config
 
 	// Settings.
 	Flag  *flag.Flag
@@ -36,9 +51,13 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		// Dependencies.
-		FileSystem:       afero.NewMemMapFs(),
-		KubernetesClient: nil,
-		Logger:           nil,
+		FileSystem:           afero.NewMemMapFs(),
+		HTTPClient:           nil,
+		KubernetesClient:     nil,
+		Logger:               nil,
+		ProjectConfiguration: nil,
+		ProjectLister:        nil,
+		RESTConfig:           nil,
 
 		// Settings.
 		Flag:  nil,
@@ -79,22 +98,94 @@ func New(config Config) (spec.Installer, error) {
 		configurerServices = append(configurerServices, configurerService)
 	}
 
+	var ociClient *oci.Client
+	{
+		ociConfig := oci.DefaultConfig()
+
+		ociConfig.HTTPClient = config.HTTPClient
+		ociConfig.KubernetesClient = config.KubernetesClient
+		ociConfig.Logger = config.Logger
+
+		ociConfig.PlainHTTP = config.Viper.GetBool(config.Flag.Service.Deployer.Installer.Helm.OCI.PlainHTTP)
+		ociConfig.SecretName = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.OCI.SecretName)
+		ociConfig.SecretNamespace = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.OCI.SecretNamespace)
+
+		ociClient, err = oci.New(ociConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var newInstaller spec.Installer
 	switch config.Type {
 	case helm.HelmInstallerType:
+		var ociSource *chartsource.OCISource
+		{
+			c := chartsource.DefaultOCIConfig()
+
+			c.Client = ociClient
+			c.FileSystem = config.FileSystem
+
+			ociSource, err = chartsource.NewOCI(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		var repositorySource *chartsource.RepositorySource
+		{
+			c := chartsource.DefaultRepositoryConfig()
+
+			c.FileSystem = config.FileSystem
+			c.HTTPClient = config.HTTPClient
+			c.Logger = config.Logger
+
+			c.CAFile = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Repository.CAFile)
+			c.Password = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Repository.Password)
+			c.Timeout = config.Viper.GetDuration(config.Flag.Service.HTTPClient.Timeout)
+			c.Username = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Repository.Username)
+
+			repositorySource, err = chartsource.NewRepository(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		var chartCache *chartcache.Cache
+		if directory := config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Cache.Directory); directory != "" {
+			c := chartcache.DefaultConfig()
+
+			c.FileSystem = config.FileSystem
+			c.Logger = config.Logger
+
+			c.Directory = directory
+			c.MaxSize = config.Viper.GetInt64(config.Flag.Service.Deployer.Installer.Helm.Cache.MaxSize)
+
+			chartCache, err = chartcache.New(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
 		helmConfig := helm.DefaultConfig()
 
+		helmConfig.ChartCache = chartCache
+		helmConfig.ChartSources = []chartsource.Source{ociSource, repositorySource}
 		helmConfig.Configurers = configurerServices
 		helmConfig.FileSystem = config.FileSystem
 		helmConfig.Logger = config.Logger
+		helmConfig.ProjectConfiguration = config.ProjectConfiguration
+		helmConfig.ProjectLister = config.ProjectLister
 
+		helmConfig.Atomic = config.Viper.GetBool(config.Flag.Service.Deployer.Installer.Helm.Atomic)
+		helmConfig.ChartTemplate = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Chart)
 		helmConfig.Environment = config.Viper.GetString(config.Flag.Service.Deployer.Environment)
 		helmConfig.HelmBinaryPath = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.HelmBinaryPath)
-		helmConfig.Namespace = config.Viper.GetString(config.Flag.Release.Namespace)
 		helmConfig.Organisation = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Organisation)
 		helmConfig.Password = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Password)
 		helmConfig.Provider = config.Viper.GetString(config.Flag.Service.Deployer.Provider)
 		helmConfig.Registry = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Registry)
+		helmConfig.Timeout = config.Viper.GetDuration(config.Flag.Service.Deployer.Installer.Helm.Timeout)
 		helmConfig.Username = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Username)
 
 		newInstaller, err = helm.New(helmConfig)
@@ -102,6 +193,34 @@ func New(config Config) (spec.Installer, error) {
 			return nil, microerror.Mask(err)
 		}
 
+	case helmsdk.HelmSDKInstallerType:
+		actionConfig, err := helmsdk.NewActionConfigFunc(config.RESTConfig, config.Logger)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		helmSDKConfig := helmsdk.DefaultConfig()
+
+		helmSDKConfig.ActionConfig = actionConfig
+		helmSDKConfig.Configurers = configurerServices
+		helmSDKConfig.HTTPClient = config.HTTPClient
+		helmSDKConfig.Logger = config.Logger
+		helmSDKConfig.OCIClient = ociClient
+		helmSDKConfig.ProjectConfiguration = config.ProjectConfiguration
+		helmSDKConfig.ProjectLister = config.ProjectLister
+
+		helmSDKConfig.ChartTemplate = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Chart)
+		helmSDKConfig.Organisation = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Organisation)
+		helmSDKConfig.Password = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Password)
+		helmSDKConfig.Registry = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Registry)
+		helmSDKConfig.Timeout = config.Viper.GetDuration(config.Flag.Service.Deployer.Installer.Helm.Timeout)
+		helmSDKConfig.Username = config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.Username)
+
+		newInstaller, err = helmsdk.New(helmSDKConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
 	default:
 		return nil, microerror.Maskf(invalidConfigError, "installer type not implemented")
 	}
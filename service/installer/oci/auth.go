@@ -0,0 +1,86 @@
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/draughtsman/pkg/registryauth"
+)
+
+// credentials returns the credentials of the registry, as held by the
+// Docker config secret. The secret is read for every pull, so that rotated
+// credentials are used right away.
+func (c *Client) credentials(registry string) (credentials, error) {
+	if c.secretName == "" {
+		return credentials{}, nil
+	}
+
+	s, err := c.kubernetesClient.CoreV1().Secrets(c.secretNamespace).Get(c.secretName, metav1.GetOptions{})
+	if err != nil {
+		return credentials{}, microerror.Mask(err)
+	}
+
+	b, ok := s.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return credentials{}, microerror.Maskf(invalidDockerConfigError, "key %#q not found in secret %#q", corev1.DockerConfigJsonKey, c.secretName)
+	}
+
+	return registryCredentials(b, registry)
+}
+
+// registryCredentials returns the credentials of the registry in the given
+// Docker config. Registries without credentials are accessed anonymously.
+func registryCredentials(b []byte, registry string) (credentials, error) {
+	var config dockerConfig
+	if err := json.Unmarshal(b, &config); err != nil {
+		return credentials{}, microerror.Maskf(invalidDockerConfigError, err.Error())
+	}
+
+	for host, auth := range config.Auths {
+		if strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://") != registry {
+			continue
+		}
+
+		if auth.Auth == "" {
+			return credentials{Password: auth.Password, Username: auth.Username}, nil
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return credentials{}, microerror.Maskf(invalidDockerConfigError, "auth of %#q: %s", host, err.Error())
+		}
+
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return credentials{}, microerror.Maskf(invalidDockerConfigError, "auth of %#q must be username:password", host)
+		}
+
+		return credentials{Password: parts[1], Username: parts[0]}, nil
+	}
+
+	return credentials{}, nil
+}
+
+// fetchToken fetches a bearer token from the token service named by the
+// given authentication challenge, authenticated with the credentials.
+func (c *Client) fetchToken(ctx context.Context, challenge string, credentials credentials) (registryauth.Token, error) {
+	do := func(u string) (*http.Response, error) {
+		return c.do(ctx, u, "application/json", "", credentials)
+	}
+
+	token, err := registryauth.FetchToken(challenge, do)
+	if registryauth.IsInvalidChallenge(err) || registryauth.IsUnexpectedStatusCode(err) {
+		return registryauth.Token{}, microerror.Maskf(unexpectedStatusCodeError, err.Error())
+	} else if err != nil {
+		return registryauth.Token{}, microerror.Mask(err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,77 @@
+package oci
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var cancelledError = &microerror.Error{
+	Kind: "cancelledError",
+}
+
+// IsCancelled asserts cancelledError.
+func IsCancelled(err error) bool {
+	return microerror.Cause(err) == cancelledError
+}
+
+var digestMismatchError = &microerror.Error{
+	Kind: "digestMismatchError",
+}
+
+// IsDigestMismatch asserts digestMismatchError.
+func IsDigestMismatch(err error) bool {
+	return microerror.Cause(err) == digestMismatchError
+}
+
+var invalidDockerConfigError = &microerror.Error{
+	Kind: "invalidDockerConfigError",
+}
+
+// IsInvalidDockerConfig asserts invalidDockerConfigError.
+func IsInvalidDockerConfig(err error) bool {
+	return microerror.Cause(err) == invalidDockerConfigError
+}
+
+var invalidManifestError = &microerror.Error{
+	Kind: "invalidManifestError",
+}
+
+// IsInvalidManifest asserts invalidManifestError.
+func IsInvalidManifest(err error) bool {
+	return microerror.Cause(err) == invalidManifestError
+}
+
+var invalidReferenceError = &microerror.Error{
+	Kind: "invalidReferenceError",
+}
+
+// IsInvalidReference asserts invalidReferenceError.
+func IsInvalidReference(err error) bool {
+	return microerror.Cause(err) == invalidReferenceError
+}
+
+var notFoundError = &microerror.Error{
+	Kind: "notFoundError",
+}
+
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
+}
+
+var unexpectedStatusCodeError = &microerror.Error{
+	Kind: "unexpectedStatusCodeError",
+}
+
+// IsUnexpectedStatusCode asserts unexpectedStatusCodeError.
+func IsUnexpectedStatusCode(err error) bool {
+	return microerror.Cause(err) == unexpectedStatusCodeError
+}
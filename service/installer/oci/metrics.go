@@ -0,0 +1,48 @@
+package oci
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// prometheusNamespace is the namespace to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusNamespace = "draughtsman"
+
+	// prometheusSubsystem is the subsystem to use for Prometheus metrics.
+	// See: https://godoc.org/github.com/prometheus/client_golang/prometheus#Opts
+	prometheusSubsystem = "oci_client"
+)
+
+var (
+	pullDuration = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "pull_duration_milliseconds",
+			Help:      "Time taken to pull charts from OCI registries.",
+		},
+	)
+	pullTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "pull_total",
+			Help:      "Number of total charts pulled from OCI registries.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(pullDuration)
+	prometheus.MustRegister(pullTotal)
+}
+
+// updatePullMetrics is a utility function for updating metrics related to
+// chart pulls.
+func updatePullMetrics(startTime time.Time) {
+	pullDuration.Set(float64(time.Since(startTime) / time.Millisecond))
+	pullTotal.Inc()
+}
@@ -0,0 +1,295 @@
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/draughtsman/pkg/registryauth"
+	httpspec "github.com/giantswarm/draughtsman/service/http"
+)
+
+const (
+	// manifestUrlFormat is the string format for the OCI distribution API
+	// call for a Manifest. Templated with the scheme, registry, repository
+	// and tag.
+	manifestUrlFormat = "%s://%s/v2/%s/manifests/%s"
+
+	// blobUrlFormat is the string format for the OCI distribution API call
+	// for a Blob. Templated with the scheme, registry, repository and
+	// digest.
+	blobUrlFormat = "%s://%s/v2/%s/blobs/%s"
+
+	// manifestMediaType is the media type of OCI image manifests, which
+	// charts are pushed as.
+	manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// chartLayerMediaTypes are the media types of the layer holding the chart
+// archive, as pushed by Helm 3.8 and later, and by earlier experimental
+// releases.
+var chartLayerMediaTypes = []string{
+	"application/vnd.cncf.helm.chart.content.v1.tar+gzip",
+	"application/tar+gzip",
+}
+
+// Config represents the configuration used to create an OCI Client.
+type Config struct {
+	// Dependencies.
+	HTTPClient       httpspec.Client
+	KubernetesClient kubernetes.Interface
+	Logger           micrologger.Logger
+
+	// Settings.
+
+	// PlainHTTP talks to registries over HTTP instead of HTTPS, e.g. to a
+	// local registry during development.
+	PlainHTTP bool
+	// SecretName is the name of the Docker config secret holding the
+	// credentials of the registries. Registries are accessed anonymously
+	// when empty.
+	SecretName      string
+	SecretNamespace string
+}
+
+// DefaultConfig provides a default configuration to create a new OCI Client
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		HTTPClient:       nil,
+		KubernetesClient: nil,
+		Logger:           nil,
+
+		// Settings.
+		PlainHTTP:       false,
+		SecretName:      "",
+		SecretNamespace: "",
+	}
+}
+
+// New creates a new configured OCI Client.
+func New(config Config) (*Client, error) {
+	// Dependencies.
+	if config.HTTPClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "http client must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	// Settings.
+	if config.SecretName != "" {
+		if config.KubernetesClient == nil {
+			return nil, microerror.Maskf(invalidConfigError, "kubernetes client must not be empty")
+		}
+		if config.SecretNamespace == "" {
+			return nil, microerror.Maskf(invalidConfigError, "secret namespace must not be empty")
+		}
+	}
+
+	scheme := "https"
+	if config.PlainHTTP {
+		scheme = "http"
+	}
+
+	client := &Client{
+		// Dependencies.
+		client:           config.HTTPClient,
+		kubernetesClient: config.KubernetesClient,
+		logger:           config.Logger,
+
+		// Internals.
+		tokens:      map[string]registryauth.Token{},
+		tokensMutex: &sync.Mutex{},
+
+		// Settings.
+		scheme:          scheme,
+		secretName:      config.SecretName,
+		secretNamespace: config.SecretNamespace,
+	}
+
+	return client, nil
+}
+
+// Client pulls charts from OCI registries, following the OCI distribution
+// API.
+type Client struct {
+	// Dependencies.
+	client           httpspec.Client
+	kubernetesClient kubernetes.Interface
+	logger           micrologger.Logger
+
+	// Internals.
+
+	// tokens holds the bearer token of every repository, keyed by registry
+	// and repository, e.g: quay.io/giantswarm/api-chart, as issued by the
+	// token service of the registry.
+	tokens      map[string]registryauth.Token
+	tokensMutex *sync.Mutex
+
+	// Settings.
+	scheme          string
+	secretName      string
+	secretNamespace string
+}
+
+// Pull pulls the chart archive of the given reference, e.g:
+// oci://quay.io/giantswarm/api-chart:1.0.0-a1b2c3. The digest of the archive
+// is verified against the manifest.
+func (c *Client) Pull(ctx context.Context, reference string) ([]byte, error) {
+	defer updatePullMetrics(time.Now())
+
+	ref, err := ParseReference(reference)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c.logger.Log("debug", "pulling chart", "reference", reference)
+
+	credentials, err := c.credentials(ref.Registry)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var m manifest
+	{
+		u := fmt.Sprintf(manifestUrlFormat, c.scheme, ref.Registry, ref.Repository, ref.Tag)
+
+		b, err := c.request(ctx, u, manifestMediaType, ref, credentials)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, microerror.Maskf(invalidManifestError, "manifest of %#q: %s", reference, err.Error())
+		}
+	}
+
+	layer, ok := m.chartLayer()
+	if !ok {
+		return nil, microerror.Maskf(invalidManifestError, "manifest of %#q has no chart layer", reference)
+	}
+
+	u := fmt.Sprintf(blobUrlFormat, c.scheme, ref.Registry, ref.Repository, layer.Digest)
+
+	b, err := c.request(ctx, u, layer.MediaType, ref, credentials)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if err := verifyDigest(b, layer.Digest); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
+
+// request makes a GET request to the registry. Requests that are challenged
+// for authentication are retried with a bearer token of the repository, or
+// basic authentication, as requested by the challenge. Tokens are reused
+// until they expire.
+func (c *Client) request(ctx context.Context, u, accept string, ref Reference, credentials credentials) ([]byte, error) {
+	tokenKey := ref.Registry + "/" + ref.Repository
+
+	var token string
+	c.tokensMutex.Lock()
+	if cached := c.tokens[tokenKey]; cached.Valid() {
+		token = cached.Value
+	}
+	c.tokensMutex.Unlock()
+
+	resp, err := c.do(ctx, u, accept, token, credentials)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("Www-Authenticate")
+
+		var token string
+		if !strings.HasPrefix(challenge, basicPrefix) {
+			fetched, err := c.fetchToken(ctx, challenge, credentials)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			c.tokensMutex.Lock()
+			c.tokens[tokenKey] = fetched
+			c.tokensMutex.Unlock()
+
+			token = fetched.Value
+		}
+
+		resp, err = c.do(ctx, u, accept, token, credentials)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		defer resp.Body.Close()
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, microerror.Maskf(notFoundError, "%#q", u)
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, microerror.Maskf(unexpectedStatusCodeError, "received status code: %v, body: %q", resp.StatusCode, string(b))
+	}
+
+	return b, nil
+}
+
+// do makes an authenticated GET request, using the bearer token if there is
+// one, and basic authentication otherwise.
+func (c *Client) do(ctx context.Context, u, accept, token string, credentials credentials) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", accept)
+
+	if token != "" {
+		req.Header.Set("Authorization", registryauth.BearerPrefix+token)
+	} else if credentials.Username != "" {
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if ctx.Err() != nil {
+		return nil, microerror.Maskf(cancelledError, ctx.Err().Error())
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return resp, nil
+}
+
+// verifyDigest verifies the content against the given digest, e.g:
+// sha256:9f86d08...
+func verifyDigest(b []byte, digest string) error {
+	if !strings.HasPrefix(digest, sha256Prefix) {
+		return microerror.Maskf(digestMismatchError, "unsupported digest algorithm of %#q", digest)
+	}
+
+	sum := sha256.Sum256(b)
+	if sha256Prefix+hex.EncodeToString(sum[:]) != digest {
+		return microerror.Maskf(digestMismatchError, "content does not match digest %#q", digest)
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testRegistry returns a server behaving like a registry:2 server, that
+// challenges requests for the given bearer token issued to the given user,
+// and serves the given chart under the api-chart:1.0.0-a tag.
+func testRegistry(t *testing.T, username, password, token string, chart []byte, digest string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, pass, _ := r.BasicAuth()
+			if user != username || pass != password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:giantswarm/api-chart:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprintf(w, `{"token": %q}`, token)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:giantswarm/api-chart:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/giantswarm/api-chart/manifests/1.0.0-a":
+			w.Header().Set("Content-Type", manifestMediaType)
+			fmt.Fprintf(w, `{"schemaVersion": 2, "layers": [{"mediaType": "application/vnd.cncf.helm.chart.content.v1.tar+gzip", "digest": %q}]}`, digest)
+		case "/v2/giantswarm/api-chart/blobs/" + digest:
+			w.Write(chart)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server
+}
+
+// testSecret returns a Docker config secret holding the credentials of the
+// given registry.
+func testSecret(registry, username, password string) *corev1.Secret {
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-credentials", Namespace: "draughtsman"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, registry, auth)),
+		},
+	}
+}
+
+// TestPull tests pulling charts from a registry.
+func TestPull(t *testing.T) {
+	chart := []byte("chart archive")
+	sum := sha256.Sum256(chart)
+	digest := sha256Prefix + hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		username      string
+		password      string
+		digest        string
+		reference     string
+		secretName    string
+		expectedChart []byte
+		errorMatcher  func(error) bool
+	}{
+		// Test that the chart is pulled with the credentials of the secret.
+		{
+			username:      "robot",
+			password:      "pass",
+			digest:        digest,
+			reference:     "oci://%s/giantswarm/api-chart:1.0.0-a",
+			secretName:    "registry-credentials",
+			expectedChart: chart,
+		},
+		// Test that the chart is pulled anonymously without secret.
+		{
+			username:      "",
+			password:      "",
+			digest:        digest,
+			reference:     "oci://%s/giantswarm/api-chart:1.0.0-a",
+			expectedChart: chart,
+		},
+		// Test that charts with unknown tags are not found.
+		{
+			username:     "robot",
+			password:     "pass",
+			digest:       digest,
+			reference:    "oci://%s/giantswarm/api-chart:1.0.0-b",
+			secretName:   "registry-credentials",
+			errorMatcher: IsNotFound,
+		},
+		// Test that charts not matching their digest are rejected.
+		{
+			username:     "robot",
+			password:     "pass",
+			digest:       sha256Prefix + strings.Repeat("0", 64),
+			reference:    "oci://%s/giantswarm/api-chart:1.0.0-a",
+			secretName:   "registry-credentials",
+			errorMatcher: IsDigestMismatch,
+		},
+		// Test that wrong credentials are rejected.
+		{
+			username:     "robot",
+			password:     "other",
+			digest:       digest,
+			reference:    "oci://%s/giantswarm/api-chart:1.0.0-a",
+			errorMatcher: IsUnexpectedStatusCode,
+		},
+	}
+
+	for index, test := range tests {
+		server := testRegistry(t, test.username, test.password, "secret-token", chart, test.digest)
+		registry := strings.TrimPrefix(server.URL, "http://")
+
+		c := DefaultConfig()
+
+		c.HTTPClient = http.DefaultClient
+		c.KubernetesClient = fake.NewSimpleClientset(testSecret(registry, "robot", "pass"))
+		c.Logger = microloggertest.New()
+
+		c.PlainHTTP = true
+		c.SecretName = test.secretName
+		c.SecretNamespace = "draughtsman"
+
+		client, err := New(c)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %#v", index, err)
+		}
+
+		b, err := client.Pull(context.Background(), fmt.Sprintf(test.reference, registry))
+		server.Close()
+
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v: unexpected error returned: %#v", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+
+		if string(b) != string(test.expectedChart) {
+			t.Fatalf("%v\nexpected: %q\nreturned: %q\n", index, test.expectedChart, b)
+		}
+	}
+}
+
+// TestPullTokenPerRegistry tests that the bearer tokens of a repository are
+// not reused for the same repository in other registries.
+func TestPullTokenPerRegistry(t *testing.T) {
+	chart := []byte("chart archive")
+	sum := sha256.Sum256(chart)
+	digest := sha256Prefix + hex.EncodeToString(sum[:])
+
+	c := DefaultConfig()
+
+	c.HTTPClient = http.DefaultClient
+	c.KubernetesClient = fake.NewSimpleClientset()
+	c.Logger = microloggertest.New()
+
+	c.PlainHTTP = true
+	c.SecretNamespace = "draughtsman"
+
+	client, err := New(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+
+	for _, token := range []string{"first-token", "second-token"} {
+		server := testRegistry(t, "", "", token, chart, digest)
+		registry := strings.TrimPrefix(server.URL, "http://")
+
+		b, err := client.Pull(context.Background(), fmt.Sprintf("oci://%s/giantswarm/api-chart:1.0.0-a", registry))
+		server.Close()
+
+		if err != nil {
+			t.Fatalf("%s: expected nil error, returned: %#v", token, err)
+		}
+		if string(b) != string(chart) {
+			t.Fatalf("%s\nexpected: %q\nreturned: %q\n", token, chart, b)
+		}
+	}
+
+	if len(client.tokens) != 2 {
+		t.Fatalf("expected a token per registry, returned: %#v", client.tokens)
+	}
+}
+
+// TestParseReference tests parsing chart references.
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		reference         string
+		expectedReference Reference
+		errorMatcher      func(error) bool
+	}{
+		{
+			reference:         "oci://quay.io/giantswarm/api-chart:1.0.0-a1b2c3",
+			expectedReference: Reference{Registry: "quay.io", Repository: "giantswarm/api-chart", Tag: "1.0.0-a1b2c3"},
+		},
+		{
+			reference:         "oci://localhost:5000/api-chart:1.0.0",
+			expectedReference: Reference{Registry: "localhost:5000", Repository: "api-chart", Tag: "1.0.0"},
+		},
+		{
+			reference:    "quay.io/giantswarm/api-chart:1.0.0",
+			errorMatcher: IsInvalidReference,
+		},
+		{
+			reference:    "oci://localhost:5000/api-chart",
+			errorMatcher: IsInvalidReference,
+		},
+		{
+			reference:    "oci://quay.io",
+			errorMatcher: IsInvalidReference,
+		},
+	}
+
+	for index, test := range tests {
+		reference, err := ParseReference(test.reference)
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v: unexpected error returned: %#v", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: expected nil error, returned: %#v", index, err)
+		}
+
+		if reference != test.expectedReference {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedReference, reference)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package oci
+
+import (
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// referencePrefix is the prefix of references of charts in OCI
+	// registries.
+	referencePrefix = "oci://"
+)
+
+// Reference represents the reference of a chart in an OCI registry.
+type Reference struct {
+	// Registry is the host of the registry, e.g: quay.io.
+	Registry string
+	// Repository is the repository of the chart, e.g: giantswarm/api-chart.
+	Repository string
+	// Tag is the tag of the chart, e.g: 1.0.0-a1b2c3.
+	Tag string
+}
+
+// IsReference returns true if the given reference is the reference of a
+// chart in an OCI registry.
+func IsReference(reference string) bool {
+	return strings.HasPrefix(reference, referencePrefix)
+}
+
+// ParseReference parses a reference of a chart in an OCI registry, e.g:
+// oci://quay.io/giantswarm/api-chart:1.0.0-a1b2c3.
+func ParseReference(reference string) (Reference, error) {
+	if !IsReference(reference) {
+		return Reference{}, microerror.Maskf(invalidReferenceError, "reference %#q must start with %#q", reference, referencePrefix)
+	}
+
+	rest := strings.TrimPrefix(reference, referencePrefix)
+
+	slash := strings.Index(rest, "/")
+	if slash <= 0 {
+		return Reference{}, microerror.Maskf(invalidReferenceError, "reference %#q must name a registry and repository", reference)
+	}
+
+	colon := strings.LastIndex(rest, ":")
+	if colon < slash {
+		return Reference{}, microerror.Maskf(invalidReferenceError, "reference %#q must name a tag", reference)
+	}
+
+	ref := Reference{
+		Registry:   rest[:slash],
+		Repository: rest[slash+1 : colon],
+		Tag:        rest[colon+1:],
+	}
+	if ref.Repository == "" || ref.Tag == "" {
+		return Reference{}, microerror.Maskf(invalidReferenceError, "reference %#q must name a repository and tag", reference)
+	}
+
+	return ref, nil
+}
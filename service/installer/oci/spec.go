@@ -0,0 +1,52 @@
+package oci
+
+const (
+	// basicPrefix is the prefix of basic authentication challenges.
+	basicPrefix = "Basic "
+
+	// sha256Prefix is the prefix of SHA-256 digests.
+	sha256Prefix = "sha256:"
+)
+
+// manifest represents an OCI image manifest.
+type manifest struct {
+	Layers []descriptor `json:"layers"`
+}
+
+// chartLayer returns the layer holding the chart archive.
+func (m manifest) chartLayer() (descriptor, bool) {
+	for _, mediaType := range chartLayerMediaTypes {
+		for _, layer := range m.Layers {
+			if layer.MediaType == mediaType {
+				return layer, true
+			}
+		}
+	}
+
+	return descriptor{}, false
+}
+
+// descriptor represents an OCI content descriptor.
+type descriptor struct {
+	Digest    string `json:"digest"`
+	MediaType string `json:"mediaType"`
+}
+
+// dockerConfig represents a Docker config, as held by secrets of type
+// kubernetes.io/dockerconfigjson.
+type dockerConfig struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+// dockerAuth represents the credentials of a registry in a Docker config.
+type dockerAuth struct {
+	Auth     string `json:"auth"`
+	Password string `json:"password"`
+	Username string `json:"username"`
+}
+
+// credentials represents the credentials of a registry.
+type credentials struct {
+	Password string
+	Username string
+}
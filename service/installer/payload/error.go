@@ -0,0 +1,15 @@
+package payload
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var forbiddenPayloadError = &microerror.Error{
+	Kind: "forbiddenPayloadError",
+}
+
+// IsForbiddenPayload asserts forbiddenPayloadError, returned when a
+// deployment requests a namespace, or value, its project does not allow.
+func IsForbiddenPayload(err error) bool {
+	return microerror.Cause(err) == forbiddenPayloadError
+}
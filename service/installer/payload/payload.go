@@ -0,0 +1,55 @@
+// Package payload provides the options requested by the payload of
+// deployments to the installers, as far as their project allows them.
+package payload
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+// valueReplacer escapes the characters that Helm parses in --set-string
+// values, so that a value can not set other values, or a list.
+var valueReplacer = strings.NewReplacer(`\`, `\\`, `,`, `\,`, `{`, `\{`)
+
+// Namespace returns the namespace the release of the project is installed
+// to. Deployments may request a namespace in their payload, if the project
+// allows it.
+func Namespace(project configuration.Project, payload eventerspec.DeploymentPayload) (string, error) {
+	if payload.Namespace == "" {
+		return project.Namespace, nil
+	}
+
+	if !project.AllowsPayloadNamespace(payload.Namespace) {
+		return "", microerror.Maskf(forbiddenPayloadError, "project %#q does not allow namespace %#q", project.Name, payload.Namespace)
+	}
+
+	return payload.Namespace, nil
+}
+
+// Values returns the values requested by the payload of the deployment, in a
+// stable order, formatted as Helm --set-string arguments, e.g:
+// image.tag=1.2.3. Values are always strings. Only values the project allows
+// may be set.
+func Values(project configuration.Project, payload eventerspec.DeploymentPayload) ([]string, error) {
+	var keys []string
+	for key := range payload.Values {
+		if !project.AllowsPayloadValue(key) {
+			return nil, microerror.Maskf(forbiddenPayloadError, "project %#q does not allow value %#q", project.Name, key)
+		}
+
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var values []string
+	for _, key := range keys {
+		values = append(values, key+"="+valueReplacer.Replace(payload.Values[key]))
+	}
+
+	return values, nil
+}
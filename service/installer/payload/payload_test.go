@@ -0,0 +1,150 @@
+package payload
+
+import (
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/strvals"
+
+	"github.com/giantswarm/draughtsman/pkg/project/configuration"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
+)
+
+var testProject = configuration.Project{
+	Name:              "api",
+	Namespace:         "draughtsman",
+	PayloadNamespaces: []string{"monitoring"},
+	PayloadValues:     []string{"image.tag", "replicas"},
+}
+
+// TestNamespace tests the Namespace function.
+func TestNamespace(t *testing.T) {
+	tests := []struct {
+		payload           eventerspec.DeploymentPayload
+		expectedNamespace string
+		errorMatcher      func(error) bool
+	}{
+		// Test that the namespace of the project is used by default.
+		{
+			payload:           eventerspec.DeploymentPayload{},
+			expectedNamespace: "draughtsman",
+		},
+
+		// Test that an allowed namespace is used.
+		{
+			payload:           eventerspec.DeploymentPayload{Namespace: "monitoring"},
+			expectedNamespace: "monitoring",
+		},
+
+		// Test that other namespaces are rejected.
+		{
+			payload:      eventerspec.DeploymentPayload{Namespace: "kube-system"},
+			errorMatcher: IsForbiddenPayload,
+		},
+	}
+
+	for index, test := range tests {
+		namespace, err := Namespace(testProject, test.payload)
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected error, returned: %#v\n", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+		}
+
+		if namespace != test.expectedNamespace {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedNamespace, namespace)
+		}
+	}
+}
+
+// TestValues tests the Values function, and that Helm parses the values it
+// returns into the requested values only.
+func TestValues(t *testing.T) {
+	tests := []struct {
+		payload        eventerspec.DeploymentPayload
+		expectedValues []string
+		expectedParsed map[string]interface{}
+		errorMatcher   func(error) bool
+	}{
+		// Test that an empty payload sets no values.
+		{
+			payload:        eventerspec.DeploymentPayload{},
+			expectedValues: nil,
+			expectedParsed: map[string]interface{}{},
+		},
+
+		// Test that values are set as strings, in a stable order.
+		{
+			payload: eventerspec.DeploymentPayload{
+				Values: map[string]string{
+					"replicas":  "2",
+					"image.tag": "1.2.3",
+				},
+			},
+			expectedValues: []string{"image.tag=1.2.3", "replicas=2"},
+			expectedParsed: map[string]interface{}{
+				"image":    map[string]interface{}{"tag": "1.2.3"},
+				"replicas": "2",
+			},
+		},
+
+		// Test that values can not set other values, or lists.
+		{
+			payload: eventerspec.DeploymentPayload{
+				Values: map[string]string{
+					"image.tag": `1,securityContext.privileged=true`,
+					"replicas":  `{1,2}\`,
+				},
+			},
+			expectedValues: []string{`image.tag=1\,securityContext.privileged=true`, `replicas=\{1\,2}\\`},
+			expectedParsed: map[string]interface{}{
+				"image":    map[string]interface{}{"tag": "1,securityContext.privileged=true"},
+				"replicas": `{1,2}\`,
+			},
+		},
+
+		// Test that values the project does not allow are rejected.
+		{
+			payload: eventerspec.DeploymentPayload{
+				Values: map[string]string{
+					"image.tag":                  "1.2.3",
+					"securityContext.privileged": "true",
+				},
+			},
+			errorMatcher: IsForbiddenPayload,
+		},
+	}
+
+	for index, test := range tests {
+		values, err := Values(testProject, test.payload)
+		if test.errorMatcher != nil {
+			if !test.errorMatcher(err) {
+				t.Fatalf("%v\nexpected error, returned: %#v\n", index, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+		}
+
+		if !reflect.DeepEqual(test.expectedValues, values) {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedValues, values)
+		}
+
+		parsed := map[string]interface{}{}
+		for _, value := range values {
+			err := strvals.ParseIntoString(value, parsed)
+			if err != nil {
+				t.Fatalf("%v\nexpected nil error, returned: %#v\n", index, err)
+			}
+		}
+
+		if !reflect.DeepEqual(test.expectedParsed, parsed) {
+			t.Fatalf("%v\nexpected: %#v\nreturned: %#v\n", index, test.expectedParsed, parsed)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package spec
 
 import (
+	"context"
+
 	"github.com/giantswarm/draughtsman/service/eventer/spec"
 )
 
@@ -10,6 +12,7 @@ type InstallerType string
 // Installer represents a Service that installs charts.
 type Installer interface {
 	// Install takes a DeploymentEvent, and installs the referenced chart.
-	// If an error occurs, the returned error will be non-nil.
-	Install(spec.DeploymentEvent) error
+	// Cancelling the context aborts the installation. If an error occurs,
+	// the returned error will be non-nil.
+	Install(context.Context, spec.DeploymentEvent) error
 }
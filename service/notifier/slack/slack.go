@@ -26,6 +26,14 @@ const (
 	titleFormat = "%v - %v"
 	// successMessage is the message for success Slack messages.
 	successMessage = "Successfully deployed"
+	// rollbackSuccessMessage is the message for success Slack messages of
+	// rollbacks.
+	rollbackSuccessMessage = "Successfully rolled back"
+	// dryRunSuffix is appended to the messages of dry run deployments.
+	dryRunSuffix = " (dry run)"
+	// authorFormat is the format for the author of Slack messages.
+	// Templated with the login of the creator of the deployment.
+	authorFormat = "Triggered by %v"
 	// failedMessageFormat is the format for failure Slack messages.
 	// Templated with the error message itself.
 	failedMessageFormat = "Encountered an error ```%v```"
@@ -141,6 +149,18 @@ func (n *SlackNotifier) postSlackMessage(event eventerspec.DeploymentEvent, erro
 	attachment.Text = fmt.Sprintf(failedMessageFormat, errorMessage)
 	if success {
 		attachment.Text = successMessage
+		if event.Task == eventerspec.RollbackTask {
+			attachment.Text = rollbackSuccessMessage
+		}
+	}
+	if event.Payload.DryRun {
+		attachment.Text += dryRunSuffix
+	}
+	if event.Description != "" {
+		attachment.Pretext = event.Description
+	}
+	if event.Creator != "" {
+		attachment.AuthorName = fmt.Sprintf(authorFormat, event.Creator)
 	}
 	attachment.Footer = fmt.Sprintf(footerFormat, n.environment, event.ID)
 
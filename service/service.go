@@ -5,6 +5,8 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/giantswarm/k8sclient/k8srestconfig"
 	"github.com/giantswarm/microendpoint/service/version"
@@ -12,12 +14,17 @@ import (
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
 	"github.com/giantswarm/draughtsman/flag"
 	"github.com/giantswarm/draughtsman/pkg/project/configuration"
 	"github.com/giantswarm/draughtsman/service/deployer"
+	"github.com/giantswarm/draughtsman/service/deployer/history"
+	"github.com/giantswarm/draughtsman/service/eventer"
+	"github.com/giantswarm/draughtsman/service/eventer/github"
+	eventerspec "github.com/giantswarm/draughtsman/service/eventer/spec"
 	"github.com/giantswarm/draughtsman/service/helmmigration"
 	httpspec "github.com/giantswarm/draughtsman/service/http"
 	slackspec "github.com/giantswarm/draughtsman/service/slack"
@@ -44,9 +51,18 @@ type Config struct {
 
 type Service struct {
 	// Dependencies.
-	Deployer     deployer.Deployer
-	HelmMigrator *helmmigration.HelmMigration
-	Version      *version.Service
+	Deployer             deployer.Deployer
+	Eventer              eventerspec.Eventer
+	HelmMigrator         *helmmigration.HelmMigration
+	History              *history.History
+	ProjectConfiguration *configuration.Loader
+	Version              *version.Service
+
+	// Internals.
+	cancel  context.CancelFunc
+	done    chan struct{}
+	mutex   sync.Mutex
+	stopped bool
 }
 
 // New creates a new configured service object.
@@ -101,9 +117,66 @@ func New(config Config) (*Service, error) {
 		return nil, microerror.Mask(err)
 	}
 
-	var projectList []string
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var projectConfiguration *configuration.Loader
 	{
-		projectList = configuration.GetProjectList(config.Viper.GetString(config.Flag.Service.Deployer.Provider), config.Viper.GetString(config.Flag.Service.Deployer.Environment))
+		c := configuration.DefaultConfig()
+
+		c.KubernetesClient = k8sClient
+		c.Logger = config.Logger
+
+		c.Environment = config.Viper.GetString(config.Flag.Service.Deployer.Environment)
+		c.Key = config.Viper.GetString(config.Flag.Service.Deployer.Project.ConfigMap.Key)
+		c.Name = config.Viper.GetString(config.Flag.Service.Deployer.Project.ConfigMap.Name)
+		c.Namespace = config.Viper.GetString(config.Flag.Release.Namespace)
+		c.Provider = config.Viper.GetString(config.Flag.Service.Deployer.Provider)
+
+		projectConfiguration, err = configuration.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// githubClient is shared by the Eventer and the project discovery, so
+	// that both are subject to the same GitHub rate limit.
+	var githubClient *github.Client
+	{
+		c := eventer.DefaultConfig()
+
+		c.HTTPClient = config.HTTPClient
+		c.Logger = config.Logger
+
+		c.Flag = config.Flag
+		c.Viper = config.Viper
+
+		githubClient, err = eventer.NewGithubClient(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// projectLister is shared by the Eventer and the Installer, so that both
+	// work on the same, possibly discovered, projects.
+	var projectLister eventerspec.ProjectLister
+	{
+		c := eventer.DefaultConfig()
+
+		c.GithubClient = githubClient
+		c.HTTPClient = config.HTTPClient
+		c.Logger = config.Logger
+		c.ProjectLister = projectConfiguration
+
+		c.Flag = config.Flag
+		c.Viper = config.Viper
+
+		projectLister, err = eventer.NewProjectLister(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
 	}
 
 	var helmMigrationService *helmmigration.HelmMigration
@@ -114,7 +187,7 @@ func New(config Config) (*Service, error) {
 
 			Repository:     config.Viper.GetString(config.Flag.Service.HelmMigration.Image.Repository),
 			HelmBinaryPath: config.Viper.GetString(config.Flag.Service.Deployer.Installer.Helm.HelmBinaryPath),
-			ProjectList:    projectList,
+			ProjectLister:  projectLister,
 		}
 
 		helmMigrationService, err = helmmigration.New(c)
@@ -123,14 +196,52 @@ func New(config Config) (*Service, error) {
 		}
 	}
 
+	var eventerService eventerspec.Eventer
+	{
+		eventerConfig := eventer.DefaultConfig()
+
+		eventerConfig.DynamicClient = dynamicClient
+		eventerConfig.FileSystem = config.FileSystem
+		eventerConfig.GithubClient = githubClient
+		eventerConfig.HTTPClient = config.HTTPClient
+		eventerConfig.KubernetesClient = k8sClient
+		eventerConfig.Logger = config.Logger
+		eventerConfig.ProjectLister = projectLister
+
+		eventerConfig.Flag = config.Flag
+		eventerConfig.Viper = config.Viper
+
+		for _, t := range strings.Split(config.Viper.GetString(config.Flag.Service.Deployer.Eventer.Type), ",") {
+			eventerConfig.Types = append(eventerConfig.Types, eventerspec.EventerType(strings.TrimSpace(t)))
+		}
+
+		eventerService, err = eventer.New(eventerConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var historyService *history.History
+	{
+		historyService, err = history.New(history.DefaultConfig())
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var deployerService deployer.Deployer
 	{
 		deployerConfig := deployer.DefaultConfig()
 
+		deployerConfig.Eventer = eventerService
 		deployerConfig.FileSystem = config.FileSystem
+		deployerConfig.History = historyService
 		deployerConfig.HTTPClient = config.HTTPClient
 		deployerConfig.KubernetesClient = k8sClient
 		deployerConfig.Logger = config.Logger
+		deployerConfig.ProjectConfiguration = projectConfiguration
+		deployerConfig.ProjectLister = projectLister
+		deployerConfig.RESTConfig = restConfig
 		deployerConfig.SlackClient = config.SlackClient
 
 		deployerConfig.Flag = config.Flag
@@ -162,21 +273,59 @@ func New(config Config) (*Service, error) {
 
 	newService := &Service{
 		// Dependencies.
-		Deployer:     deployerService,
-		HelmMigrator: helmMigrationService,
-		Version:      versionService,
+		Deployer:             deployerService,
+		Eventer:              eventerService,
+		HelmMigrator:         helmMigrationService,
+		History:              historyService,
+		ProjectConfiguration: projectConfiguration,
+		Version:              versionService,
 	}
 
 	return newService, nil
 }
 
+// Boot runs the service until the given context is cancelled, or Shutdown
+// is called.
 func (s *Service) Boot(ctx context.Context) error {
-	err := s.HelmMigrator.Migrate(ctx)
+	s.mutex.Lock()
+	if s.stopped {
+		s.mutex.Unlock()
+		return nil
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	done := s.done
+	s.mutex.Unlock()
+
+	defer close(done)
+
+	err := s.ProjectConfiguration.Boot(ctx)
+	if err != nil {
+		panic(fmt.Sprintf("%#v", err))
+	}
+
+	err = s.HelmMigrator.Migrate(ctx)
 	if err != nil {
 		panic(fmt.Sprintf("%#v", err))
 	}
 
-	s.Deployer.Boot()
+	s.Deployer.Boot(ctx)
 
 	return nil
 }
+
+// Shutdown stops the service, and waits for the deployment in progress to
+// finish.
+func (s *Service) Shutdown() {
+	s.mutex.Lock()
+	s.stopped = true
+	cancel, done := s.cancel, s.done
+	s.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+}